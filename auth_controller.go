@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"go-task/internal/service"
+	"go-task/internal/template"
+	"go-task/pkg"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const sessionCookieName = "go_task_session"
+
+type AuthController struct {
+	service *service.AuthService
+	tokens  *service.TokenService
+	// sso offers single sign-on on the login page.
+	sso bool
+}
+
+func NewAuthController(service *service.AuthService, tokens *service.TokenService, sso bool) *AuthController {
+	return &AuthController{
+		service: service,
+		tokens:  tokens,
+		sso:     sso,
+	}
+}
+
+func (controller *AuthController) register(router *http.ServeMux) {
+	router.Handle("GET /login", taskHandler(controller.loginPage))
+	router.Handle("POST /login", taskHandler(controller.login))
+	router.Handle("POST /logout", taskHandler(controller.logout))
+	router.Handle("GET /register", taskHandler(controller.registerPage))
+	router.Handle("POST /register", taskHandler(controller.signUp))
+}
+
+// authenticate resolves the bearer token or session cookie and attaches
+// the user to the request context. Requests without valid credentials only
+// reach the login and registration pages and the single sign-on flow.
+func (controller *AuthController) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if bearer, ok := bearerToken(r); ok {
+			controller.authenticateToken(next, w, r, bearer)
+			return
+		}
+		if isPublicPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cookie, err := r.Cookie(sessionCookieName)
+		if err == nil {
+			user, err := controller.service.Authenticate(cookie.Value)
+			if err == nil {
+				next.ServeHTTP(w, r.WithContext(service.WithUser(r.Context(), user)))
+				return
+			}
+			if !errors.Is(err, pkg.ErrNotFound) {
+				log.Println("Failed to authenticate session:", err)
+				pkg.WriteProblem(w, pkg.NewProblem(http.StatusInternalServerError, ""))
+				return
+			}
+		}
+		unauthorized(w, r)
+	})
+}
+
+// authenticateToken serves requests from machine clients. A token with
+// read scope is refused any method that changes state.
+func (controller *AuthController) authenticateToken(next http.Handler, w http.ResponseWriter, r *http.Request, bearer string) {
+	user, token, err := controller.tokens.Authenticate(bearer)
+	if err != nil {
+		if !errors.Is(err, pkg.ErrNotFound) {
+			log.Println("Failed to authenticate api token:", err)
+			pkg.WriteProblem(w, pkg.NewProblem(http.StatusInternalServerError, ""))
+			return
+		}
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		pkg.WriteProblem(w, pkg.NewProblem(http.StatusUnauthorized, "invalid or revoked token"))
+		return
+	}
+	if !token.Scope.Allows(r.Method) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+		pkg.WriteProblem(w, pkg.NewProblem(http.StatusForbidden, "token scope does not allow "+r.Method))
+		return
+	}
+	next.ServeHTTP(w, r.WithContext(service.WithUser(r.Context(), user)))
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+func isPublicPath(path string) bool {
+	return path == "/login" || path == "/register" || path == openAPIPath || path == apiDocsPath ||
+		strings.HasPrefix(path, apiDocsPath+"/") ||
+		strings.HasPrefix(path, ssoCookiePath+"/") || strings.HasPrefix(path, ssoStubPath+"/")
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/api/"):
+		pkg.WriteProblem(w, pkg.NewProblem(http.StatusUnauthorized, "authentication required"))
+	case r.Header.Get("HX-Request") == "true":
+		w.Header().Set("HX-Redirect", "/login")
+		w.WriteHeader(http.StatusUnauthorized)
+	default:
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	}
+}
+
+func (controller *AuthController) loginPage(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Cache-Control", "no-cache")
+	return template.Login("", controller.sso).Render(r.Context(), w)
+}
+
+func (controller *AuthController) login(w http.ResponseWriter, r *http.Request) error {
+	_, token, err := controller.service.Login(r.FormValue("username"), r.FormValue("password"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return template.Login(err.Error(), controller.sso).Render(r.Context(), w)
+		}
+		return err
+	}
+	setSessionCookie(w, token, time.Now().Add(service.SessionTTL))
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+	return nil
+}
+
+func (controller *AuthController) logout(w http.ResponseWriter, r *http.Request) error {
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		if err := controller.service.Logout(cookie.Value); err != nil {
+			return err
+		}
+	}
+	setSessionCookie(w, "", time.Unix(0, 0))
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+	return nil
+}
+
+func (controller *AuthController) registerPage(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Cache-Control", "no-cache")
+	return template.Register("").Render(r.Context(), w)
+}
+
+func (controller *AuthController) signUp(w http.ResponseWriter, r *http.Request) error {
+	username, password := r.FormValue("username"), r.FormValue("password")
+	if _, err := controller.service.Register(username, password, r.FormValue("workspace")); err != nil {
+		var taskErr *pkg.TaskError
+		if errors.As(err, &taskErr) {
+			return err
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		return template.Register(err.Error()).Render(r.Context(), w)
+	}
+	return controller.login(w, r)
+}
+
+func setSessionCookie(w http.ResponseWriter, token string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
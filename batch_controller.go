@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-task/internal/service"
+	"go-task/pkg"
+	"go-task/pkg/request"
+	"go-task/pkg/response"
+	"net/http"
+)
+
+type BatchController struct {
+	service *service.Service
+}
+
+func NewBatchController(service *service.Service) *BatchController {
+	return &BatchController{
+		service: service,
+	}
+}
+
+func (controller *BatchController) register(router *http.ServeMux) {
+	router.Handle("POST /api/v1/tasks:batch", taskHandler(controller.batch))
+}
+
+// batch answers a committed batch with 200 and the result of every
+// operation. When an atomic batch is rolled back the response carries the
+// status of the operation that failed.
+func (controller *BatchController) batch(w http.ResponseWriter, r *http.Request) error {
+	var batchReq request.BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&batchReq); err != nil {
+		badRequest(w, err.Error())
+		return nil
+	}
+	var atomic bool
+	switch batchReq.Mode {
+	case "", "atomic":
+		atomic = true
+	case "per-item":
+	default:
+		return writeServiceError(w, &pkg.ValidationError{Field: "mode", Message: fmt.Sprintf("unknown batch mode %q", batchReq.Mode)})
+	}
+
+	ops := make([]service.BatchOperation, 0, len(batchReq.Operations))
+	for _, opReq := range batchReq.Operations {
+		ops = append(ops, mapToBatchOperation(opReq))
+	}
+	results, committed, err := controller.service.Batch(r.Context(), ops, atomic)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+
+	status := http.StatusOK
+	batchRs := &response.BatchResponse{Committed: committed, Results: make([]*response.BatchResultResponse, 0, len(results))}
+	for i, result := range results {
+		resultRs, err := mapToBatchResultRes(ops[i].Op, result)
+		if err != nil {
+			return err
+		}
+		if !committed && resultRs.Status != http.StatusFailedDependency {
+			status = resultRs.Status
+		}
+		batchRs.Results = append(batchRs.Results, resultRs)
+	}
+	return writeJSON(w, status, batchRs)
+}
+
+func mapToBatchOperation(req request.BatchOperationRequest) service.BatchOperation {
+	op := service.BatchOperation{Op: service.BatchOp(req.Op), ID: req.ID, Status: req.Status}
+	switch op.Op {
+	case service.BatchCreate:
+		if req.Task == nil {
+			op.Err = &pkg.ValidationError{Field: "task", Message: "create needs a task"}
+			break
+		}
+		op.Task, op.Err = mapToTask(*req.Task)
+	case service.BatchUpdate:
+		if req.Patch == nil {
+			op.Err = &pkg.ValidationError{Field: "patch", Message: "update needs a patch"}
+			break
+		}
+		op.Patch, op.Err = mapToTaskPatch(*req.Patch)
+	}
+	return op
+}
+
+func mapToBatchResultRes(op service.BatchOp, result service.BatchResult) (*response.BatchResultResponse, error) {
+	if errors.Is(result.Err, service.ErrBatchAborted) {
+		problem := pkg.NewProblem(http.StatusFailedDependency, result.Err.Error())
+		return &response.BatchResultResponse{Status: problem.Status, Error: problem}, nil
+	}
+	if result.Err != nil {
+		problem := pkg.ProblemFor(result.Err)
+		return &response.BatchResultResponse{Status: problem.Status, Error: problem}, nil
+	}
+	if result.Task == nil {
+		return &response.BatchResultResponse{Status: http.StatusNoContent}, nil
+	}
+	taskRs, err := mapToTaskRes(*result.Task)
+	if err != nil {
+		return nil, err
+	}
+	status := http.StatusOK
+	if op == service.BatchCreate {
+		status = http.StatusCreated
+	}
+	return &response.BatchResultResponse{Status: status, Task: taskRs}, nil
+}
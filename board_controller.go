@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"go-task/internal/model"
+	"go-task/internal/service"
+	"go-task/internal/template"
+	"go-task/pkg"
+	"net/http"
+	"strconv"
+)
+
+// boardStatuses are the workflow columns of the board, left to right.
+var boardStatuses = []pkg.TaskStatus{pkg.TODO, pkg.PENDING, pkg.COMPLETED}
+
+type BoardController struct {
+	service  *service.Service
+	projects *service.ProjectService
+}
+
+func NewBoardController(service *service.Service, projects *service.ProjectService) *BoardController {
+	return &BoardController{
+		service:  service,
+		projects: projects,
+	}
+}
+
+func (controller *BoardController) register(router *http.ServeMux) {
+	router.Handle("GET /board", taskHandler(controller.board))
+	router.Handle("POST /board/move", taskHandler(controller.move))
+}
+
+// board shows every task of the workspace, or of one project when called
+// with ?project=ID.
+func (controller *BoardController) board(w http.ResponseWriter, r *http.Request) error {
+	projectID, ok := optionalID(w, r.FormValue("project"))
+	if !ok {
+		return nil
+	}
+	var project *model.Project
+	if projectID != nil {
+		var err error
+		project, err = controller.projects.FindById(r.Context(), *projectID)
+		if err != nil {
+			return writeServiceError(w, err)
+		}
+	}
+	columns, err := controller.columns(r, projectID)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	return template.Board(project, columns).Render(r.Context(), w)
+}
+
+// move applies a drop from the board and answers with the refreshed
+// columns. A move the service refuses is reported on the card itself with
+// a 200 so htmx swaps it in; the card then shows up where it was.
+func (controller *BoardController) move(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		badRequest(w, "invalid task id")
+		return nil
+	}
+	projectID, ok := optionalID(w, r.FormValue("project"))
+	if !ok {
+		return nil
+	}
+	before, ok := optionalID(w, r.FormValue("before"))
+	if !ok {
+		return nil
+	}
+	after, ok := optionalID(w, r.FormValue("after"))
+	if !ok {
+		return nil
+	}
+
+	var rejection string
+	_, err = controller.service.Move(r.Context(), id, pkg.TaskStatus(r.FormValue("status")), before, after)
+	if err != nil {
+		var taskErr *pkg.TaskError
+		if errors.As(err, &taskErr) {
+			return err
+		}
+		rejection = err.Error()
+	}
+
+	columns, err := controller.columns(r, projectID)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	var boardProject int64
+	if projectID != nil {
+		boardProject = *projectID
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	return template.BoardColumns(boardProject, columns, id, rejection).Render(r.Context(), w)
+}
+
+func (controller *BoardController) columns(r *http.Request, projectID *int64) ([]template.BoardColumn, error) {
+	var tasks []*model.Task
+	var err error
+	if projectID != nil {
+		tasks, err = controller.projects.Tasks(r.Context(), *projectID)
+	} else {
+		tasks, err = controller.service.FindAll(r.Context())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]template.BoardColumn, 0, len(boardStatuses))
+	for _, status := range boardStatuses {
+		column := template.BoardColumn{Status: status}
+		for _, task := range tasks {
+			if task.Status == status {
+				column.Tasks = append(column.Tasks, task)
+			}
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// optionalID parses an id form value that may be absent; zero counts as
+// absent too, which is how the board marks "no project".
+func optionalID(w http.ResponseWriter, value string) (*int64, bool) {
+	if value == "" || value == "0" {
+		return nil, true
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		badRequest(w, "invalid id "+strconv.Quote(value))
+		return nil, false
+	}
+	return &id, true
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/internal/service"
+	"go-task/pkg"
+	"go-task/pkg/client"
+	"go-task/pkg/request"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testAPI is the router over in-memory stores, served to the client.
+type testAPI struct {
+	deps   services
+	db     *memDB
+	server *httptest.Server
+	user   *model.User
+	token  string
+	client *client.Client
+
+	mu sync.Mutex
+	// loseResponses is how many more responses to requests that change
+	// state are replaced by a 503 after the router answered them, as if a
+	// proxy lost them.
+	loseResponses int
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	db := newMemDB()
+	api := &testAPI{deps: newMemServices(db), db: db}
+	router := newRouter(api.deps)
+	api.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !api.loseResponse(r) {
+			router.ServeHTTP(w, r)
+			return
+		}
+		router.ServeHTTP(httptest.NewRecorder(), r)
+		pkg.WriteProblem(w, pkg.NewProblem(http.StatusServiceUnavailable, ""))
+	}))
+	t.Cleanup(api.server.Close)
+
+	user, token := signUp(t, db, api.deps, "ada", model.ScopeWrite)
+	api.user, api.token = user, token
+	api.client = api.newClient(t, client.WithToken(token))
+	return api
+}
+
+func (api *testAPI) newClient(t *testing.T, options ...client.Option) *client.Client {
+	t.Helper()
+	c, err := client.New(api.server.URL, append([]client.Option{client.WithRetries(3, time.Millisecond)}, options...)...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return c
+}
+
+func (api *testAPI) loseResponse(r *http.Request) bool {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if api.loseResponses == 0 || !changesState(r.Method) {
+		return false
+	}
+	api.loseResponses--
+	return true
+}
+
+// seed creates n tasks titled "task 1" to "task n" in rank order.
+func (api *testAPI) seed(t *testing.T, n int) []int64 {
+	t.Helper()
+	ctx := service.WithUser(context.Background(), api.user)
+	ids := make([]int64, 0, n)
+	for i := 1; i <= n; i++ {
+		task, err := api.deps.tasks.Create(ctx, &model.Task{Title: fmt.Sprintf("task %d", i), Status: pkg.TODO})
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		ids = append(ids, task.ID)
+	}
+	return ids
+}
+
+func TestClientTaskLifecycle(t *testing.T) {
+	api := newTestAPI(t)
+	ctx := context.Background()
+
+	created, err := api.client.Create(ctx, request.TaskRequest{Title: "write report", Status: pkg.TODO, Labels: []string{"work"}})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if created.ID == 0 || created.Title != "write report" || created.CreatedBy == nil || *created.CreatedBy != api.user.ID {
+		t.Fatalf("created %+v", created)
+	}
+	found, err := api.client.Get(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if found.ID != created.ID || found.Title != created.Title || !slices.Equal(found.Labels, created.Labels) {
+		t.Fatalf("got %+v, want %+v", found, created)
+	}
+
+	updated, err := api.client.Update(ctx, created.ID, request.TaskPatchRequest{Status: pkg.Optional[pkg.TaskStatus]{Value: pkg.COMPLETED, Set: true}})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if updated.Status != string(pkg.COMPLETED) || updated.Title != created.Title {
+		t.Fatalf("updated %+v, want only the status changed", updated)
+	}
+
+	if err := api.client.Delete(ctx, created.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	for task, err := range api.client.List(ctx, client.ListOptions{}) {
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		t.Fatalf("listed %+v after it was deleted", task)
+	}
+}
+
+func TestClientListFollowsLinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		tasks    int
+		pageSize int
+	}{
+		{"empty", 0, 2},
+		{"one page", 3, 5},
+		{"exact pages", 6, 2},
+		{"last page short", 7, 3},
+		{"default page size", 250, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newTestAPI(t)
+			want := api.seed(t, tt.tasks)
+			var ids []int64
+			for task, err := range api.client.List(context.Background(), client.ListOptions{PageSize: tt.pageSize}) {
+				if err != nil {
+					t.Fatalf("List: %v", err)
+				}
+				ids = append(ids, task.ID)
+			}
+			if !slices.Equal(ids, want) {
+				t.Fatalf("listed %v, want every task once in rank order", ids)
+			}
+		})
+	}
+}
+
+func TestClientListStopsEarly(t *testing.T) {
+	api := newTestAPI(t)
+	want := api.seed(t, 10)[:4]
+	var ids []int64
+	for task, err := range api.client.List(context.Background(), client.ListOptions{PageSize: 3}) {
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		ids = append(ids, task.ID)
+		if len(ids) == 4 {
+			break
+		}
+	}
+	if !slices.Equal(ids, want) {
+		t.Fatalf("listed %v, want %v", ids, want)
+	}
+}
+
+func TestClientProblemsBecomeTypedErrors(t *testing.T) {
+	api := newTestAPI(t)
+	ctx := context.Background()
+
+	_, err := api.client.Get(ctx, 42)
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || !errors.Is(err, pkg.ErrNotFound) {
+		t.Fatalf("Get missing task: got %v, want %v", err, pkg.ErrNotFound)
+	}
+	if apiErr.Status != http.StatusNotFound || apiErr.Detail != "task 42: not found" || apiErr.IsValidation() {
+		t.Errorf("problem = %+v", apiErr.Problem)
+	}
+
+	_, err = api.client.Create(ctx, request.TaskRequest{Status: pkg.TODO})
+	if !errors.As(err, &apiErr) || !apiErr.IsValidation() {
+		t.Fatalf("Create without title: got %v, want a validation problem", err)
+	}
+	if len(apiErr.Errors) != 1 || apiErr.Errors[0].Field != "title" {
+		t.Errorf("rejected fields = %+v, want title", apiErr.Errors)
+	}
+	if errors.Is(err, pkg.ErrNotFound) || errors.Is(err, pkg.ErrInternal) {
+		t.Errorf("validation problem matches another error class")
+	}
+
+	if _, err := api.newClient(t).Get(ctx, 1); !errors.Is(err, pkg.ErrUnauthorized) {
+		t.Errorf("Get without token: got %v, want %v", err, pkg.ErrUnauthorized)
+	}
+	_, readToken, err := api.deps.tokens.Issue(api.user, "read only", model.ScopeRead, nil)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	reader := api.newClient(t, client.WithToken(readToken))
+	if _, err := reader.Create(ctx, request.TaskRequest{Title: "write report", Status: pkg.TODO}); !errors.Is(err, pkg.ErrForbidden) {
+		t.Errorf("Create with a read token: got %v, want %v", err, pkg.ErrForbidden)
+	}
+}
+
+// A create whose response is lost is retried with the same key, and the
+// server answers the retry with the task it already created.
+func TestClientCreateRetriesWithIdempotencyKey(t *testing.T) {
+	api := newTestAPI(t)
+	api.loseResponses = 2
+	ctx := service.WithUser(context.Background(), api.user)
+
+	created, err := api.client.Create(context.Background(), request.TaskRequest{Title: "write report", Status: pkg.TODO})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	tasks, _ := api.deps.tasks.FindAll(ctx)
+	if len(tasks) != 1 || tasks[0].ID != created.ID {
+		t.Fatalf("created %+v; the server has %d tasks, want one", created, len(tasks))
+	}
+
+	// Another call is a new request with a key of its own.
+	if _, err := api.client.Create(context.Background(), request.TaskRequest{Title: "write report", Status: pkg.TODO}); err != nil {
+		t.Fatalf("second Create: %v", err)
+	}
+	if tasks, _ := api.deps.tasks.FindAll(ctx); len(tasks) != 2 {
+		t.Fatalf("server has %d tasks, want two", len(tasks))
+	}
+}
+
+func TestClientProjectTasks(t *testing.T) {
+	api := newTestAPI(t)
+	ctx := service.WithUser(context.Background(), api.user)
+	project, err := api.deps.projects.Create(ctx, &model.Project{Name: "home"})
+	if err != nil {
+		t.Fatalf("Create project: %v", err)
+	}
+	api.seed(t, 2)
+	var want []int64
+	for _, title := range []string{"water plants", "fix tap"} {
+		task, err := api.deps.tasks.Create(ctx, &model.Task{Title: title, Status: pkg.TODO, ProjectID: &project.ID})
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		want = append(want, task.ID)
+	}
+
+	tasks, err := api.client.ProjectTasks(context.Background(), project.ID)
+	if err != nil {
+		t.Fatalf("ProjectTasks: %v", err)
+	}
+	var ids []int64
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	if !slices.Equal(ids, want) {
+		t.Fatalf("listed %v, want the project's tasks %v", ids, want)
+	}
+	if _, err := api.client.ProjectTasks(context.Background(), project.ID+1); !errors.Is(err, pkg.ErrNotFound) {
+		t.Errorf("ProjectTasks of a missing project: got %v, want %v", err, pkg.ErrNotFound)
+	}
+}
@@ -0,0 +1,449 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"go-task/pkg"
+	"go-task/pkg/client"
+	"go-task/pkg/request"
+	"go-task/pkg/response"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dueLayouts are the accepted forms of a due date, read in local time.
+var dueLayouts = []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02"}
+
+func add(ctx context.Context, a *app, args []string) error {
+	flags := a.flagSet("add")
+	content := flags.String("content", "", "task description")
+	status := flags.String("status", "", "TODO, PENDING or COMPLETED")
+	labels := flags.String("labels", "", "comma-separated labels")
+	due := flags.String("due", "", "due date, such as 2024-05-01 or 2024-05-01 17:00")
+	project := flags.Int64("project", 0, "project id")
+	recurrence := flags.String("recurrence", "", "recurrence rule, such as FREQ=WEEKLY")
+	asJSON := flags.Bool("json", false, "print JSON")
+	rest, err := parse(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 {
+		return a.usageError(flags, "missing title")
+	}
+
+	task := request.TaskRequest{
+		Title:      strings.Join(rest, " "),
+		Content:    *content,
+		Labels:     splitLabels(*labels),
+		Recurrence: *recurrence,
+	}
+	if *status != "" {
+		if task.Status, err = parseStatus(*status); err != nil {
+			return err
+		}
+	}
+	if *due != "" {
+		dueAt, err := parseDue(*due)
+		if err != nil {
+			return err
+		}
+		task.DueAt = &dueAt
+	}
+	if *project != 0 {
+		task.ProjectID = project
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	created, err := c.Create(ctx, task)
+	if err != nil {
+		return describe(err)
+	}
+	return a.printer(*asJSON).task(created)
+}
+
+func list(ctx context.Context, a *app, args []string) error {
+	flags := a.flagSet("ls")
+	filter := newFilter(flags)
+	asJSON := flags.Bool("json", false, "print JSON")
+	rest, err := parse(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return a.usageError(flags, "unexpected arguments")
+	}
+	if err := filter.validate(); err != nil {
+		return err
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	if *filter.project != 0 {
+		found, err := c.ProjectTasks(ctx, *filter.project)
+		if err != nil {
+			return describe(err)
+		}
+		tasks := slices.DeleteFunc(found, func(task *response.TaskResponse) bool { return !filter.matches(task) })
+		return a.printer(*asJSON).tasks(tasks)
+	}
+	var tasks []*response.TaskResponse
+	for task, err := range c.List(ctx, client.ListOptions{}) {
+		if err != nil {
+			return describe(err)
+		}
+		if filter.matches(task) {
+			tasks = append(tasks, task)
+		}
+	}
+	return a.printer(*asJSON).tasks(tasks)
+}
+
+func done(ctx context.Context, a *app, args []string) error {
+	return a.eachTask(ctx, "done", args, func(c *client.Client, id int64) error {
+		_, err := c.Update(ctx, id, request.TaskPatchRequest{Status: set(pkg.COMPLETED)})
+		return err
+	})
+}
+
+func edit(ctx context.Context, a *app, args []string) error {
+	flags := a.flagSet("edit")
+	title := flags.String("title", "", "new title")
+	content := flags.String("content", "", "new description")
+	status := flags.String("status", "", "TODO, PENDING or COMPLETED")
+	labels := flags.String("labels", "", "comma-separated labels replacing the current ones; empty clears them")
+	due := flags.String("due", "", "due date; empty clears it")
+	project := flags.String("project", "", "project id; empty clears it")
+	recurrence := flags.String("recurrence", "", "recurrence rule; empty clears it")
+	asJSON := flags.Bool("json", false, "print JSON")
+	rest, err := parse(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return a.usageError(flags, "expected one task id")
+	}
+	id, err := parseID(rest[0])
+	if err != nil {
+		return err
+	}
+
+	// Only the flags given are sent, so the rest of the task is kept.
+	var patch request.TaskPatchRequest
+	var changed bool
+	var visitErr error
+	flags.Visit(func(f *flag.Flag) {
+		if visitErr != nil || f.Name == "json" {
+			return
+		}
+		changed = true
+		switch f.Name {
+		case "title":
+			patch.Title = set(*title)
+		case "content":
+			patch.Content = set(*content)
+		case "status":
+			var s pkg.TaskStatus
+			s, visitErr = parseStatus(*status)
+			patch.Status = set(s)
+		case "labels":
+			patch.Labels = set(splitLabels(*labels))
+		case "due":
+			var dueAt *time.Time
+			if *due != "" {
+				var t time.Time
+				t, visitErr = parseDue(*due)
+				dueAt = &t
+			}
+			patch.DueAt = set(dueAt)
+		case "project":
+			var projectID *int64
+			if *project != "" {
+				var id int64
+				id, visitErr = parseID(*project)
+				projectID = &id
+			}
+			patch.ProjectID = set(projectID)
+		case "recurrence":
+			patch.Recurrence = set(*recurrence)
+		}
+	})
+	if visitErr != nil {
+		return visitErr
+	}
+	if !changed {
+		return a.usageError(flags, "nothing to change")
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	updated, err := c.Update(ctx, id, patch)
+	if err != nil {
+		return describe(err)
+	}
+	return a.printer(*asJSON).task(updated)
+}
+
+func remove(ctx context.Context, a *app, args []string) error {
+	return a.eachTask(ctx, "rm", args, func(c *client.Client, id int64) error {
+		return c.Delete(ctx, id)
+	})
+}
+
+func search(ctx context.Context, a *app, args []string) error {
+	flags := a.flagSet("search")
+	filter := newFilter(flags)
+	asJSON := flags.Bool("json", false, "print JSON")
+	rest, err := parse(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 {
+		return a.usageError(flags, "missing query")
+	}
+	if err := filter.validate(); err != nil {
+		return err
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	found, err := c.Search(ctx, strings.Join(rest, " "))
+	if err != nil {
+		return describe(err)
+	}
+	tasks := slices.DeleteFunc(found, func(task *response.TaskResponse) bool { return !filter.matches(task) })
+	return a.printer(*asJSON).tasks(tasks)
+}
+
+// configure writes the server and token given, or prints the config in
+// use when none is.
+func configure(_ context.Context, a *app, args []string) error {
+	flags := a.flagSet("config")
+	server := flags.String("server", "", "server `url`")
+	token := flags.String("token", "", "API `token`")
+	rest, err := parse(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return a.usageError(flags, "unexpected arguments")
+	}
+	if flags.NFlag() == 0 {
+		token := "(none)"
+		if a.cfg.Token != "" {
+			token = "(set)"
+		}
+		fmt.Fprintf(a.stdout, "config: %s\nserver: %s\ntoken:  %s\n", a.configPath, a.cfg.Server, token)
+		return nil
+	}
+
+	// Start from the file alone so environment overrides are not saved.
+	cfg, err := loadFile(a.configPath)
+	if err != nil {
+		return err
+	}
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "server":
+			cfg.Server = *server
+		case "token":
+			cfg.Token = *token
+		}
+	})
+	if _, err := client.New(cfg.Server); err != nil {
+		return err
+	}
+	if err := cfg.save(a.configPath); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "saved %s\n", a.configPath)
+	return nil
+}
+
+// eachTask runs fn for every task id in args and prints the ids it
+// succeeded for. It goes on after a failure and reports the failures
+// together.
+func (a *app) eachTask(ctx context.Context, name string, args []string, fn func(c *client.Client, id int64) error) error {
+	flags := a.flagSet(name)
+	rest, err := parse(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 {
+		return a.usageError(flags, "missing task id")
+	}
+	ids := make([]int64, 0, len(rest))
+	for _, arg := range rest {
+		id, err := parseID(arg)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, id := range ids {
+		if err := fn(c, id); err != nil {
+			fmt.Fprintf(a.stderr, "go-task: task %d: %v\n", id, describe(err))
+			failed++
+			continue
+		}
+		fmt.Fprintln(a.stdout, id)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tasks failed", failed, len(ids))
+	}
+	return nil
+}
+
+// filter narrows listed tasks by status, label and project. Only the
+// project is narrowed by the API, so the rest of the filtering happens
+// here.
+type filter struct {
+	status  *string
+	label   *string
+	project *int64
+}
+
+func newFilter(flags *flag.FlagSet) *filter {
+	return &filter{
+		status:  flags.String("status", "", "only tasks with this status"),
+		label:   flags.String("label", "", "only tasks with this label"),
+		project: flags.Int64("project", 0, "only tasks of this project"),
+	}
+}
+
+func (f *filter) validate() error {
+	if *f.status == "" {
+		return nil
+	}
+	status, err := parseStatus(*f.status)
+	*f.status = string(status)
+	return err
+}
+
+func (f *filter) matches(task *response.TaskResponse) bool {
+	if *f.status != "" && task.Status != *f.status {
+		return false
+	}
+	if *f.label != "" && !slices.Contains(task.Labels, *f.label) {
+		return false
+	}
+	if *f.project != 0 && (task.ProjectID == nil || *task.ProjectID != *f.project) {
+		return false
+	}
+	return true
+}
+
+func (a *app) flagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(a.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(a.stderr, "usage: go-task %s\n", usages[name])
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+func (a *app) usageError(flags *flag.FlagSet, message string) error {
+	fmt.Fprintf(a.stderr, "go-task %s: %s\n", flags.Name(), message)
+	flags.Usage()
+	return errUsage
+}
+
+func (a *app) printer(asJSON bool) *printer {
+	return &printer{out: a.stdout, json: asJSON}
+}
+
+// parse parses flags given before, between or after the positional
+// arguments, which it returns; "--" ends the flags.
+func parse(flags *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, errUsage
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return rest, nil
+		}
+		if args[0] == "--" {
+			return append(rest, args[1:]...), nil
+		}
+		rest = append(rest, args[0])
+		args = args[1:]
+	}
+}
+
+// set is an Optional that is sent, for fields of a patch.
+func set[T any](value T) pkg.Optional[T] {
+	return pkg.Optional[T]{Value: value, Set: true}
+}
+
+func parseID(value string) (int64, error) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid task id %q", value)
+	}
+	return id, nil
+}
+
+// parseStatus accepts a status in any case, and "done" for COMPLETED.
+func parseStatus(value string) (pkg.TaskStatus, error) {
+	status := pkg.TaskStatus(strings.ToUpper(value))
+	if status == "DONE" {
+		status = pkg.COMPLETED
+	}
+	if !status.IsValid() {
+		return "", fmt.Errorf("invalid status %q: use TODO, PENDING or COMPLETED", value)
+	}
+	return status, nil
+}
+
+func parseDue(value string) (time.Time, error) {
+	for _, layout := range dueLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid due date %q: use 2006-01-02, 2006-01-02 15:04 or RFC 3339", value)
+}
+
+func splitLabels(value string) []string {
+	var labels []string
+	for _, label := range strings.Split(value, ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// describe adds the rejected fields of a validation error to its message.
+func describe(err error) error {
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || len(apiErr.Errors) == 0 {
+		return err
+	}
+	fields := make([]string, 0, len(apiErr.Errors))
+	for _, field := range apiErr.Errors {
+		fields = append(fields, field.Field+": "+field.Detail)
+	}
+	return fmt.Errorf("%w (%s)", err, strings.Join(fields, "; "))
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const defaultServer = "http://localhost:7000"
+
+// config is where the CLI finds the server. It is read from a JSON file
+// and can be overridden by the GO_TASK_SERVER and GO_TASK_TOKEN
+// environment variables, which the global flags override in turn.
+type config struct {
+	Server string `json:"server"`
+	Token  string `json:"token,omitempty"`
+}
+
+// defaultConfigPath is go-task/config.json in the user's config directory,
+// such as ~/.config on Linux.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "go-task", "config.json")
+}
+
+// loadConfig reads the config file at path and applies the environment
+// overrides.
+func loadConfig(path string) (*config, error) {
+	cfg, err := loadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if server := os.Getenv("GO_TASK_SERVER"); server != "" {
+		cfg.Server = server
+	}
+	if token := os.Getenv("GO_TASK_TOKEN"); token != "" {
+		cfg.Token = token
+	}
+	return cfg, nil
+}
+
+// loadFile reads the config file at path. A missing file is not an error;
+// the defaults apply.
+func loadFile(path string) (*config, error) {
+	cfg := &config{Server: defaultServer}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			return nil, err
+		default:
+			if err := json.Unmarshal(data, cfg); err != nil {
+				return nil, fmt.Errorf("reading %s: %w", path, err)
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// save writes the config to path, readable by the user only since it
+// holds the token. WriteFile only sets the mode of a new file, so the mode
+// of an existing one is narrowed as well.
+func (cfg *config) save(path string) error {
+	if path == "" {
+		return errors.New("no config path; pass -config")
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0o600)
+}
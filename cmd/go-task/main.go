@@ -0,0 +1,129 @@
+// Command go-task manages tasks on a running go-task server through its
+// API.
+//
+//	go-task [-config file] [-server url] [-token token] <command> [flags] [args]
+//
+// The server and token come from the config file, by default
+// go-task/config.json in the user's config directory, unless set by the
+// GO_TASK_SERVER and GO_TASK_TOKEN environment variables or the flags.
+// "go-task config" writes the file.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"go-task/pkg/client"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+)
+
+const requestTimeout = 30 * time.Second
+
+// commands maps the name of each subcommand to its function, which gets
+// the arguments after the name, flags included.
+var commands = map[string]func(ctx context.Context, a *app, args []string) error{
+	"add":    add,
+	"ls":     list,
+	"done":   done,
+	"edit":   edit,
+	"rm":     remove,
+	"search": search,
+	"config": configure,
+}
+
+var commandOrder = []string{"add", "ls", "done", "edit", "rm", "search", "config"}
+
+var usages = map[string]string{
+	"add":    "add [-content text] [-status status] [-labels a,b] [-due date] [-project id] [-recurrence rule] [-json] title",
+	"ls":     "ls [-status status] [-label label] [-project id] [-json]",
+	"done":   "done id...",
+	"edit":   "edit [-title text] [-content text] [-status status] [-labels a,b] [-due date] [-project id] [-recurrence rule] [-json] id",
+	"rm":     "rm id...",
+	"search": "search [-status status] [-label label] [-project id] [-json] query",
+	"config": "config [-server url] [-token token]",
+}
+
+// errUsage reports wrong arguments; the usage has already been printed.
+var errUsage = errors.New("usage")
+
+// app is what commands share: the loaded config and where to write.
+type app struct {
+	cfg        *config
+	configPath string
+	stdout     io.Writer
+	stderr     io.Writer
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("go-task", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configPath := flags.String("config", defaultConfigPath(), "config `file`")
+	server := flags.String("server", "", "server `url`, overriding the config")
+	token := flags.String("token", "", "API `token`, overriding the config")
+	flags.Usage = func() { usage(flags) }
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	runCommand, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "go-task: unknown command %q\n", flags.Arg(0))
+		flags.Usage()
+		return 2
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "go-task: %v\n", err)
+		return 1
+	}
+	if *server != "" {
+		cfg.Server = *server
+	}
+	if *token != "" {
+		cfg.Token = *token
+	}
+	a := &app{cfg: cfg, configPath: *configPath, stdout: stdout, stderr: stderr}
+	if err := runCommand(ctx, a, flags.Args()[1:]); err != nil {
+		if errors.Is(err, errUsage) {
+			return 2
+		}
+		fmt.Fprintf(stderr, "go-task: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func usage(flags *flag.FlagSet) {
+	w := flags.Output()
+	fmt.Fprintln(w, "usage: go-task [-config file] [-server url] [-token token] <command> [flags] [args]")
+	fmt.Fprintln(w, "\ncommands:")
+	for _, name := range commandOrder {
+		fmt.Fprintf(w, "  %s\n", usages[name])
+	}
+	fmt.Fprintln(w, "\nflags:")
+	flags.PrintDefaults()
+}
+
+// client returns an API client of the configured server.
+func (a *app) client() (*client.Client, error) {
+	options := []client.Option{client.WithHTTPClient(&http.Client{Timeout: requestTimeout})}
+	if a.cfg.Token != "" {
+		options = append(options, client.WithToken(a.cfg.Token))
+	}
+	return client.New(a.cfg.Server, options...)
+}
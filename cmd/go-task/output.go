@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-task/pkg/response"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const dueLayout = "2006-01-02 15:04"
+
+// printer writes tasks as a table for people or as JSON for scripts.
+type printer struct {
+	out  io.Writer
+	json bool
+}
+
+func (p *printer) task(task *response.TaskResponse) error {
+	if p.json {
+		return p.encode(task)
+	}
+	return p.tasks([]*response.TaskResponse{task})
+}
+
+func (p *printer) tasks(tasks []*response.TaskResponse) error {
+	if p.json {
+		if tasks == nil {
+			tasks = []*response.TaskResponse{}
+		}
+		return p.encode(tasks)
+	}
+	w := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tDUE\tPROJECT\tLABELS\tTITLE")
+	for _, task := range tasks {
+		due := "-"
+		if task.DueAt != nil {
+			due = task.DueAt.Local().Format(dueLayout)
+		}
+		project := "-"
+		if task.ProjectID != nil {
+			project = strconv.FormatInt(*task.ProjectID, 10)
+		}
+		labels := "-"
+		if len(task.Labels) > 0 {
+			labels = strings.Join(task.Labels, ",")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", task.ID, task.Status, due, project, labels, task.Title)
+	}
+	return w.Flush()
+}
+
+func (p *printer) encode(v any) error {
+	encoder := json.NewEncoder(p.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
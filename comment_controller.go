@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"go-task/internal/model"
+	"go-task/internal/service"
+	"go-task/pkg/request"
+	"go-task/pkg/response"
+	"net/http"
+	"strconv"
+)
+
+type CommentController struct {
+	service *service.CommentService
+}
+
+func NewCommentController(service *service.CommentService) *CommentController {
+	return &CommentController{
+		service: service,
+	}
+}
+
+func (controller *CommentController) register(router *http.ServeMux) {
+	router.Handle("GET /api/v1/tasks/{id}/comments", taskHandler(controller.list))
+	router.Handle("POST /api/v1/tasks/{id}/comments", taskHandler(controller.create))
+	router.Handle("GET /api/v1/tasks/{id}/comments/{commentId}", taskHandler(controller.get))
+	router.Handle("PUT /api/v1/tasks/{id}/comments/{commentId}", taskHandler(controller.update))
+	router.Handle("DELETE /api/v1/tasks/{id}/comments/{commentId}", taskHandler(controller.delete))
+}
+
+func (controller *CommentController) list(w http.ResponseWriter, r *http.Request) error {
+	taskID, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	comments, err := controller.service.FindByTaskId(r.Context(), taskID)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	commentsRS := make([]*response.CommentResponse, 0, len(comments))
+	for _, c := range comments {
+		commentsRS = append(commentsRS, mapToCommentRes(*c))
+	}
+	return writeJSON(w, http.StatusOK, commentsRS)
+}
+
+func (controller *CommentController) get(w http.ResponseWriter, r *http.Request) error {
+	taskID, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	id, ok := pathID(w, r, "commentId")
+	if !ok {
+		return nil
+	}
+	comment, err := controller.service.FindById(r.Context(), taskID, id)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	return writeJSON(w, http.StatusOK, mapToCommentRes(*comment))
+}
+
+func (controller *CommentController) create(w http.ResponseWriter, r *http.Request) error {
+	taskID, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	var commentReq request.CommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&commentReq); err != nil {
+		badRequest(w, err.Error())
+		return nil
+	}
+	comment, err := controller.service.Create(r.Context(), taskID, commentReq.Body)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	return writeJSON(w, http.StatusCreated, mapToCommentRes(*comment))
+}
+
+func (controller *CommentController) update(w http.ResponseWriter, r *http.Request) error {
+	taskID, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	id, ok := pathID(w, r, "commentId")
+	if !ok {
+		return nil
+	}
+	var commentReq request.CommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&commentReq); err != nil {
+		badRequest(w, err.Error())
+		return nil
+	}
+	comment, err := controller.service.Update(r.Context(), taskID, id, commentReq.Body)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	return writeJSON(w, http.StatusOK, mapToCommentRes(*comment))
+}
+
+func (controller *CommentController) delete(w http.ResponseWriter, r *http.Request) error {
+	taskID, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	id, ok := pathID(w, r, "commentId")
+	if !ok {
+		return nil
+	}
+	if err := controller.service.Delete(r.Context(), taskID, id); err != nil {
+		return writeServiceError(w, err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func mapToCommentRes(comment model.Comment) *response.CommentResponse {
+	return &response.CommentResponse{
+		ID:        comment.ID,
+		TaskID:    comment.TaskID,
+		Author:    comment.Author,
+		Body:      comment.Body,
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.UpdatedAt,
+	}
+}
+
+func pathID(w http.ResponseWriter, r *http.Request, name string) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue(name), 10, 64)
+	if err != nil {
+		badRequest(w, "invalid "+name)
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-task/internal/graph"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GraphQLController serves the GraphQL schema over HTTP. Queries may be
+// sent with GET, which read tokens are allowed to use, or POST; mutations
+// need POST. A client that accepts text/event-stream gets the results as
+// Server-Sent Events, which is how subscriptions are consumed.
+type GraphQLController struct {
+	schema *graph.Schema
+}
+
+func NewGraphQLController(schema *graph.Schema) *GraphQLController {
+	return &GraphQLController{
+		schema: schema,
+	}
+}
+
+func (controller *GraphQLController) register(router *http.ServeMux) {
+	router.Handle("GET /api/graphql", taskHandler(controller.get))
+	router.Handle("POST /api/graphql", taskHandler(controller.post))
+}
+
+func (controller *GraphQLController) get(w http.ResponseWriter, r *http.Request) error {
+	params := graph.Params{
+		Query:         r.URL.Query().Get("query"),
+		OperationName: r.URL.Query().Get("operationName"),
+	}
+	if variables := r.URL.Query().Get("variables"); variables != "" {
+		if err := json.Unmarshal([]byte(variables), &params.Variables); err != nil {
+			badRequest(w, "variables: "+err.Error())
+			return nil
+		}
+	}
+	return controller.serve(w, r, params, true)
+}
+
+func (controller *GraphQLController) post(w http.ResponseWriter, r *http.Request) error {
+	var params graph.Params
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		badRequest(w, err.Error())
+		return nil
+	}
+	return controller.serve(w, r, params, false)
+}
+
+func (controller *GraphQLController) serve(w http.ResponseWriter, r *http.Request, params graph.Params, readOnly bool) error {
+	if strings.TrimSpace(params.Query) == "" {
+		badRequest(w, "query is required")
+		return nil
+	}
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		return controller.stream(w, r, params, readOnly)
+	}
+	return writeJSON(w, http.StatusOK, controller.schema.Exec(r.Context(), params, readOnly))
+}
+
+// stream sends every result of the operation as a "next" event and a
+// "complete" event once the operation ends, following the distinct
+// connections mode of the GraphQL over SSE protocol.
+func (controller *GraphQLController) stream(w http.ResponseWriter, r *http.Request, params graph.Params, readOnly bool) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return fmt.Errorf("response writer does not support streaming")
+	}
+	results, err := controller.schema.Subscribe(r.Context(), params, readOnly)
+	if err != nil {
+		badRequest(w, err.Error())
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(liveKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+		case result, ok := <-results:
+			if !ok {
+				_, _ = io.WriteString(w, "event: complete\ndata:\n\n")
+				flusher.Flush()
+				return nil
+			}
+			data, err := json.Marshal(result)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "event: next\ndata: %s\n\n", data); err != nil {
+				return nil
+			}
+		}
+		flusher.Flush()
+	}
+}
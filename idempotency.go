@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"go-task/internal/model"
+	"go-task/internal/service"
+	"go-task/pkg"
+	"io"
+	"log"
+	"net/http"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	maxIdempotencyKeyLen = 255
+)
+
+// replayedHeaders are the response headers stored along with a response
+// and sent again when it is replayed.
+var replayedHeaders = []string{"Content-Type", "Location"}
+
+// idempotent honours the Idempotency-Key header on every request that
+// changes state. The first request with a key is handled and its response
+// stored; a retry with the same key and the same request gets the stored
+// response, and a different request reusing the key is refused with 422.
+// Keys belong to the signed-in user, so anonymous requests pass through.
+func idempotent(idempotency *service.IdempotencyService, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		user, ok := service.UserFrom(r.Context())
+		if key == "" || !ok || !changesState(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			badRequest(w, "Idempotency-Key is too long")
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		stored, err := idempotency.Begin(user.ID, key, requestHash(r, body))
+		if err != nil {
+			if errors.Is(err, service.ErrIdempotencyKeyReused) {
+				pkg.WriteProblem(w, pkg.NewProblem(http.StatusUnprocessableEntity, err.Error()))
+				return
+			}
+			pkg.WriteProblem(w, pkg.ProblemFor(err))
+			return
+		}
+		if stored != nil {
+			replay(w, stored)
+			return
+		}
+
+		// Until the response is stored the key is released again, also
+		// when the handler panics, so the client can retry.
+		stored = &model.IdempotencyRecord{UserID: user.ID, Key: key}
+		defer func() {
+			if !stored.Completed() {
+				if err := idempotency.Release(user.ID, key); err != nil {
+					log.Println("Failed to release idempotency key:", err)
+				}
+			}
+		}()
+		recorder := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+		if recorder.statusCode() >= http.StatusInternalServerError {
+			return
+		}
+
+		stored.StatusCode = recorder.statusCode()
+		stored.Body = recorder.body.Bytes()
+		stored.Header = map[string]string{}
+		for _, name := range replayedHeaders {
+			if value := w.Header().Get(name); value != "" {
+				stored.Header[name] = value
+			}
+		}
+		if err := idempotency.Complete(stored); err != nil {
+			log.Println("Failed to store idempotent response:", err)
+			stored.StatusCode = 0
+		}
+	})
+}
+
+func changesState(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// requestHash identifies a request by method, target and body, so a key
+// reused for a different request is told apart from a retry.
+func requestHash(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.RequestURI()+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func replay(w http.ResponseWriter, record *model.IdempotencyRecord) {
+	for name, value := range record.Header {
+		w.Header().Set(name, value)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.StatusCode)
+	_, _ = w.Write(record.Body)
+}
+
+// responseRecorder passes a response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (recorder *responseRecorder) WriteHeader(status int) {
+	if recorder.status == 0 {
+		recorder.status = status
+	}
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *responseRecorder) Write(b []byte) (int, error) {
+	if recorder.status == 0 {
+		recorder.status = http.StatusOK
+	}
+	recorder.body.Write(b)
+	return recorder.ResponseWriter.Write(b)
+}
+
+func (recorder *responseRecorder) statusCode() int {
+	if recorder.status == 0 {
+		return http.StatusOK
+	}
+	return recorder.status
+}
@@ -0,0 +1,119 @@
+package dao
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/pkg"
+	"log"
+	"time"
+)
+
+const apiTokenTableName string = "api_tokens"
+const apiTokenColumns string = "id, user_id, name, token_hash, scope, expires_at, last_used_at, revoked_at, created_at"
+
+type MysqlAPITokenStore struct {
+	db *sql.DB
+}
+
+func NewMysqlAPITokenStore(db *sql.DB) *MysqlAPITokenStore {
+	return &MysqlAPITokenStore{
+		db: db,
+	}
+}
+
+func (mysql *MysqlAPITokenStore) Save(token *model.APIToken) (*model.APIToken, error) {
+	query := fmt.Sprintf("INSERT INTO %s (user_id, name, token_hash, scope, expires_at) VALUES (?, ?, ?, ?, ?)", apiTokenTableName)
+	inserted, err := mysql.db.Exec(query, token.UserID, token.Name, token.TokenHash, token.Scope, nullTime(token.ExpiresAt))
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert api token: %s", err.Error()), Err: err}
+	}
+	token.ID, err = inserted.LastInsertId()
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert api token: %s", err.Error()), Err: err}
+	}
+	return token, nil
+}
+
+func (mysql *MysqlAPITokenStore) FindByHash(hash string) (*model.APIToken, error) {
+	query := fmt.Sprintf("select %s from %s where token_hash = ?", apiTokenColumns, apiTokenTableName)
+	token, err := scanAPIToken(mysql.db.QueryRow(query, hash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("api token: %w", pkg.ErrNotFound)
+		}
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Data Access Error: %s", err.Error()), Err: err}
+	}
+	return token, nil
+}
+
+func (mysql *MysqlAPITokenStore) FindByUserId(userID int64) ([]*model.APIToken, error) {
+	query := fmt.Sprintf("select %s from %s where user_id = ? order by created_at desc, id desc", apiTokenColumns, apiTokenTableName)
+	results, err := mysql.db.Query(query, userID)
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query api tokens: %s", err.Error()), Err: err}
+	}
+	defer func(results *sql.Rows) {
+		err := results.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(results)
+
+	var tokens []*model.APIToken
+	for results.Next() {
+		token, err := scanAPIToken(results)
+		if err != nil {
+			return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query api tokens: %s", err.Error()), Err: err}
+		}
+		tokens = append(tokens, token)
+	}
+	if err = results.Err(); err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query api tokens: %s", err.Error()), Err: err}
+	}
+	return tokens, nil
+}
+
+// Revoke marks the token of userID as revoked. Revoking an unknown or
+// foreign token reports not found.
+func (mysql *MysqlAPITokenStore) Revoke(userID int64, id int64, at time.Time) error {
+	query := fmt.Sprintf("UPDATE %s SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL", apiTokenTableName)
+	result, err := mysql.db.Exec(query, at, id, userID)
+	if err != nil {
+		return &pkg.TaskError{Message: fmt.Sprintf("Failed to revoke api token: %s", err.Error()), Err: err}
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("api token %d: %w", id, pkg.ErrNotFound)
+	}
+	return nil
+}
+
+func (mysql *MysqlAPITokenStore) Touch(id int64, at time.Time) error {
+	query := fmt.Sprintf("UPDATE %s SET last_used_at = ? WHERE id = ?", apiTokenTableName)
+	_, err := mysql.db.Exec(query, at, id)
+	if err != nil {
+		return &pkg.TaskError{Message: fmt.Sprintf("Failed to update api token: %s", err.Error()), Err: err}
+	}
+	return nil
+}
+
+func scanAPIToken(row scanner) (*model.APIToken, error) {
+	var token model.APIToken
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	err := row.Scan(&token.ID, &token.UserID, &token.Name, &token.TokenHash, &token.Scope, &expiresAt, &lastUsedAt, &revokedAt, &token.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	token.ExpiresAt = timePtr(expiresAt)
+	token.LastUsedAt = timePtr(lastUsedAt)
+	token.RevokedAt = timePtr(revokedAt)
+	return &token, nil
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,110 @@
+package dao
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/pkg"
+	"log"
+)
+
+const commentTableName string = "task_comments"
+const commentColumns string = "id, workspace_id, task_id, author, body, created_at, updated_at"
+
+type MysqlCommentStore struct {
+	db *sql.DB
+}
+
+func NewMysqlCommentStore(db *sql.DB) *MysqlCommentStore {
+	return &MysqlCommentStore{
+		db: db,
+	}
+}
+
+func (mysql *MysqlCommentStore) Save(comment *model.Comment) (*model.Comment, error) {
+	if comment.ID != 0 {
+		query := fmt.Sprintf("UPDATE %s SET body = ? WHERE id = ? AND workspace_id = ?", commentTableName)
+		_, err := mysql.db.Exec(query, comment.Body, comment.ID, comment.WorkspaceID)
+		if err != nil {
+			return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to update comment: %s", err.Error()), Err: err}
+		}
+		return comment, nil
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (workspace_id, task_id, author, body) VALUES (?, ?, ?, ?)", commentTableName)
+	inserted, err := mysql.db.Exec(query, comment.WorkspaceID, comment.TaskID, comment.Author, comment.Body)
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert comment: %s", err.Error()), Err: err}
+	}
+	comment.ID, err = inserted.LastInsertId()
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert comment: %s", err.Error()), Err: err}
+	}
+	return comment, nil
+}
+
+func (mysql *MysqlCommentStore) FindById(workspaceID int64, id int64) (*model.Comment, error) {
+	query := fmt.Sprintf("select %s from %s where id = ? and workspace_id = ?", commentColumns, commentTableName)
+	var comment model.Comment
+	err := mysql.db.QueryRow(query, id, workspaceID).Scan(&comment.ID, &comment.WorkspaceID, &comment.TaskID, &comment.Author, &comment.Body, &comment.CreatedAt, &comment.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("comment %d: %w", id, pkg.ErrNotFound)
+		}
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Data Access Error: %s", err.Error()), Err: err}
+	}
+	return &comment, nil
+}
+
+func (mysql *MysqlCommentStore) FindByTaskId(workspaceID int64, taskID int64) ([]*model.Comment, error) {
+	query := fmt.Sprintf("select %s from %s where workspace_id = ? and task_id = ? order by created_at, id", commentColumns, commentTableName)
+	return mysql.query(query, workspaceID, taskID)
+}
+
+// FindByTaskIds lists the comments on several tasks, oldest first.
+func (mysql *MysqlCommentStore) FindByTaskIds(workspaceID int64, taskIDs []int64) ([]*model.Comment, error) {
+	if len(taskIDs) == 0 {
+		return nil, nil
+	}
+	in, args := inList(taskIDs)
+	query := fmt.Sprintf("select %s from %s where workspace_id = ? and task_id in (%s) order by created_at, id", commentColumns, commentTableName, in)
+	return mysql.query(query, append([]any{workspaceID}, args...)...)
+}
+
+func (mysql *MysqlCommentStore) query(query string, args ...any) ([]*model.Comment, error) {
+	results, err := mysql.db.Query(query, args...)
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query comments: %s", err.Error()), Err: err}
+	}
+	defer func(results *sql.Rows) {
+		err := results.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(results)
+
+	var comments []*model.Comment
+	for results.Next() {
+		var comment model.Comment
+		err := results.Scan(&comment.ID, &comment.WorkspaceID, &comment.TaskID, &comment.Author, &comment.Body, &comment.CreatedAt, &comment.UpdatedAt)
+		if err != nil {
+			return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query comments: %s", err.Error()), Err: err}
+		}
+		comments = append(comments, &comment)
+	}
+	if err = results.Err(); err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query comments: %s", err.Error()), Err: err}
+	}
+
+	return comments, nil
+}
+
+func (mysql *MysqlCommentStore) Delete(workspaceID int64, id int64) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = ? AND workspace_id = ?", commentTableName)
+	_, err := mysql.db.Exec(query, id, workspaceID)
+	if err != nil {
+		return &pkg.TaskError{Message: fmt.Sprintf("Failed to delete comment: %s", err.Error()), Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package dao
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/pkg"
+	"log"
+)
+
+const eventTableName string = "task_events"
+const eventColumns string = "id, workspace_id, task_id, actor, operation, changes, created_at"
+
+// MysqlEventStore is append-only: events are never updated or deleted.
+type MysqlEventStore struct {
+	db querier
+}
+
+func NewMysqlEventStore(db *sql.DB) *MysqlEventStore {
+	return &MysqlEventStore{
+		db: db,
+	}
+}
+
+func (mysql *MysqlEventStore) Append(event *model.TaskEvent) (*model.TaskEvent, error) {
+	changes, err := json.Marshal(event.Changes)
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert task event: %s", err.Error()), Err: err}
+	}
+	query := fmt.Sprintf("INSERT INTO %s (workspace_id, task_id, actor, operation, changes, created_at) VALUES (?, ?, ?, ?, ?, ?)", eventTableName)
+	inserted, err := mysql.db.Exec(query, event.WorkspaceID, event.TaskID, event.Actor, event.Operation, changes, event.CreatedAt)
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert task event: %s", err.Error()), Err: err}
+	}
+	event.ID, err = inserted.LastInsertId()
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert task event: %s", err.Error()), Err: err}
+	}
+	return event, nil
+}
+
+func (mysql *MysqlEventStore) FindByTaskId(workspaceID int64, taskID int64) ([]*model.TaskEvent, error) {
+	query := fmt.Sprintf("select %s from %s where workspace_id = ? and task_id = ? order by created_at, id", eventColumns, eventTableName)
+	return mysql.query(query, workspaceID, taskID)
+}
+
+// FindByTaskIds returns the events of several tasks, oldest first.
+func (mysql *MysqlEventStore) FindByTaskIds(workspaceID int64, taskIDs []int64) ([]*model.TaskEvent, error) {
+	if len(taskIDs) == 0 {
+		return nil, nil
+	}
+	in, args := inList(taskIDs)
+	query := fmt.Sprintf("select %s from %s where workspace_id = ? and task_id in (%s) order by created_at, id", eventColumns, eventTableName, in)
+	return mysql.query(query, append([]any{workspaceID}, args...)...)
+}
+
+func (mysql *MysqlEventStore) query(query string, args ...any) ([]*model.TaskEvent, error) {
+	results, err := mysql.db.Query(query, args...)
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query task events: %s", err.Error()), Err: err}
+	}
+	defer func(results *sql.Rows) {
+		err := results.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(results)
+
+	var events []*model.TaskEvent
+	for results.Next() {
+		var event model.TaskEvent
+		var changes []byte
+		err := results.Scan(&event.ID, &event.WorkspaceID, &event.TaskID, &event.Actor, &event.Operation, &changes, &event.CreatedAt)
+		if err != nil {
+			return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query task events: %s", err.Error()), Err: err}
+		}
+		if err := json.Unmarshal(changes, &event.Changes); err != nil {
+			return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query task events: %s", err.Error()), Err: err}
+		}
+		events = append(events, &event)
+	}
+	if err = results.Err(); err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query task events: %s", err.Error()), Err: err}
+	}
+
+	return events, nil
+}
@@ -0,0 +1,107 @@
+package dao
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/pkg"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const idempotencyTableName string = "idempotency_keys"
+const idempotencyColumns string = "user_id, idempotency_key, request_hash, status_code, response_headers, response_body, created_at, expires_at"
+
+type MysqlIdempotencyStore struct {
+	db *sql.DB
+}
+
+func NewMysqlIdempotencyStore(db *sql.DB) *MysqlIdempotencyStore {
+	return &MysqlIdempotencyStore{
+		db: db,
+	}
+}
+
+// Reserve stores a record for a request that is about to be handled. The
+// unique key makes concurrent retries race for it; the losers get
+// ErrConflict.
+func (store *MysqlIdempotencyStore) Reserve(record *model.IdempotencyRecord) error {
+	query := fmt.Sprintf("INSERT INTO %s (user_id, idempotency_key, request_hash, created_at, expires_at) VALUES (?, ?, ?, ?, ?)", idempotencyTableName)
+	_, err := store.db.Exec(query, record.UserID, record.Key, record.RequestHash, record.CreatedAt, record.ExpiresAt)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+			return fmt.Errorf("idempotency key %q: %w", record.Key, pkg.ErrConflict)
+		}
+		return &pkg.TaskError{Message: fmt.Sprintf("Failed to reserve idempotency key: %s", err.Error()), Err: err}
+	}
+	return nil
+}
+
+func (store *MysqlIdempotencyStore) Find(userID int64, key string) (*model.IdempotencyRecord, error) {
+	query := fmt.Sprintf("select %s from %s where user_id = ? and idempotency_key = ?", idempotencyColumns, idempotencyTableName)
+	record, err := scanIdempotencyRecord(store.db.QueryRow(query, userID, key))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("idempotency key %q: %w", key, pkg.ErrNotFound)
+		}
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Data Access Error: %s", err.Error()), Err: err}
+	}
+	return record, nil
+}
+
+// Complete stores the response the request was answered with.
+func (store *MysqlIdempotencyStore) Complete(record *model.IdempotencyRecord) error {
+	header, err := json.Marshal(record.Header)
+	if err != nil {
+		return &pkg.TaskError{Message: fmt.Sprintf("Failed to store idempotent response: %s", err.Error()), Err: err}
+	}
+	query := fmt.Sprintf("UPDATE %s SET status_code = ?, response_headers = ?, response_body = ? WHERE user_id = ? AND idempotency_key = ?", idempotencyTableName)
+	_, err = store.db.Exec(query, record.StatusCode, header, record.Body, record.UserID, record.Key)
+	if err != nil {
+		return &pkg.TaskError{Message: fmt.Sprintf("Failed to store idempotent response: %s", err.Error()), Err: err}
+	}
+	return nil
+}
+
+func (store *MysqlIdempotencyStore) Delete(userID int64, key string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE user_id = ? AND idempotency_key = ?", idempotencyTableName)
+	if _, err := store.db.Exec(query, userID, key); err != nil {
+		return &pkg.TaskError{Message: fmt.Sprintf("Failed to release idempotency key: %s", err.Error()), Err: err}
+	}
+	return nil
+}
+
+// Purge removes the records that expired before cutoff.
+func (store *MysqlIdempotencyStore) Purge(cutoff time.Time) (int64, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE expires_at < ?", idempotencyTableName)
+	result, err := store.db.Exec(query, cutoff)
+	if err != nil {
+		return 0, &pkg.TaskError{Message: fmt.Sprintf("Failed to purge idempotency keys: %s", err.Error()), Err: err}
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, &pkg.TaskError{Message: fmt.Sprintf("Failed to purge idempotency keys: %s", err.Error()), Err: err}
+	}
+	return purged, nil
+}
+
+func scanIdempotencyRecord(row scanner) (*model.IdempotencyRecord, error) {
+	var record model.IdempotencyRecord
+	var statusCode sql.NullInt32
+	var header []byte
+	err := row.Scan(&record.UserID, &record.Key, &record.RequestHash, &statusCode, &header, &record.Body, &record.CreatedAt, &record.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	record.StatusCode = int(statusCode.Int32)
+	if header != nil {
+		if err := json.Unmarshal(header, &record.Header); err != nil {
+			return nil, err
+		}
+	}
+	return &record, nil
+}
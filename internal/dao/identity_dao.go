@@ -0,0 +1,47 @@
+package dao
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/pkg"
+)
+
+const identityTableName string = "user_identities"
+
+type MysqlIdentityStore struct {
+	db *sql.DB
+}
+
+func NewMysqlIdentityStore(db *sql.DB) *MysqlIdentityStore {
+	return &MysqlIdentityStore{
+		db: db,
+	}
+}
+
+func (store *MysqlIdentityStore) Save(identity *model.Identity) (*model.Identity, error) {
+	query := fmt.Sprintf("INSERT INTO %s (user_id, issuer, subject) VALUES (?, ?, ?)", identityTableName)
+	inserted, err := store.db.Exec(query, identity.UserID, identity.Issuer, identity.Subject)
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert identity: %s", err.Error()), Err: err}
+	}
+	identity.ID, err = inserted.LastInsertId()
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert identity: %s", err.Error()), Err: err}
+	}
+	return identity, nil
+}
+
+func (store *MysqlIdentityStore) FindBySubject(issuer string, subject string) (*model.Identity, error) {
+	query := fmt.Sprintf("select id, user_id, issuer, subject, created_at from %s where issuer = ? and subject = ?", identityTableName)
+	var identity model.Identity
+	err := store.db.QueryRow(query, issuer, subject).Scan(&identity.ID, &identity.UserID, &identity.Issuer, &identity.Subject, &identity.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("identity %s: %w", subject, pkg.ErrNotFound)
+		}
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Data Access Error: %s", err.Error()), Err: err}
+	}
+	return &identity, nil
+}
@@ -0,0 +1,146 @@
+package dao
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/pkg"
+	"log"
+)
+
+const projectTableName string = "projects"
+const projectColumns string = "id, workspace_id, name, description, archived, created_at, updated_at"
+
+type MysqlProjectStore struct {
+	db *sql.DB
+}
+
+func NewMysqlProjectStore(db *sql.DB) *MysqlProjectStore {
+	return &MysqlProjectStore{
+		db: db,
+	}
+}
+
+func (mysql *MysqlProjectStore) Save(project *model.Project) (*model.Project, error) {
+	if project.ID != 0 {
+		query := fmt.Sprintf("UPDATE %s SET name = ?, description = ?, archived = ? WHERE id = ? AND workspace_id = ?", projectTableName)
+		_, err := mysql.db.Exec(query, project.Name, project.Description, project.Archived, project.ID, project.WorkspaceID)
+		if err != nil {
+			return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to update project: %s", err.Error()), Err: err}
+		}
+		return project, nil
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (workspace_id, name, description, archived) VALUES (?, ?, ?, ?)", projectTableName)
+	inserted, err := mysql.db.Exec(query, project.WorkspaceID, project.Name, project.Description, project.Archived)
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert project: %s", err.Error()), Err: err}
+	}
+	project.ID, err = inserted.LastInsertId()
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert project: %s", err.Error()), Err: err}
+	}
+	return project, nil
+}
+
+func (mysql *MysqlProjectStore) FindById(workspaceID int64, id int64) (*model.Project, error) {
+	query := fmt.Sprintf("select %s from %s where id = ? and workspace_id = ?", projectColumns, projectTableName)
+	project, err := scanProject(mysql.db.QueryRow(query, id, workspaceID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("project %d: %w", id, pkg.ErrNotFound)
+		}
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Data Access Error: %s", err.Error()), Err: err}
+	}
+	return project, nil
+}
+
+// FindByIds loads the projects of a workspace with the given ids in no
+// particular order. Ids that are not found are left out.
+func (mysql *MysqlProjectStore) FindByIds(workspaceID int64, ids []int64) ([]*model.Project, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	in, args := inList(ids)
+	query := fmt.Sprintf("select %s from %s where workspace_id = ? and id in (%s)", projectColumns, projectTableName, in)
+	return mysql.query(query, append([]any{workspaceID}, args...)...)
+}
+
+// FindAll lists the projects of a workspace by name, leaving out archived
+// ones unless includeArchived is set.
+func (mysql *MysqlProjectStore) FindAll(workspaceID int64, includeArchived bool) ([]*model.Project, error) {
+	query := fmt.Sprintf("select %s from %s where workspace_id = ? and (archived = false or ?) order by name", projectColumns, projectTableName)
+	return mysql.query(query, workspaceID, includeArchived)
+}
+
+func (mysql *MysqlProjectStore) query(query string, args ...any) ([]*model.Project, error) {
+	results, err := mysql.db.Query(query, args...)
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query projects: %s", err.Error()), Err: err}
+	}
+	defer func(results *sql.Rows) {
+		err := results.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(results)
+
+	var projects []*model.Project
+	for results.Next() {
+		project, err := scanProject(results)
+		if err != nil {
+			return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query projects: %s", err.Error()), Err: err}
+		}
+		projects = append(projects, project)
+	}
+	if err = results.Err(); err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query projects: %s", err.Error()), Err: err}
+	}
+	return projects, nil
+}
+
+// CountByStatus counts the live tasks of every project in a workspace,
+// keyed by project id. Projects without tasks are absent.
+func (mysql *MysqlProjectStore) CountByStatus(workspaceID int64) (map[int64]model.StatusCounts, error) {
+	query := fmt.Sprintf("select project_id, status, count(*) from %s where workspace_id = ? and project_id is not null and deleted_at is null group by project_id, status", tableName)
+	results, err := mysql.db.Query(query, workspaceID)
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to count tasks: %s", err.Error()), Err: err}
+	}
+	defer func(results *sql.Rows) {
+		err := results.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(results)
+
+	counts := map[int64]model.StatusCounts{}
+	for results.Next() {
+		var projectID int64
+		var status pkg.TaskStatus
+		var count int
+		if err := results.Scan(&projectID, &status, &count); err != nil {
+			return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to count tasks: %s", err.Error()), Err: err}
+		}
+		if counts[projectID] == nil {
+			counts[projectID] = model.StatusCounts{}
+		}
+		counts[projectID][status] = count
+	}
+	if err = results.Err(); err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to count tasks: %s", err.Error()), Err: err}
+	}
+	return counts, nil
+}
+
+func scanProject(row scanner) (*model.Project, error) {
+	var project model.Project
+	var description sql.NullString
+	err := row.Scan(&project.ID, &project.WorkspaceID, &project.Name, &description, &project.Archived, &project.CreatedAt, &project.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	project.Description = description.String
+	return &project, nil
+}
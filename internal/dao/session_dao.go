@@ -0,0 +1,52 @@
+package dao
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/pkg"
+)
+
+const sessionTableName string = "sessions"
+
+type MysqlSessionStore struct {
+	db *sql.DB
+}
+
+func NewMysqlSessionStore(db *sql.DB) *MysqlSessionStore {
+	return &MysqlSessionStore{
+		db: db,
+	}
+}
+
+func (store *MysqlSessionStore) Save(session *model.Session) (*model.Session, error) {
+	query := fmt.Sprintf("INSERT INTO %s (id, user_id, expires_at) VALUES (?, ?, ?)", sessionTableName)
+	_, err := store.db.Exec(query, session.ID, session.UserID, session.ExpiresAt)
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert session: %s", err.Error()), Err: err}
+	}
+	return session, nil
+}
+
+func (store *MysqlSessionStore) FindById(id string) (*model.Session, error) {
+	query := fmt.Sprintf("select id, user_id, expires_at, created_at from %s where id = ?", sessionTableName)
+	var session model.Session
+	err := store.db.QueryRow(query, id).Scan(&session.ID, &session.UserID, &session.ExpiresAt, &session.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("session: %w", pkg.ErrNotFound)
+		}
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Data Access Error: %s", err.Error()), Err: err}
+	}
+	return &session, nil
+}
+
+func (store *MysqlSessionStore) Delete(id string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = ?", sessionTableName)
+	_, err := store.db.Exec(query, id)
+	if err != nil {
+		return &pkg.TaskError{Message: fmt.Sprintf("Failed to delete session: %s", err.Error()), Err: err}
+	}
+	return nil
+}
@@ -3,43 +3,72 @@ package dao
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	db "go-task/internal/db/go-task"
 	"go-task/internal/model"
 	"go-task/pkg"
 	"log"
+	"strings"
+	"time"
 )
 
 const tableName string = "tasks"
+const deadLetterTableName string = "dead_letter_tasks"
+const taskColumns string = "id, workspace_id, project_id, title, content, status, labels, due_at, recurrence, `rank`, created_by, updated_by, created_at, updated_at, deleted_at"
+
+// querier runs the statements of a store, either directly on the database
+// or inside a transaction.
+type querier interface {
+	db.DBTX
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
 
 type MysqlStore struct {
 	db       *sql.DB
-	taskChan chan *model.Task
+	conn     querier
+	taskChan chan []*model.Task
+	// pending collects the changed tasks of a transaction, which are
+	// only published once it commits.
+	pending *[]*model.Task
 }
 
-func NewMysqlStore(db *sql.DB, taskChan chan *model.Task) *MysqlStore {
+func NewMysqlStore(db *sql.DB, taskChan chan []*model.Task) *MysqlStore {
 	return &MysqlStore{
 		db:       db,
+		conn:     db,
 		taskChan: taskChan,
 	}
 }
 
 func (mysql *MysqlStore) Save(task *model.Task) (*model.Task, error) {
-	query := fmt.Sprintf("INSERT INTO %s (title, content, status) VALUES (?, ?, ?)", tableName)
-	sqlc := db.New(mysql.db)
-	_, err := sqlc.SaveTask(
+	if task.ID != 0 {
+		return mysql.update(task)
+	}
+
+	labels, err := marshalLabels(task.Labels)
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert task: %s", err.Error()), Err: err}
+	}
+	sqlc := db.New(mysql.conn)
+	inserted, err := sqlc.SaveTask(
 		context.Background(),
 		db.SaveTaskParams{
-			Title:   task.Title,
-			Content: sql.NullString{String: task.Content},
-			Status:  db.TasksStatus(string(task.Status)),
+			WorkspaceID: task.WorkspaceID,
+			ProjectID:   nullInt64(task.ProjectID),
+			Title:       task.Title,
+			Content:     sql.NullString{String: task.Content, Valid: true},
+			Status:      db.TasksStatus(string(task.Status)),
+			Labels:      labels,
+			DueAt:       nullTime(task.DueAt),
+			Recurrence:  nullRecurrence(task.Recurrence),
+			Rank:        task.Rank,
+			CreatedBy:   nullInt64(task.CreatedBy),
+			UpdatedBy:   nullInt64(task.UpdatedBy),
 		})
-	if err != nil {
-		return nil, err
-	}
-	inserted, err := mysql.db.Exec(query, task.Title, task.Content, task.Status)
-
 	if err != nil {
 		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert task: %s", err.Error()), Err: err}
 	}
@@ -47,31 +76,192 @@ func (mysql *MysqlStore) Save(task *model.Task) (*model.Task, error) {
 	if err != nil {
 		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert task: %s", err.Error()), Err: err}
 	}
-	mysql.taskChan <- task
+	mysql.publish(task)
 	return task, nil
 }
 
-func (mysql *MysqlStore) FindById(id int64) (*model.Task, error) {
-	var task model.Task
-	query := "select * from tasks where id = ?"
-	result := mysql.db.QueryRow(query, id)
+func (mysql *MysqlStore) update(task *model.Task) (*model.Task, error) {
+	labels, err := marshalLabels(task.Labels)
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to update task: %s", err.Error()), Err: err}
+	}
+	query := fmt.Sprintf("UPDATE %s SET project_id = ?, title = ?, content = ?, status = ?, labels = ?, due_at = ?, recurrence = ?, `rank` = ?, updated_by = ?, deleted_at = ? WHERE id = ? AND workspace_id = ?", tableName)
+	_, err = mysql.conn.Exec(query,
+		nullInt64(task.ProjectID),
+		task.Title,
+		task.Content,
+		task.Status,
+		labels,
+		nullTime(task.DueAt),
+		nullRecurrence(task.Recurrence),
+		task.Rank,
+		nullInt64(task.UpdatedBy),
+		nullTime(task.DeletedAt),
+		task.ID,
+		task.WorkspaceID,
+	)
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to update task: %s", err.Error()), Err: err}
+	}
+	mysql.publish(task)
+	return task, nil
+}
+
+// Reindex queues task for search synchronisation without writing it, for
+// changes to data that lives outside the tasks table.
+func (mysql *MysqlStore) Reindex(task *model.Task) {
+	mysql.publish(task)
+}
+
+func (mysql *MysqlStore) publish(task *model.Task) {
+	if mysql.pending != nil {
+		*mysql.pending = append(*mysql.pending, task)
+		return
+	}
+	mysql.taskChan <- []*model.Task{task}
+}
+
+// FindById only finds tasks of workspaceID; a task of another workspace is
+// reported as not found rather than forbidden so its existence is not leaked.
+func (mysql *MysqlStore) FindById(workspaceID int64, id int64) (*model.Task, error) {
+	query := fmt.Sprintf("select %s from %s where id = ? and workspace_id = ?", taskColumns, tableName)
+	result := mysql.conn.QueryRow(query, id, workspaceID)
 
-	err := result.Scan(&task.ID, &task.Title, &task.Content, &task.Status, &task.CreatedAt, &task.UpdatedAt, &task.DeletedAt)
+	task, err := scanTask(result)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("task not found", &pkg.ErrNotFound)
+			return nil, fmt.Errorf("task %d: %w", id, pkg.ErrNotFound)
 		}
 		return nil, &pkg.TaskError{Message: fmt.Sprintf("Data Access Error: %s", err.Error()), Err: err}
 	}
 
-	return &task, nil
+	return task, nil
+}
+
+// FindAll lists the live tasks of a workspace in rank order.
+func (mysql *MysqlStore) FindAll(workspaceID int64) ([]*model.Task, error) {
+	q := fmt.Sprintf("select %s from %s where workspace_id = ? and deleted_at is null order by `rank`, id", taskColumns, tableName)
+	return mysql.query(q, workspaceID)
+}
+
+// FindPage lists up to limit live tasks of a workspace in rank order,
+// starting after the cursor when it is set.
+func (mysql *MysqlStore) FindPage(workspaceID int64, after *model.TaskCursor, limit int) ([]*model.Task, error) {
+	if after == nil {
+		q := fmt.Sprintf("select %s from %s where workspace_id = ? and deleted_at is null order by `rank`, id limit ?", taskColumns, tableName)
+		return mysql.query(q, workspaceID, limit)
+	}
+	q := fmt.Sprintf("select %s from %s where workspace_id = ? and deleted_at is null and (`rank` > ? or (`rank` = ? and id > ?)) order by `rank`, id limit ?", taskColumns, tableName)
+	return mysql.query(q, workspaceID, after.Rank, after.Rank, after.ID, limit)
 }
 
-func (mysql *MysqlStore) FindAll() ([]*model.Task, error) {
-	q := fmt.Sprintf("select * from %s where deleted_at is null", tableName)
-	results, err := mysql.db.Query(q)
+// LastRank returns the highest rank in a workspace, zero when it has no
+// tasks yet, so new tasks can be appended at the end.
+func (mysql *MysqlStore) LastRank(workspaceID int64) (float64, error) {
+	var rank float64
+	query := fmt.Sprintf("select coalesce(max(`rank`), 0) from %s where workspace_id = ?", tableName)
+	if err := mysql.conn.QueryRow(query, workspaceID).Scan(&rank); err != nil {
+		return 0, &pkg.TaskError{Message: fmt.Sprintf("Data Access Error: %s", err.Error()), Err: err}
+	}
+	return rank, nil
+}
+
+// Rebalance spreads the ranks of a workspace back out to 1, 2, 3, ...
+// keeping their order, once fractional moves have used up the room between
+// two neighbours. Deleted tasks keep their place so a restore lands where
+// the task was. It runs on the store's connection, so inside a transaction
+// the renumbering commits or rolls back with the move that needed it, and
+// the renumbered tasks are published once it commits.
+func (mysql *MysqlStore) Rebalance(workspaceID int64) error {
+	query := fmt.Sprintf("select %s from %s where workspace_id = ? order by `rank`, id for update", taskColumns, tableName)
+	tasks, err := mysql.query(query, workspaceID)
 	if err != nil {
-		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert task: %s", err.Error()), Err: err}
+		return err
+	}
+
+	update := fmt.Sprintf("UPDATE %s SET `rank` = ? WHERE id = ?", tableName)
+	for i, task := range tasks {
+		rank := float64(i + 1)
+		if task.Rank == rank {
+			continue
+		}
+		if _, err := mysql.conn.Exec(update, rank, task.ID); err != nil {
+			return &pkg.TaskError{Message: fmt.Sprintf("Failed to rebalance ranks: %s", err.Error()), Err: err}
+		}
+		task.Rank = rank
+		mysql.publish(task)
+	}
+	return nil
+}
+
+// FindByProject lists the live tasks of a project in rank order.
+func (mysql *MysqlStore) FindByProject(workspaceID int64, projectID int64) ([]*model.Task, error) {
+	q := fmt.Sprintf("select %s from %s where workspace_id = ? and project_id = ? and deleted_at is null order by `rank`, id", taskColumns, tableName)
+	return mysql.query(q, workspaceID, projectID)
+}
+
+// FindByIds loads the tasks of a workspace with the given ids, deleted ones
+// included, in no particular order. Ids that are not found are left out.
+func (mysql *MysqlStore) FindByIds(workspaceID int64, ids []int64) ([]*model.Task, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	in, args := inList(ids)
+	q := fmt.Sprintf("select %s from %s where workspace_id = ? and id in (%s)", taskColumns, tableName, in)
+	return mysql.query(q, append([]any{workspaceID}, args...)...)
+}
+
+// FindByProjects lists the live tasks of several projects in rank order.
+func (mysql *MysqlStore) FindByProjects(workspaceID int64, projectIDs []int64) ([]*model.Task, error) {
+	if len(projectIDs) == 0 {
+		return nil, nil
+	}
+	in, args := inList(projectIDs)
+	q := fmt.Sprintf("select %s from %s where workspace_id = ? and project_id in (%s) and deleted_at is null order by `rank`, id", taskColumns, tableName, in)
+	return mysql.query(q, append([]any{workspaceID}, args...)...)
+}
+
+// FindDeleted lists the soft-deleted tasks that have not been purged yet,
+// most recently deleted first.
+func (mysql *MysqlStore) FindDeleted(workspaceID int64) ([]*model.Task, error) {
+	q := fmt.Sprintf("select %s from %s where workspace_id = ? and deleted_at is not null order by deleted_at desc", taskColumns, tableName)
+	return mysql.query(q, workspaceID)
+}
+
+// Purge permanently removes the tasks soft-deleted before cutoff together
+// with their dead letter rows, and returns how many tasks were removed. It
+// runs across all workspaces since retention is a deployment setting.
+func (mysql *MysqlStore) Purge(cutoff time.Time) (int64, error) {
+	tx, err := mysql.db.Begin()
+	if err != nil {
+		return 0, &pkg.TaskError{Message: fmt.Sprintf("Failed to purge tasks: %s", err.Error()), Err: err}
+	}
+	defer func(tx *sql.Tx) {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			log.Println(err)
+		}
+	}(tx)
+
+	deadLetters := fmt.Sprintf("DELETE d FROM %s d JOIN %s t ON d.task_id = t.id WHERE t.deleted_at < ?", deadLetterTableName, tableName)
+	if _, err := tx.Exec(deadLetters, cutoff); err != nil {
+		return 0, &pkg.TaskError{Message: fmt.Sprintf("Failed to purge tasks: %s", err.Error()), Err: err}
+	}
+	tasks := fmt.Sprintf("DELETE FROM %s WHERE deleted_at < ?", tableName)
+	purged, err := tx.Exec(tasks, cutoff)
+	if err != nil {
+		return 0, &pkg.TaskError{Message: fmt.Sprintf("Failed to purge tasks: %s", err.Error()), Err: err}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, &pkg.TaskError{Message: fmt.Sprintf("Failed to purge tasks: %s", err.Error()), Err: err}
+	}
+	return purged.RowsAffected()
+}
+
+func (mysql *MysqlStore) query(q string, args ...any) ([]*model.Task, error) {
+	results, err := mysql.conn.Query(q, args...)
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query tasks: %s", err.Error()), Err: err}
 	}
 	defer func(results *sql.Rows) {
 		err := results.Close()
@@ -83,16 +273,94 @@ func (mysql *MysqlStore) FindAll() ([]*model.Task, error) {
 
 	var tasks []*model.Task
 	for results.Next() {
-		var task model.Task
-		err := results.Scan(&task.ID, &task.Title, &task.Content, &task.Status, &task.CreatedAt, &task.UpdatedAt, &task.DeletedAt)
+		task, err := scanTask(results)
 		if err != nil {
-			return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert task: %s", err.Error()), Err: err}
+			return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query tasks: %s", err.Error()), Err: err}
 		}
-		tasks = append(tasks, &task)
+		tasks = append(tasks, task)
 	}
 	if err = results.Err(); err != nil {
-		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert task: %s", err.Error()), Err: err}
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query tasks: %s", err.Error()), Err: err}
 	}
 
 	return tasks, nil
 }
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTask(row scanner) (*model.Task, error) {
+	var task model.Task
+	var content, recurrence sql.NullString
+	var labels []byte
+	var dueAt sql.NullTime
+	var projectID, createdBy, updatedBy sql.NullInt64
+	err := row.Scan(&task.ID, &task.WorkspaceID, &projectID, &task.Title, &content, &task.Status, &labels, &dueAt, &recurrence, &task.Rank, &createdBy, &updatedBy, &task.CreatedAt, &task.UpdatedAt, &task.DeletedAt)
+	if err != nil {
+		return nil, err
+	}
+	task.Content = content.String
+	if len(labels) > 0 {
+		if err := json.Unmarshal(labels, &task.Labels); err != nil {
+			return nil, err
+		}
+	}
+	if dueAt.Valid {
+		task.DueAt = &dueAt.Time
+	}
+	if projectID.Valid {
+		task.ProjectID = &projectID.Int64
+	}
+	if createdBy.Valid {
+		task.CreatedBy = &createdBy.Int64
+	}
+	if updatedBy.Valid {
+		task.UpdatedBy = &updatedBy.Int64
+	}
+	if recurrence.Valid {
+		task.Recurrence, err = model.ParseRecurrence(recurrence.String)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &task, nil
+}
+
+// inList returns the placeholders of an IN list of ids with the matching
+// query arguments.
+func inList(ids []int64) (string, []any) {
+	args := make([]any, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", "), args
+}
+
+func marshalLabels(labels []string) (json.RawMessage, error) {
+	if labels == nil {
+		return nil, nil
+	}
+	return json.Marshal(labels)
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+func nullRecurrence(r *model.Recurrence) sql.NullString {
+	if r == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: r.String(), Valid: true}
+}
+
+func nullInt64(i *int64) sql.NullInt64 {
+	if i == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *i, Valid: true}
+}
@@ -0,0 +1,100 @@
+package dao
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/pkg"
+	"log"
+)
+
+// MysqlTransactor runs several task writes and their history in one
+// database transaction.
+type MysqlTransactor struct {
+	db       *sql.DB
+	taskChan chan []*model.Task
+}
+
+func NewMysqlTransactor(db *sql.DB, taskChan chan []*model.Task) *MysqlTransactor {
+	return &MysqlTransactor{
+		db:       db,
+		taskChan: taskChan,
+	}
+}
+
+// Transaction commits the writes fn makes through tasks and events when it
+// returns nil and rolls them back otherwise. The changed tasks are
+// published together once the transaction is committed, so the search
+// index gets them as one bulk request.
+func (transactor *MysqlTransactor) Transaction(fn func(tasks *MysqlStore, events *MysqlEventStore, savepoints *MysqlSavepoints) error) error {
+	tx, err := transactor.db.Begin()
+	if err != nil {
+		return &pkg.TaskError{Message: fmt.Sprintf("Failed to begin transaction: %s", err.Error()), Err: err}
+	}
+	defer func(tx *sql.Tx) {
+		err := tx.Rollback()
+		if err != nil && !errors.Is(err, sql.ErrTxDone) {
+			log.Println(err)
+		}
+	}(tx)
+
+	var changes []*model.Task
+	tasks := &MysqlStore{db: transactor.db, conn: tx, taskChan: transactor.taskChan, pending: &changes}
+	events := &MysqlEventStore{db: tx}
+	savepoints := &MysqlSavepoints{tx: tx, pending: &changes, marks: map[string]int{}}
+	if err := fn(tasks, events, savepoints); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return &pkg.TaskError{Message: fmt.Sprintf("Failed to commit transaction: %s", err.Error()), Err: err}
+	}
+	if len(changes) > 0 {
+		transactor.taskChan <- changes
+	}
+	return nil
+}
+
+// MysqlSavepoints rolls a transaction back to a savepoint while keeping
+// what was written before it. The tasks changed after the savepoint are
+// dropped from the ones published on commit as well.
+type MysqlSavepoints struct {
+	tx      *sql.Tx
+	pending *[]*model.Task
+	// marks holds how many changes were pending at each savepoint.
+	marks map[string]int
+}
+
+// Savepoint sets the savepoint name, moving it when it is already set.
+// name must be a plain identifier as it cannot be a query parameter.
+func (savepoints *MysqlSavepoints) Savepoint(name string) error {
+	if !identifier(name) {
+		return &pkg.TaskError{Message: "Failed to set savepoint", Err: fmt.Errorf("invalid savepoint name %q", name)}
+	}
+	if _, err := savepoints.tx.Exec("SAVEPOINT " + name); err != nil {
+		return &pkg.TaskError{Message: fmt.Sprintf("Failed to set savepoint: %s", err.Error()), Err: err}
+	}
+	savepoints.marks[name] = len(*savepoints.pending)
+	return nil
+}
+
+func (savepoints *MysqlSavepoints) RollbackTo(name string) error {
+	mark, ok := savepoints.marks[name]
+	if !ok {
+		return &pkg.TaskError{Message: "Failed to roll back to savepoint", Err: fmt.Errorf("savepoint %q is not set", name)}
+	}
+	if _, err := savepoints.tx.Exec("ROLLBACK TO SAVEPOINT " + name); err != nil {
+		return &pkg.TaskError{Message: fmt.Sprintf("Failed to roll back to savepoint: %s", err.Error()), Err: err}
+	}
+	*savepoints.pending = (*savepoints.pending)[:mark]
+	return nil
+}
+
+func identifier(name string) bool {
+	for i, r := range name {
+		if !(r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || i > 0 && '0' <= r && r <= '9') {
+			return false
+		}
+	}
+	return name != ""
+}
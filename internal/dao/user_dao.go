@@ -0,0 +1,115 @@
+package dao
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/pkg"
+	"log"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const userTableName string = "users"
+const userColumns string = "id, workspace_id, username, password_hash, role, created_at, updated_at"
+
+// mysqlDuplicateEntry is the server error number for a unique key violation.
+const mysqlDuplicateEntry uint16 = 1062
+
+type MysqlUserStore struct {
+	db *sql.DB
+}
+
+func NewMysqlUserStore(db *sql.DB) *MysqlUserStore {
+	return &MysqlUserStore{
+		db: db,
+	}
+}
+
+func (store *MysqlUserStore) Save(user *model.User) (*model.User, error) {
+	if user.ID != 0 {
+		query := fmt.Sprintf("UPDATE %s SET username = ?, password_hash = ?, role = ? WHERE id = ?", userTableName)
+		_, err := store.db.Exec(query, user.Username, user.PasswordHash, user.Role, user.ID)
+		if err != nil {
+			return nil, userWriteError(err)
+		}
+		return user, nil
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (workspace_id, username, password_hash, role) VALUES (?, ?, ?, ?)", userTableName)
+	inserted, err := store.db.Exec(query, user.WorkspaceID, user.Username, user.PasswordHash, user.Role)
+	if err != nil {
+		return nil, userWriteError(err)
+	}
+	user.ID, err = inserted.LastInsertId()
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert user: %s", err.Error()), Err: err}
+	}
+	return user, nil
+}
+
+func (store *MysqlUserStore) FindById(id int64) (*model.User, error) {
+	query := fmt.Sprintf("select %s from %s where id = ?", userColumns, userTableName)
+	return store.findOne(query, id)
+}
+
+func (store *MysqlUserStore) FindByUsername(username string) (*model.User, error) {
+	query := fmt.Sprintf("select %s from %s where username = ?", userColumns, userTableName)
+	return store.findOne(query, username)
+}
+
+func (store *MysqlUserStore) FindAll(workspaceID int64) ([]*model.User, error) {
+	query := fmt.Sprintf("select %s from %s where workspace_id = ? order by username", userColumns, userTableName)
+	results, err := store.db.Query(query, workspaceID)
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query users: %s", err.Error()), Err: err}
+	}
+	defer func(results *sql.Rows) {
+		err := results.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(results)
+
+	var users []*model.User
+	for results.Next() {
+		user, err := scanUser(results)
+		if err != nil {
+			return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query users: %s", err.Error()), Err: err}
+		}
+		users = append(users, user)
+	}
+	if err = results.Err(); err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query users: %s", err.Error()), Err: err}
+	}
+	return users, nil
+}
+
+func (store *MysqlUserStore) findOne(query string, arg any) (*model.User, error) {
+	user, err := scanUser(store.db.QueryRow(query, arg))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("user %v: %w", arg, pkg.ErrNotFound)
+		}
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Data Access Error: %s", err.Error()), Err: err}
+	}
+	return user, nil
+}
+
+func scanUser(row scanner) (*model.User, error) {
+	var user model.User
+	err := row.Scan(&user.ID, &user.WorkspaceID, &user.Username, &user.PasswordHash, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func userWriteError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+		return fmt.Errorf("username already taken: %w", pkg.ErrConflict)
+	}
+	return &pkg.TaskError{Message: fmt.Sprintf("Failed to save user: %s", err.Error()), Err: err}
+}
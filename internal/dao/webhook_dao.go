@@ -0,0 +1,220 @@
+package dao
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/pkg"
+	"log"
+)
+
+const webhookTableName string = "webhooks"
+const webhookColumns string = "id, workspace_id, url, secret, events, created_at"
+const deliveryTableName string = "webhook_deliveries"
+const deliveryColumns string = "id, webhook_id, event, payload, status, attempts, response_code, error_msg, created_at, updated_at"
+
+type MysqlWebhookStore struct {
+	db *sql.DB
+}
+
+func NewMysqlWebhookStore(db *sql.DB) *MysqlWebhookStore {
+	return &MysqlWebhookStore{
+		db: db,
+	}
+}
+
+func (mysql *MysqlWebhookStore) Save(webhook *model.Webhook) (*model.Webhook, error) {
+	events, err := json.Marshal(webhook.Events)
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert webhook: %s", err.Error()), Err: err}
+	}
+	query := fmt.Sprintf("INSERT INTO %s (workspace_id, url, secret, events) VALUES (?, ?, ?, ?)", webhookTableName)
+	inserted, err := mysql.db.Exec(query, webhook.WorkspaceID, webhook.URL, webhook.Secret, events)
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert webhook: %s", err.Error()), Err: err}
+	}
+	webhook.ID, err = inserted.LastInsertId()
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert webhook: %s", err.Error()), Err: err}
+	}
+	return webhook, nil
+}
+
+func (mysql *MysqlWebhookStore) FindById(workspaceID int64, id int64) (*model.Webhook, error) {
+	query := fmt.Sprintf("select %s from %s where id = ? and workspace_id = ?", webhookColumns, webhookTableName)
+	return mysql.findOne(query, id, id, workspaceID)
+}
+
+// FindWebhook looks a webhook up in any workspace, for replaying its
+// deliveries in the background.
+func (mysql *MysqlWebhookStore) FindWebhook(id int64) (*model.Webhook, error) {
+	query := fmt.Sprintf("select %s from %s where id = ?", webhookColumns, webhookTableName)
+	return mysql.findOne(query, id, id)
+}
+
+func (mysql *MysqlWebhookStore) findOne(query string, id int64, args ...any) (*model.Webhook, error) {
+	webhook, err := scanWebhook(mysql.db.QueryRow(query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("webhook %d: %w", id, pkg.ErrNotFound)
+		}
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Data Access Error: %s", err.Error()), Err: err}
+	}
+	return webhook, nil
+}
+
+func (mysql *MysqlWebhookStore) FindAll(workspaceID int64) ([]*model.Webhook, error) {
+	query := fmt.Sprintf("select %s from %s where workspace_id = ? order by id", webhookColumns, webhookTableName)
+	results, err := mysql.db.Query(query, workspaceID)
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query webhooks: %s", err.Error()), Err: err}
+	}
+	defer func(results *sql.Rows) {
+		err := results.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(results)
+
+	var webhooks []*model.Webhook
+	for results.Next() {
+		webhook, err := scanWebhook(results)
+		if err != nil {
+			return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query webhooks: %s", err.Error()), Err: err}
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err = results.Err(); err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query webhooks: %s", err.Error()), Err: err}
+	}
+	return webhooks, nil
+}
+
+// Delete removes a webhook together with its delivery log.
+func (mysql *MysqlWebhookStore) Delete(workspaceID int64, id int64) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = ? AND workspace_id = ?", webhookTableName)
+	result, err := mysql.db.Exec(query, id, workspaceID)
+	if err != nil {
+		return &pkg.TaskError{Message: fmt.Sprintf("Failed to delete webhook: %s", err.Error()), Err: err}
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return &pkg.TaskError{Message: fmt.Sprintf("Failed to delete webhook: %s", err.Error()), Err: err}
+	}
+	if affected == 0 {
+		return fmt.Errorf("webhook %d: %w", id, pkg.ErrNotFound)
+	}
+	return nil
+}
+
+func (mysql *MysqlWebhookStore) SaveDelivery(delivery *model.WebhookDelivery) (*model.WebhookDelivery, error) {
+	var responseCode sql.NullInt32
+	if delivery.ResponseCode != nil {
+		responseCode = sql.NullInt32{Int32: int32(*delivery.ResponseCode), Valid: true}
+	}
+	errorMsg := sql.NullString{String: delivery.Error, Valid: delivery.Error != ""}
+
+	if delivery.ID != 0 {
+		query := fmt.Sprintf("UPDATE %s SET status = ?, attempts = ?, response_code = ?, error_msg = ? WHERE id = ?", deliveryTableName)
+		_, err := mysql.db.Exec(query, delivery.Status, delivery.Attempts, responseCode, errorMsg, delivery.ID)
+		if err != nil {
+			return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to update webhook delivery: %s", err.Error()), Err: err}
+		}
+		return delivery, nil
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (webhook_id, event, payload, status, attempts, response_code, error_msg) VALUES (?, ?, ?, ?, ?, ?, ?)", deliveryTableName)
+	inserted, err := mysql.db.Exec(query, delivery.WebhookID, delivery.Event, delivery.Payload, delivery.Status, delivery.Attempts, responseCode, errorMsg)
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert webhook delivery: %s", err.Error()), Err: err}
+	}
+	delivery.ID, err = inserted.LastInsertId()
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert webhook delivery: %s", err.Error()), Err: err}
+	}
+	return delivery, nil
+}
+
+// FindDeliveries returns the latest deliveries of a webhook, newest first.
+func (mysql *MysqlWebhookStore) FindDeliveries(webhookID int64, limit int) ([]*model.WebhookDelivery, error) {
+	query := fmt.Sprintf("select %s from %s where webhook_id = ? order by id desc limit ?", deliveryColumns, deliveryTableName)
+	return mysql.findDeliveries(query, webhookID, limit)
+}
+
+// FindDeadDeliveries returns the oldest deliveries waiting for a replay.
+func (mysql *MysqlWebhookStore) FindDeadDeliveries(limit int) ([]*model.WebhookDelivery, error) {
+	query := fmt.Sprintf("select %s from %s where status = ? order by id limit ?", deliveryColumns, deliveryTableName)
+	return mysql.findDeliveries(query, model.DeliveryDead, limit)
+}
+
+// ParkPendingDeliveries marks every pending delivery dead so the replay
+// sends it again. It is called on start, when no delivery is in flight.
+func (mysql *MysqlWebhookStore) ParkPendingDeliveries() (int64, error) {
+	query := fmt.Sprintf("UPDATE %s SET status = ? WHERE status = ?", deliveryTableName)
+	result, err := mysql.db.Exec(query, model.DeliveryDead, model.DeliveryPending)
+	if err != nil {
+		return 0, &pkg.TaskError{Message: fmt.Sprintf("Failed to park pending webhook deliveries: %s", err.Error()), Err: err}
+	}
+	parked, err := result.RowsAffected()
+	if err != nil {
+		return 0, &pkg.TaskError{Message: fmt.Sprintf("Failed to park pending webhook deliveries: %s", err.Error()), Err: err}
+	}
+	return parked, nil
+}
+
+func (mysql *MysqlWebhookStore) findDeliveries(query string, args ...any) ([]*model.WebhookDelivery, error) {
+	results, err := mysql.db.Query(query, args...)
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query webhook deliveries: %s", err.Error()), Err: err}
+	}
+	defer func(results *sql.Rows) {
+		err := results.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(results)
+
+	var deliveries []*model.WebhookDelivery
+	for results.Next() {
+		delivery, err := scanDelivery(results)
+		if err != nil {
+			return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query webhook deliveries: %s", err.Error()), Err: err}
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err = results.Err(); err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to query webhook deliveries: %s", err.Error()), Err: err}
+	}
+	return deliveries, nil
+}
+
+func scanWebhook(row scanner) (*model.Webhook, error) {
+	var webhook model.Webhook
+	var events []byte
+	err := row.Scan(&webhook.ID, &webhook.WorkspaceID, &webhook.URL, &webhook.Secret, &events, &webhook.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(events, &webhook.Events); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func scanDelivery(row scanner) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	var responseCode sql.NullInt32
+	var errorMsg sql.NullString
+	err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.Event, &delivery.Payload, &delivery.Status, &delivery.Attempts, &responseCode, &errorMsg, &delivery.CreatedAt, &delivery.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if responseCode.Valid {
+		code := int(responseCode.Int32)
+		delivery.ResponseCode = &code
+	}
+	delivery.Error = errorMsg.String
+	return &delivery, nil
+}
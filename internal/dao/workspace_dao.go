@@ -0,0 +1,56 @@
+package dao
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/pkg"
+)
+
+const workspaceTableName string = "workspaces"
+
+type MysqlWorkspaceStore struct {
+	db *sql.DB
+}
+
+func NewMysqlWorkspaceStore(db *sql.DB) *MysqlWorkspaceStore {
+	return &MysqlWorkspaceStore{
+		db: db,
+	}
+}
+
+func (mysql *MysqlWorkspaceStore) Save(workspace *model.Workspace) (*model.Workspace, error) {
+	if workspace.ID != 0 {
+		query := fmt.Sprintf("UPDATE %s SET name = ? WHERE id = ?", workspaceTableName)
+		_, err := mysql.db.Exec(query, workspace.Name, workspace.ID)
+		if err != nil {
+			return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to update workspace: %s", err.Error()), Err: err}
+		}
+		return workspace, nil
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (name) VALUES (?)", workspaceTableName)
+	inserted, err := mysql.db.Exec(query, workspace.Name)
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert workspace: %s", err.Error()), Err: err}
+	}
+	workspace.ID, err = inserted.LastInsertId()
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to insert workspace: %s", err.Error()), Err: err}
+	}
+	return workspace, nil
+}
+
+func (mysql *MysqlWorkspaceStore) FindById(id int64) (*model.Workspace, error) {
+	query := fmt.Sprintf("select id, name, created_at from %s where id = ?", workspaceTableName)
+	var workspace model.Workspace
+	err := mysql.db.QueryRow(query, id).Scan(&workspace.ID, &workspace.Name, &workspace.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("workspace %d: %w", id, pkg.ErrNotFound)
+		}
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Data Access Error: %s", err.Error()), Err: err}
+	}
+	return &workspace, nil
+}
@@ -9,8 +9,96 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"time"
 )
 
+type ApiTokensScope string
+
+const (
+	ApiTokensScopeRead  ApiTokensScope = "read"
+	ApiTokensScopeWrite ApiTokensScope = "write"
+)
+
+func (e *ApiTokensScope) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ApiTokensScope(s)
+	case string:
+		*e = ApiTokensScope(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ApiTokensScope: %T", src)
+	}
+	return nil
+}
+
+type NullApiTokensScope struct {
+	ApiTokensScope ApiTokensScope
+	Valid          bool // Valid is true if ApiTokensScope is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullApiTokensScope) Scan(value interface{}) error {
+	if value == nil {
+		ns.ApiTokensScope, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ApiTokensScope.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullApiTokensScope) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ApiTokensScope), nil
+}
+
+type TaskEventsOperation string
+
+const (
+	TaskEventsOperationCREATE       TaskEventsOperation = "CREATE"
+	TaskEventsOperationUPDATE       TaskEventsOperation = "UPDATE"
+	TaskEventsOperationSTATUSCHANGE TaskEventsOperation = "STATUS_CHANGE"
+	TaskEventsOperationDELETE       TaskEventsOperation = "DELETE"
+	TaskEventsOperationRESTORE      TaskEventsOperation = "RESTORE"
+)
+
+func (e *TaskEventsOperation) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = TaskEventsOperation(s)
+	case string:
+		*e = TaskEventsOperation(s)
+	default:
+		return fmt.Errorf("unsupported scan type for TaskEventsOperation: %T", src)
+	}
+	return nil
+}
+
+type NullTaskEventsOperation struct {
+	TaskEventsOperation TaskEventsOperation
+	Valid               bool // Valid is true if TaskEventsOperation is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullTaskEventsOperation) Scan(value interface{}) error {
+	if value == nil {
+		ns.TaskEventsOperation, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.TaskEventsOperation.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullTaskEventsOperation) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.TaskEventsOperation), nil
+}
+
 type TasksStatus string
 
 const (
@@ -54,6 +142,105 @@ func (ns NullTasksStatus) Value() (driver.Value, error) {
 	return string(ns.TasksStatus), nil
 }
 
+type UsersRole string
+
+const (
+	UsersRoleViewer UsersRole = "viewer"
+	UsersRoleMember UsersRole = "member"
+	UsersRoleAdmin  UsersRole = "admin"
+)
+
+func (e *UsersRole) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = UsersRole(s)
+	case string:
+		*e = UsersRole(s)
+	default:
+		return fmt.Errorf("unsupported scan type for UsersRole: %T", src)
+	}
+	return nil
+}
+
+type NullUsersRole struct {
+	UsersRole UsersRole
+	Valid     bool // Valid is true if UsersRole is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullUsersRole) Scan(value interface{}) error {
+	if value == nil {
+		ns.UsersRole, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.UsersRole.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullUsersRole) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.UsersRole), nil
+}
+
+type WebhookDeliveriesStatus string
+
+const (
+	WebhookDeliveriesStatusPending   WebhookDeliveriesStatus = "pending"
+	WebhookDeliveriesStatusDelivered WebhookDeliveriesStatus = "delivered"
+	WebhookDeliveriesStatusDead      WebhookDeliveriesStatus = "dead"
+	WebhookDeliveriesStatusFailed    WebhookDeliveriesStatus = "failed"
+)
+
+func (e *WebhookDeliveriesStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = WebhookDeliveriesStatus(s)
+	case string:
+		*e = WebhookDeliveriesStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for WebhookDeliveriesStatus: %T", src)
+	}
+	return nil
+}
+
+type NullWebhookDeliveriesStatus struct {
+	WebhookDeliveriesStatus WebhookDeliveriesStatus
+	Valid                   bool // Valid is true if WebhookDeliveriesStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullWebhookDeliveriesStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.WebhookDeliveriesStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.WebhookDeliveriesStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullWebhookDeliveriesStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.WebhookDeliveriesStatus), nil
+}
+
+type ApiToken struct {
+	ID         int64
+	UserID     int64
+	Name       string
+	TokenHash  string
+	Scope      ApiTokensScope
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+	CreatedAt  sql.NullTime
+}
+
 type DeadLetterTask struct {
 	ID         int64
 	TaskID     int64
@@ -63,12 +250,115 @@ type DeadLetterTask struct {
 	CreatedAt  sql.NullTime
 }
 
+type IdempotencyKey struct {
+	ID              int64
+	UserID          int64
+	IdempotencyKey  string
+	RequestHash     string
+	StatusCode      sql.NullInt32
+	ResponseHeaders json.RawMessage
+	ResponseBody    []byte
+	CreatedAt       sql.NullTime
+	ExpiresAt       time.Time
+}
+
+type Project struct {
+	ID          int64
+	WorkspaceID int64
+	Name        string
+	Description sql.NullString
+	Archived    bool
+	CreatedAt   sql.NullTime
+	UpdatedAt   sql.NullTime
+}
+
+type Session struct {
+	ID        string
+	UserID    int64
+	ExpiresAt time.Time
+	CreatedAt sql.NullTime
+}
+
 type Task struct {
+	ID          int64
+	WorkspaceID int64
+	ProjectID   sql.NullInt64
+	Title       string
+	Content     sql.NullString
+	Status      TasksStatus
+	Labels      json.RawMessage
+	DueAt       sql.NullTime
+	Recurrence  sql.NullString
+	Rank        float64
+	CreatedBy   sql.NullInt64
+	UpdatedBy   sql.NullInt64
+	CreatedAt   sql.NullTime
+	UpdatedAt   sql.NullTime
+	DeletedAt   sql.NullTime
+}
+
+type TaskComment struct {
+	ID          int64
+	WorkspaceID int64
+	TaskID      int64
+	Author      string
+	Body        string
+	CreatedAt   sql.NullTime
+	UpdatedAt   sql.NullTime
+}
+
+type TaskEvent struct {
+	ID          int64
+	WorkspaceID int64
+	TaskID      int64
+	Actor       string
+	Operation   TaskEventsOperation
+	Changes     json.RawMessage
+	CreatedAt   sql.NullTime
+}
+
+type User struct {
+	ID           int64
+	WorkspaceID  int64
+	Username     string
+	PasswordHash string
+	Role         UsersRole
+	CreatedAt    sql.NullTime
+	UpdatedAt    sql.NullTime
+}
+
+type UserIdentity struct {
+	ID        int64
+	UserID    int64
+	Issuer    string
+	Subject   string
+	CreatedAt sql.NullTime
+}
+
+type Webhook struct {
+	ID          int64
+	WorkspaceID int64
+	Url         string
+	Secret      string
+	Events      json.RawMessage
+	CreatedAt   sql.NullTime
+}
+
+type WebhookDelivery struct {
+	ID           int64
+	WebhookID    int64
+	Event        string
+	Payload      json.RawMessage
+	Status       WebhookDeliveriesStatus
+	Attempts     int32
+	ResponseCode sql.NullInt32
+	ErrorMsg     sql.NullString
+	CreatedAt    sql.NullTime
+	UpdatedAt    sql.NullTime
+}
+
+type Workspace struct {
 	ID        int64
-	Title     string
-	Content   sql.NullString
-	Status    TasksStatus
+	Name      string
 	CreatedAt sql.NullTime
-	UpdatedAt sql.NullTime
-	DeletedAt sql.NullTime
 }
@@ -8,10 +8,11 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 )
 
 const findTaskById = `-- name: FindTaskById :one
-select id, title, content, status, created_at, updated_at, deleted_at from tasks where id = ?
+select id, workspace_id, project_id, title, content, status, labels, due_at, recurrence, ` + "`" + `rank` + "`" + `, created_by, updated_by, created_at, updated_at, deleted_at from tasks where id = ?
 `
 
 func (q *Queries) FindTaskById(ctx context.Context, id int64) (Task, error) {
@@ -19,9 +20,17 @@ func (q *Queries) FindTaskById(ctx context.Context, id int64) (Task, error) {
 	var i Task
 	err := row.Scan(
 		&i.ID,
+		&i.WorkspaceID,
+		&i.ProjectID,
 		&i.Title,
 		&i.Content,
 		&i.Status,
+		&i.Labels,
+		&i.DueAt,
+		&i.Recurrence,
+		&i.Rank,
+		&i.CreatedBy,
+		&i.UpdatedBy,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
@@ -30,7 +39,7 @@ func (q *Queries) FindTaskById(ctx context.Context, id int64) (Task, error) {
 }
 
 const getAllTask = `-- name: GetAllTask :many
-select id, title, content, status, created_at, updated_at, deleted_at from tasks where deleted_at is null
+select id, workspace_id, project_id, title, content, status, labels, due_at, recurrence, ` + "`" + `rank` + "`" + `, created_by, updated_by, created_at, updated_at, deleted_at from tasks where deleted_at is null
 `
 
 func (q *Queries) GetAllTask(ctx context.Context) ([]Task, error) {
@@ -44,9 +53,17 @@ func (q *Queries) GetAllTask(ctx context.Context) ([]Task, error) {
 		var i Task
 		if err := rows.Scan(
 			&i.ID,
+			&i.WorkspaceID,
+			&i.ProjectID,
 			&i.Title,
 			&i.Content,
 			&i.Status,
+			&i.Labels,
+			&i.DueAt,
+			&i.Recurrence,
+			&i.Rank,
+			&i.CreatedBy,
+			&i.UpdatedBy,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
@@ -65,15 +82,35 @@ func (q *Queries) GetAllTask(ctx context.Context) ([]Task, error) {
 }
 
 const saveTask = `-- name: SaveTask :execresult
-INSERT INTO tasks (title, content, status) VALUES (?, ?, ?)
+INSERT INTO tasks (workspace_id, project_id, title, content, status, labels, due_at, recurrence, ` + "`" + `rank` + "`" + `, created_by, updated_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 `
 
 type SaveTaskParams struct {
-	Title   string
-	Content sql.NullString
-	Status  TasksStatus
+	WorkspaceID int64
+	ProjectID   sql.NullInt64
+	Title       string
+	Content     sql.NullString
+	Status      TasksStatus
+	Labels      json.RawMessage
+	DueAt       sql.NullTime
+	Recurrence  sql.NullString
+	Rank        float64
+	CreatedBy   sql.NullInt64
+	UpdatedBy   sql.NullInt64
 }
 
 func (q *Queries) SaveTask(ctx context.Context, arg SaveTaskParams) (sql.Result, error) {
-	return q.db.ExecContext(ctx, saveTask, arg.Title, arg.Content, arg.Status)
+	return q.db.ExecContext(ctx, saveTask,
+		arg.WorkspaceID,
+		arg.ProjectID,
+		arg.Title,
+		arg.Content,
+		arg.Status,
+		arg.Labels,
+		arg.DueAt,
+		arg.Recurrence,
+		arg.Rank,
+		arg.CreatedBy,
+		arg.UpdatedBy,
+	)
 }
@@ -1,5 +1,5 @@
 -- name: SaveTask :execresult
-INSERT INTO tasks (title, content, status) VALUES (?, ?, ?);
+INSERT INTO tasks (workspace_id, project_id, title, content, status, labels, due_at, recurrence, `rank`, created_by, updated_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
 -- name: FindTaskById :one
 select * from tasks where id = ?;
 -- name: GetAllTask :many
@@ -1,11 +1,41 @@
+CREATE TABLE workspaces (
+    id         BIGINT AUTO_INCREMENT PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE projects (
+    id           BIGINT AUTO_INCREMENT PRIMARY KEY,
+    workspace_id BIGINT NOT NULL,
+    name         VARCHAR(255) NOT NULL,
+    description  TEXT NULL,
+    archived     BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at   TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_projects_workspace_id (workspace_id),
+    FOREIGN KEY (workspace_id) REFERENCES workspaces (id)
+);
+
 CREATE TABLE tasks (
     id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    workspace_id BIGINT NOT NULL,
+    project_id BIGINT NULL,
     title VARCHAR(255) NOT NULL,
     content TEXT NULL,
     status ENUM('TODO', 'COMPLETED', 'PENDING') NOT NULL,
+    labels JSON NULL,
+    due_at TIMESTAMP NULL DEFAULT NULL,
+    recurrence VARCHAR(255) NULL,
+    `rank` DOUBLE NOT NULL DEFAULT 0,
+    created_by BIGINT NULL,
+    updated_by BIGINT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-    deleted_at TIMESTAMP NULL DEFAULT NULL
+    deleted_at TIMESTAMP NULL DEFAULT NULL,
+    INDEX idx_tasks_workspace_id (workspace_id),
+    INDEX idx_tasks_project_id (project_id),
+    FOREIGN KEY (workspace_id) REFERENCES workspaces (id),
+    FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE SET NULL
 );
 
 CREATE TABLE dead_letter_tasks (
@@ -16,3 +46,113 @@ CREATE TABLE dead_letter_tasks (
     retry_count INT DEFAULT 0,
     created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
+
+CREATE TABLE task_comments (
+    id           BIGINT AUTO_INCREMENT PRIMARY KEY,
+    workspace_id BIGINT NOT NULL,
+    task_id      BIGINT NOT NULL,
+    author       VARCHAR(255) NOT NULL,
+    body         TEXT NOT NULL,
+    created_at   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at   TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_task_comments_workspace_task (workspace_id, task_id),
+    FOREIGN KEY (task_id) REFERENCES tasks (id) ON DELETE CASCADE
+);
+
+CREATE TABLE task_events (
+    id           BIGINT AUTO_INCREMENT PRIMARY KEY,
+    workspace_id BIGINT NOT NULL,
+    task_id      BIGINT NOT NULL,
+    actor        VARCHAR(255) NOT NULL,
+    operation    ENUM('CREATE', 'UPDATE', 'STATUS_CHANGE', 'DELETE', 'RESTORE') NOT NULL,
+    changes      JSON NOT NULL,
+    created_at   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_task_events_workspace_task (workspace_id, task_id)
+);
+
+CREATE TABLE users (
+    id            BIGINT AUTO_INCREMENT PRIMARY KEY,
+    workspace_id  BIGINT NOT NULL,
+    username      VARCHAR(255) NOT NULL UNIQUE,
+    password_hash VARCHAR(255) NOT NULL,
+    role          ENUM('viewer', 'member', 'admin') NOT NULL DEFAULT 'member',
+    created_at    TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at    TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_users_workspace_id (workspace_id),
+    FOREIGN KEY (workspace_id) REFERENCES workspaces (id)
+);
+
+CREATE TABLE sessions (
+    id         CHAR(64) PRIMARY KEY,
+    user_id    BIGINT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_sessions_user_id (user_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE api_tokens (
+    id           BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id      BIGINT NOT NULL,
+    name         VARCHAR(255) NOT NULL,
+    token_hash   CHAR(64) NOT NULL UNIQUE,
+    scope        ENUM('read', 'write') NOT NULL,
+    expires_at   TIMESTAMP NULL DEFAULT NULL,
+    last_used_at TIMESTAMP NULL DEFAULT NULL,
+    revoked_at   TIMESTAMP NULL DEFAULT NULL,
+    created_at   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_api_tokens_user_id (user_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE user_identities (
+    id         BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id    BIGINT NOT NULL,
+    issuer     VARCHAR(255) NOT NULL,
+    subject    VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_user_identities_subject (issuer, subject),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE webhooks (
+    id           BIGINT AUTO_INCREMENT PRIMARY KEY,
+    workspace_id BIGINT NOT NULL,
+    url          VARCHAR(2048) NOT NULL,
+    secret       VARCHAR(255) NOT NULL,
+    events       JSON NOT NULL,
+    created_at   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_webhooks_workspace_id (workspace_id),
+    FOREIGN KEY (workspace_id) REFERENCES workspaces (id)
+);
+
+CREATE TABLE webhook_deliveries (
+    id            BIGINT AUTO_INCREMENT PRIMARY KEY,
+    webhook_id    BIGINT NOT NULL,
+    event         VARCHAR(64) NOT NULL,
+    payload       JSON NOT NULL,
+    status        ENUM('pending', 'delivered', 'dead', 'failed') NOT NULL DEFAULT 'pending',
+    attempts      INT NOT NULL DEFAULT 0,
+    response_code INT NULL,
+    error_msg     TEXT NULL,
+    created_at    TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at    TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_webhook_deliveries_webhook_id (webhook_id),
+    INDEX idx_webhook_deliveries_status (status),
+    FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE
+);
+
+CREATE TABLE idempotency_keys (
+    id               BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id          BIGINT NOT NULL,
+    idempotency_key  VARCHAR(255) NOT NULL,
+    request_hash     CHAR(64) NOT NULL,
+    status_code      INT NULL,
+    response_headers JSON NULL,
+    response_body    MEDIUMBLOB NULL,
+    created_at       TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at       TIMESTAMP NOT NULL,
+    UNIQUE KEY uq_idempotency_keys_user_key (user_id, idempotency_key),
+    INDEX idx_idempotency_keys_expires_at (expires_at),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
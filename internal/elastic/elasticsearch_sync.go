@@ -15,23 +15,31 @@ import (
 
 const idxName string = "task-idx"
 const deadLetterTableName string = "dead_letter_tasks"
+const commentTableName string = "task_comments"
 
+// TaskDoc is the indexed form of a task. All workspaces share one index,
+// so every search must filter on WorkspaceID.
 type TaskDoc struct {
-	ID        int64     `json:"id"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID          int64      `json:"id"`
+	WorkspaceID int64      `json:"workspaceId"`
+	ProjectID   *int64     `json:"projectId,omitempty"`
+	Title       string     `json:"title"`
+	Content     string     `json:"content"`
+	Status      string     `json:"status"`
+	Labels      []string   `json:"labels,omitempty"`
+	DueAt       *time.Time `json:"dueAt,omitempty"`
+	Comments    []string   `json:"comments,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
 }
 
 type ElasticsearchSync struct {
 	esClient *elasticsearch.Client
-	taskChan chan *model.Task
+	taskChan chan []*model.Task
 	db       *sql.DB
 }
 
-func NewElasticsearchSync(esClient *elasticsearch.Client, taskChan chan *model.Task, db *sql.DB) *ElasticsearchSync {
+func NewElasticsearchSync(esClient *elasticsearch.Client, taskChan chan []*model.Task, db *sql.DB) *ElasticsearchSync {
 	es := ElasticsearchSync{
 		esClient: esClient,
 		taskChan: taskChan,
@@ -62,32 +70,15 @@ func (es *ElasticsearchSync) startWorker() {
 	log.Println("starting elasticsearch sync worker")
 	const maxRetry = 3
 	for {
-		task, ok := <-es.taskChan
+		tasks, ok := <-es.taskChan
 		if !ok {
 			log.Printf("channel closed elasticsearch sync worker exiting")
 			return
 		}
-		taskDoc := TaskDoc{
-			ID:        task.ID,
-			Title:     task.Title,
-			Content:   task.Content,
-			Status:    string(task.Status),
-			CreatedAt: task.CreatedAt,
-			UpdatedAt: task.UpdatedAt,
-		}
-		taskJson, _ := json.Marshal(taskDoc)
-		_, err := es.esClient.Index(
-			idxName,
-			bytes.NewReader(taskJson),
-			es.esClient.Index.WithDocumentID(strconv.FormatInt(task.ID, 10)),
-		)
+		err := es.syncAll(tasks)
 		if err != nil {
 			for i := 0; i < maxRetry; i++ {
-				_, err = es.esClient.Index(
-					idxName,
-					bytes.NewReader(taskJson),
-					es.esClient.Index.WithDocumentID(strconv.FormatInt(task.ID, 10)),
-				)
+				err = es.syncAll(tasks)
 				if err == nil {
 					break
 				}
@@ -97,11 +88,149 @@ func (es *ElasticsearchSync) startWorker() {
 			if err != nil {
 				log.Println("Failed to index document after retries:", err.Error())
 				log.Println("sending task to dead letter queue")
-				es.storeDeadLetter(task, err.Error())
+				for _, task := range tasks {
+					es.storeDeadLetter(task, err.Error())
+				}
+			}
+		}
+	}
+}
+
+// syncAll syncs a single change on its own and the changes of a batch
+// with one bulk request.
+func (es *ElasticsearchSync) syncAll(tasks []*model.Task) error {
+	if len(tasks) == 1 {
+		return es.sync(tasks[0])
+	}
+	return es.bulk(tasks)
+}
+
+// sync indexes task, or removes it from the index once it has been
+// soft-deleted so trashed tasks no longer show up in search.
+func (es *ElasticsearchSync) sync(task *model.Task) error {
+	docID := strconv.FormatInt(task.ID, 10)
+	if task.DeletedAt != nil {
+		_, err := es.esClient.Delete(idxName, docID)
+		return err
+	}
+
+	taskJson, _ := json.Marshal(es.taskDoc(task))
+	_, err := es.esClient.Index(
+		idxName,
+		bytes.NewReader(taskJson),
+		es.esClient.Index.WithDocumentID(docID),
+	)
+	return err
+}
+
+// bulk indexes or removes several tasks in one request. Removing a task
+// that is not indexed is not an error, just as with sync.
+func (es *ElasticsearchSync) bulk(tasks []*model.Task) error {
+	var body bytes.Buffer
+	for _, task := range tasks {
+		action := "index"
+		if task.DeletedAt != nil {
+			action = "delete"
+		}
+		meta, _ := json.Marshal(map[string]any{
+			action: map[string]string{"_index": idxName, "_id": strconv.FormatInt(task.ID, 10)},
+		})
+		body.Write(meta)
+		body.WriteByte('\n')
+		if task.DeletedAt == nil {
+			taskJson, _ := json.Marshal(es.taskDoc(task))
+			body.Write(taskJson)
+			body.WriteByte('\n')
+		}
+	}
+
+	res, err := es.esClient.Bulk(bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			log.Println("Failed to close bulk response:", err)
+		}
+	}()
+	if res.IsError() {
+		return fmt.Errorf("bulk sync failed: %s", res.Status())
+	}
+	var result struct {
+		Items []map[string]struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return err
+	}
+	for _, item := range result.Items {
+		for action, outcome := range item {
+			if outcome.Error == nil || action == "delete" && outcome.Status == 404 {
+				continue
 			}
+			return fmt.Errorf("bulk %s failed: %s", action, outcome.Error)
+		}
+	}
+	return nil
+}
+
+func (es *ElasticsearchSync) taskDoc(task *model.Task) TaskDoc {
+	return TaskDoc{
+		ID:          task.ID,
+		WorkspaceID: task.WorkspaceID,
+		ProjectID:   task.ProjectID,
+		Title:       task.Title,
+		Content:     task.Content,
+		Status:      string(task.Status),
+		Labels:      task.Labels,
+		DueAt:       task.DueAt,
+		Comments:    es.loadComments(task.ID),
+		CreatedAt:   task.CreatedAt,
+		UpdatedAt:   task.UpdatedAt,
+	}
+}
+
+// loadComments returns the comment bodies of a task so the discussion is
+// searchable together with the task itself.
+func (es *ElasticsearchSync) loadComments(taskID int64) []string {
+	query := fmt.Sprintf("SELECT body FROM %s WHERE task_id = ? ORDER BY created_at, id", commentTableName)
+	results, err := es.db.Query(query, taskID)
+	if err != nil {
+		log.Println("Failed to query task comments:", err)
+		return nil
+	}
+	defer func(results *sql.Rows) {
+		err := results.Close()
+		if err != nil {
+			log.Println("Failed to close task comments:", err)
+		}
+	}(results)
+
+	var comments []string
+	for results.Next() {
+		var body string
+		if err := results.Scan(&body); err != nil {
+			log.Println("Failed to scan task comment:", err)
+			continue
 		}
+		comments = append(comments, body)
+	}
+	if err = results.Err(); err != nil {
+		log.Println("Failed to iterate over task comments:", err)
+	}
+	return comments
+}
+
+// DeadLetter queues tasks for the next replay without trying to index
+// them now, for changes that could not be handed to the sync worker.
+func (es *ElasticsearchSync) DeadLetter(tasks []*model.Task) {
+	for _, task := range tasks {
+		es.storeDeadLetter(task, "search sync queue full")
 	}
 }
+
 func (es *ElasticsearchSync) storeDeadLetter(task *model.Task, errorMsg string) {
 	query := fmt.Sprintf(`INSERT INTO %s (task_id, payload, error_msg, retry_count) VALUES (?, ?, ?, ?)`, deadLetterTableName)
 	taskJson, _ := json.Marshal(task)
@@ -142,7 +271,7 @@ func (es *ElasticsearchSync) replayDeadLetter() {
 			continue
 		}
 		task.ID = taskID
-		es.taskChan <- task
+		es.taskChan <- []*model.Task{task}
 		deleteQuery := fmt.Sprintf("DELETE FROM %s WHERE id = ?", deadLetterTableName)
 		_, err = es.db.Exec(deleteQuery, id)
 		if err != nil {
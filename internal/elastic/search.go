@@ -0,0 +1,84 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	elasticsearch "github.com/elastic/go-elasticsearch/v8"
+)
+
+const maxSearchHits = 50
+
+type searchResponse struct {
+	Hits struct {
+		Hits []struct {
+			Source TaskDoc `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// ElasticsearchSearch runs full-text queries against the task index.
+type ElasticsearchSearch struct {
+	esClient *elasticsearch.Client
+}
+
+func NewElasticsearchSearch(esClient *elasticsearch.Client) *ElasticsearchSearch {
+	return &ElasticsearchSearch{
+		esClient: esClient,
+	}
+}
+
+// Search returns the ids of the best matching tasks of a workspace, and of
+// a single project when projectID is set. The index is shared by all
+// workspaces, so the workspace filter is always applied.
+func (es *ElasticsearchSearch) Search(ctx context.Context, workspaceID int64, projectID *int64, query string) ([]int64, error) {
+	filters := []map[string]any{
+		{"term": map[string]any{"workspaceId": workspaceID}},
+	}
+	if projectID != nil {
+		filters = append(filters, map[string]any{"term": map[string]any{"projectId": *projectID}})
+	}
+	body, err := json.Marshal(map[string]any{
+		"size":    maxSearchHits,
+		"_source": []string{"id"},
+		"query": map[string]any{
+			"bool": map[string]any{
+				"must": map[string]any{
+					"multi_match": map[string]any{
+						"query":  query,
+						"fields": []string{"title^2", "content", "labels", "comments"},
+					},
+				},
+				"filter": filters,
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := es.esClient.Search(
+		es.esClient.Search.WithContext(ctx),
+		es.esClient.Search.WithIndex(idxName),
+		es.esClient.Search.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, fmt.Errorf("search tasks: %s", res.String())
+	}
+
+	var found searchResponse
+	if err := json.NewDecoder(res.Body).Decode(&found); err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(found.Hits.Hits))
+	for _, hit := range found.Hits.Hits {
+		ids = append(ids, hit.Source.ID)
+	}
+	return ids, nil
+}
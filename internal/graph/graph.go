@@ -0,0 +1,83 @@
+package graph
+
+import (
+	"context"
+	_ "embed"
+	"go-task/internal/pubsub"
+	"go-task/internal/service"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+const (
+	// maxDepth bounds how deeply a query may nest, such as tasks of the
+	// project of a task, so one request cannot fan out without limit.
+	maxDepth = 8
+	// maxParallelism is how many resolvers of a request run at once. It
+	// bounds how many keys a loader can collect for one query, so it is
+	// sized for a page of tasks rather than the library default of 10.
+	maxParallelism = 100
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// Schema is the GraphQL view of the task domain. Resolvers call the same
+// services as the HTTP API; the related objects of a task are loaded in
+// batches per request rather than one query per task.
+type Schema struct {
+	schema   *graphql.Schema
+	services *services
+}
+
+type services struct {
+	tasks    *service.Service
+	projects *service.ProjectService
+	comments *service.CommentService
+	broker   *pubsub.Broker
+}
+
+// Params is a GraphQL request as sent over HTTP.
+type Params struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+func New(tasks *service.Service, projects *service.ProjectService, comments *service.CommentService, broker *pubsub.Broker) *Schema {
+	s := &services{
+		tasks:    tasks,
+		projects: projects,
+		comments: comments,
+		broker:   broker,
+	}
+	return &Schema{
+		schema:   graphql.MustParseSchema(schemaSDL, &resolver{services: s}, graphql.MaxDepth(maxDepth), graphql.MaxParallelism(maxParallelism)),
+		services: s,
+	}
+}
+
+// Exec runs a query or mutation. Mutations are refused when readOnly is
+// set, which callers use for requests with safe HTTP methods.
+func (schema *Schema) Exec(ctx context.Context, params Params, readOnly bool) *graphql.Response {
+	ctx = withLoaders(ctx, newLoaders(schema.services, true))
+	ctx = context.WithValue(ctx, readOnlyKey{}, readOnly)
+	return schema.schema.Exec(ctx, params.Query, params.OperationName, params.Variables)
+}
+
+// Subscribe runs any operation and sends its results until ctx is done or
+// the operation ends; a query or mutation ends after its one result.
+// Loaders do not cache across the results of a subscription so every
+// change is resolved against the current data.
+func (schema *Schema) Subscribe(ctx context.Context, params Params, readOnly bool) (<-chan any, error) {
+	ctx = withLoaders(ctx, newLoaders(schema.services, false))
+	ctx = context.WithValue(ctx, readOnlyKey{}, readOnly)
+	return schema.schema.Subscribe(ctx, params.Query, params.OperationName, params.Variables)
+}
+
+type readOnlyKey struct{}
+
+func isReadOnly(ctx context.Context) bool {
+	readOnly, _ := ctx.Value(readOnlyKey{}).(bool)
+	return readOnly
+}
@@ -0,0 +1,79 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/pkg"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+// loaderWait is how long a loader collects keys before it fetches them in
+// one query. Resolvers of a list run concurrently, so the keys of a whole
+// level of the query arrive well within it.
+const loaderWait = 2 * time.Millisecond
+
+// loaders fetch the objects related to tasks, one query per kind of
+// object and level of the query instead of one per task.
+type loaders struct {
+	tasks        *dataloader.Loader[int64, *model.Task]
+	projects     *dataloader.Loader[int64, *model.Project]
+	projectTasks *dataloader.Loader[int64, []*model.Task]
+	comments     *dataloader.Loader[int64, []*model.Comment]
+	history      *dataloader.Loader[int64, []*model.TaskEvent]
+}
+
+func newLoaders(s *services, cached bool) *loaders {
+	return &loaders{
+		tasks:        newLoader(s.tasks.FindByIds, notFound("task"), cached),
+		projects:     newLoader(s.projects.FindByIds, notFound("project"), cached),
+		projectTasks: newLoader(s.projects.TasksOf, nil, cached),
+		comments:     newLoader(s.comments.FindByTaskIds, nil, cached),
+		history:      newLoader(s.tasks.Histories, nil, cached),
+	}
+}
+
+// newLoader batches fetch, which returns the values it found keyed by id.
+// A missing id fails with missing when it is set and loads the zero value,
+// an empty list, otherwise.
+func newLoader[V any](fetch func(ctx context.Context, ids []int64) (map[int64]V, error), missing func(id int64) error, cached bool) *dataloader.Loader[int64, V] {
+	batch := func(ctx context.Context, ids []int64) []*dataloader.Result[V] {
+		results := make([]*dataloader.Result[V], len(ids))
+		values, err := fetch(ctx, ids)
+		for i, id := range ids {
+			result := &dataloader.Result[V]{Error: err}
+			if err == nil {
+				value, ok := values[id]
+				result.Data = value
+				if !ok && missing != nil {
+					result.Error = missing(id)
+				}
+			}
+			results[i] = result
+		}
+		return results
+	}
+	options := []dataloader.Option[int64, V]{dataloader.WithWait[int64, V](loaderWait)}
+	if !cached {
+		options = append(options, dataloader.WithCache[int64, V](&dataloader.NoCache[int64, V]{}))
+	}
+	return dataloader.NewBatchedLoader(batch, options...)
+}
+
+func notFound(kind string) func(id int64) error {
+	return func(id int64) error {
+		return fmt.Errorf("%s %d: %w", kind, id, pkg.ErrNotFound)
+	}
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
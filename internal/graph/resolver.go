@@ -0,0 +1,454 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/internal/service"
+	"go-task/pkg"
+	"slices"
+	"strconv"
+	"strings"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+// errReadOnly refuses mutations sent with a safe HTTP method, which read
+// tokens are allowed to use.
+var errReadOnly = fmt.Errorf("mutations must be sent with POST: %w", pkg.ErrForbidden)
+
+// resolver is the root of the schema: its methods are the fields of
+// Query, Mutation and Subscription.
+type resolver struct {
+	*services
+}
+
+func (r *resolver) Task(ctx context.Context, args struct{ ID graphql.ID }) (*taskResolver, error) {
+	id, err := parseID(args.ID, "id")
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	task, err := r.tasks.FindById(ctx, id)
+	if errors.Is(err, pkg.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	return &taskResolver{task: task}, nil
+}
+
+func (r *resolver) Tasks(ctx context.Context, args struct {
+	ProjectID *graphql.ID
+	Status    *string
+	Label     *string
+}) ([]*taskResolver, error) {
+	var tasks []*model.Task
+	if args.ProjectID != nil {
+		projectID, err := parseID(*args.ProjectID, "projectId")
+		if err != nil {
+			return nil, errorFor(err)
+		}
+		tasks, err = r.projects.Tasks(ctx, projectID)
+		if err != nil {
+			return nil, errorFor(err)
+		}
+	} else {
+		var err error
+		tasks, err = r.tasks.FindAll(ctx)
+		if err != nil {
+			return nil, errorFor(err)
+		}
+	}
+	tasks = slices.DeleteFunc(tasks, func(task *model.Task) bool {
+		return (args.Status != nil && string(task.Status) != *args.Status) ||
+			(args.Label != nil && !slices.Contains(task.Labels, *args.Label))
+	})
+	return newTasks(tasks), nil
+}
+
+func (r *resolver) Search(ctx context.Context, args struct {
+	Query     string
+	ProjectID *graphql.ID
+}) ([]*taskResolver, error) {
+	var projectID *int64
+	if args.ProjectID != nil {
+		id, err := parseID(*args.ProjectID, "projectId")
+		if err != nil {
+			return nil, errorFor(err)
+		}
+		projectID = &id
+	}
+	tasks, err := r.tasks.Search(ctx, projectID, args.Query)
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	return newTasks(tasks), nil
+}
+
+func (r *resolver) Trash(ctx context.Context) ([]*taskResolver, error) {
+	tasks, err := r.tasks.Trash(ctx)
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	return newTasks(tasks), nil
+}
+
+func (r *resolver) Project(ctx context.Context, args struct{ ID graphql.ID }) (*projectResolver, error) {
+	id, err := parseID(args.ID, "id")
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	project, err := r.projects.FindById(ctx, id)
+	if errors.Is(err, pkg.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	return &projectResolver{project: project}, nil
+}
+
+func (r *resolver) Projects(ctx context.Context, args struct{ IncludeArchived bool }) ([]*projectResolver, error) {
+	projects, err := r.projects.FindAll(ctx, args.IncludeArchived)
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	resolvers := make([]*projectResolver, 0, len(projects))
+	for _, project := range projects {
+		resolvers = append(resolvers, &projectResolver{project: project})
+	}
+	return resolvers, nil
+}
+
+// Labels lists the labels of the live tasks by name.
+func (r *resolver) Labels(ctx context.Context) ([]*labelResolver, error) {
+	tasks, err := r.tasks.FindAll(ctx)
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	byLabel := map[string][]*model.Task{}
+	for _, task := range tasks {
+		for _, label := range task.Labels {
+			byLabel[label] = append(byLabel[label], task)
+		}
+	}
+	resolvers := make([]*labelResolver, 0, len(byLabel))
+	for label, labelled := range byLabel {
+		resolvers = append(resolvers, &labelResolver{name: label, tasks: labelled})
+	}
+	slices.SortFunc(resolvers, func(a, b *labelResolver) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return resolvers, nil
+}
+
+type taskInput struct {
+	Title      string
+	Content    *string
+	Status     string
+	Labels     *[]string
+	DueAt      *graphql.Time
+	Recurrence *string
+	ProjectID  *graphql.ID
+}
+
+func (r *resolver) CreateTask(ctx context.Context, args struct{ Input taskInput }) (*taskResolver, error) {
+	if isReadOnly(ctx) {
+		return nil, errorFor(errReadOnly)
+	}
+	task, err := mapToTask(args.Input)
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	created, err := r.tasks.Create(ctx, task)
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	return &taskResolver{task: created}, nil
+}
+
+type taskPatchInput struct {
+	Title      graphql.NullString
+	Content    graphql.NullString
+	Status     *string
+	Labels     *[]string
+	DueAt      graphql.NullTime
+	Recurrence graphql.NullString
+	ProjectID  nullID
+}
+
+func (r *resolver) UpdateTask(ctx context.Context, args struct {
+	ID    graphql.ID
+	Patch taskPatchInput
+}) (*taskResolver, error) {
+	if isReadOnly(ctx) {
+		return nil, errorFor(errReadOnly)
+	}
+	id, err := parseID(args.ID, "id")
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	patch, err := mapToTaskPatch(args.Patch)
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	task, err := r.tasks.Patch(ctx, id, patch)
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	return &taskResolver{task: task}, nil
+}
+
+func (r *resolver) DeleteTask(ctx context.Context, args struct{ ID graphql.ID }) (graphql.ID, error) {
+	if isReadOnly(ctx) {
+		return "", errorFor(errReadOnly)
+	}
+	id, err := parseID(args.ID, "id")
+	if err != nil {
+		return "", errorFor(err)
+	}
+	if err := r.tasks.Delete(ctx, id); err != nil {
+		return "", errorFor(err)
+	}
+	return args.ID, nil
+}
+
+func (r *resolver) RestoreTask(ctx context.Context, args struct{ ID graphql.ID }) (*taskResolver, error) {
+	if isReadOnly(ctx) {
+		return nil, errorFor(errReadOnly)
+	}
+	id, err := parseID(args.ID, "id")
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	task, err := r.tasks.Restore(ctx, id)
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	return &taskResolver{task: task}, nil
+}
+
+func (r *resolver) AddComment(ctx context.Context, args struct {
+	TaskID graphql.ID
+	Body   string
+}) (*commentResolver, error) {
+	if isReadOnly(ctx) {
+		return nil, errorFor(errReadOnly)
+	}
+	taskID, err := parseID(args.TaskID, "taskId")
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	comment, err := r.comments.Create(ctx, taskID, args.Body)
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	return &commentResolver{comment: comment}, nil
+}
+
+func (r *resolver) UpdateComment(ctx context.Context, args struct {
+	TaskID graphql.ID
+	ID     graphql.ID
+	Body   string
+}) (*commentResolver, error) {
+	if isReadOnly(ctx) {
+		return nil, errorFor(errReadOnly)
+	}
+	taskID, err := parseID(args.TaskID, "taskId")
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	id, err := parseID(args.ID, "id")
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	comment, err := r.comments.Update(ctx, taskID, id, args.Body)
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	return &commentResolver{comment: comment}, nil
+}
+
+func (r *resolver) DeleteComment(ctx context.Context, args struct {
+	TaskID graphql.ID
+	ID     graphql.ID
+}) (graphql.ID, error) {
+	if isReadOnly(ctx) {
+		return "", errorFor(errReadOnly)
+	}
+	taskID, err := parseID(args.TaskID, "taskId")
+	if err != nil {
+		return "", errorFor(err)
+	}
+	id, err := parseID(args.ID, "id")
+	if err != nil {
+		return "", errorFor(err)
+	}
+	if err := r.comments.Delete(ctx, taskID, id); err != nil {
+		return "", errorFor(err)
+	}
+	return args.ID, nil
+}
+
+// TaskChanged sends the changes the broker publishes for the caller's
+// workspace until the subscription ends. Like the live page feed, a slow
+// client misses changes rather than holding up the others.
+func (r *resolver) TaskChanged(ctx context.Context, args struct{ ProjectID *graphql.ID }) (<-chan *changeResolver, error) {
+	user, ok := service.UserFrom(ctx)
+	if !ok {
+		return nil, errorFor(pkg.ErrUnauthorized)
+	}
+	var projectID *int64
+	if args.ProjectID != nil {
+		id, err := parseID(*args.ProjectID, "projectId")
+		if err != nil {
+			return nil, errorFor(err)
+		}
+		// Fails for a project outside the workspace.
+		if _, err := r.projects.FindById(ctx, id); err != nil {
+			return nil, errorFor(err)
+		}
+		projectID = &id
+	}
+
+	sub := r.broker.Subscribe(user.WorkspaceID, projectID)
+	changes := make(chan *changeResolver)
+	go func() {
+		defer close(changes)
+		defer r.broker.Unsubscribe(sub)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case task, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				select {
+				case changes <- &changeResolver{task: task}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return changes, nil
+}
+
+func mapToTask(input taskInput) (*model.Task, error) {
+	task, err := model.NewTask(input.Title, deref(input.Content), pkg.TaskStatus(input.Status))
+	if err != nil {
+		return nil, err
+	}
+	if input.Labels != nil {
+		task.Labels = *input.Labels
+	}
+	if input.DueAt != nil {
+		task.DueAt = &input.DueAt.Time
+	}
+	if input.ProjectID != nil {
+		projectID, err := parseID(*input.ProjectID, "projectId")
+		if err != nil {
+			return nil, err
+		}
+		task.ProjectID = &projectID
+	}
+	if deref(input.Recurrence) != "" {
+		task.Recurrence, err = model.ParseRecurrence(*input.Recurrence)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return task, nil
+}
+
+func mapToTaskPatch(input taskPatchInput) (model.TaskPatch, error) {
+	var patch model.TaskPatch
+	if input.Title.Set {
+		patch.Title = pkg.Optional[string]{Value: deref(input.Title.Value), Set: true}
+	}
+	if input.Content.Set {
+		patch.Content = pkg.Optional[string]{Value: deref(input.Content.Value), Set: true}
+	}
+	if input.Status != nil {
+		patch.Status = pkg.Optional[pkg.TaskStatus]{Value: pkg.TaskStatus(*input.Status), Set: true}
+	}
+	if input.Labels != nil {
+		patch.Labels = pkg.Optional[[]string]{Value: *input.Labels, Set: true}
+	}
+	if input.DueAt.Set {
+		patch.DueAt.Set = true
+		if input.DueAt.Value != nil {
+			dueAt := input.DueAt.Value.Time
+			patch.DueAt.Value = &dueAt
+		}
+	}
+	if input.ProjectID.Set {
+		patch.ProjectID.Set = true
+		if input.ProjectID.Value != nil {
+			projectID, err := parseID(*input.ProjectID.Value, "projectId")
+			if err != nil {
+				return model.TaskPatch{}, err
+			}
+			patch.ProjectID.Value = &projectID
+		}
+	}
+	if input.Recurrence.Set {
+		patch.Recurrence.Set = true
+		if deref(input.Recurrence.Value) != "" {
+			recurrence, err := model.ParseRecurrence(*input.Recurrence.Value)
+			if err != nil {
+				return model.TaskPatch{}, err
+			}
+			patch.Recurrence.Value = recurrence
+		}
+	}
+	return patch, nil
+}
+
+func parseID(id graphql.ID, field string) (int64, error) {
+	value, err := strconv.ParseInt(string(id), 10, 64)
+	if err != nil {
+		return 0, &pkg.ValidationError{Field: field, Message: fmt.Sprintf("invalid id %q", id)}
+	}
+	return value, nil
+}
+
+func deref[T any](value *T) T {
+	var zero T
+	if value == nil {
+		return zero
+	}
+	return *value
+}
+
+// resolverError reports an error of the service layer with the status and
+// type of the problem the HTTP API answers it with, as the extensions of
+// the GraphQL error.
+type resolverError struct {
+	problem *pkg.Problem
+}
+
+func errorFor(err error) error {
+	return &resolverError{problem: pkg.ProblemFor(err)}
+}
+
+func (e *resolverError) Error() string {
+	if e.problem.Detail != "" {
+		return e.problem.Detail
+	}
+	return e.problem.Title
+}
+
+func (e *resolverError) Extensions() map[string]any {
+	extensions := map[string]any{
+		"status": e.problem.Status,
+		"type":   e.problem.Type,
+	}
+	if len(e.problem.Errors) > 0 {
+		extensions["errors"] = e.problem.Errors
+	}
+	return extensions
+}
@@ -0,0 +1,233 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-task/internal/model"
+	"slices"
+	"strconv"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+type taskResolver struct {
+	task *model.Task
+}
+
+func newTasks(tasks []*model.Task) []*taskResolver {
+	resolvers := make([]*taskResolver, 0, len(tasks))
+	for _, task := range tasks {
+		resolvers = append(resolvers, &taskResolver{task: task})
+	}
+	return resolvers
+}
+
+func (r *taskResolver) ID() graphql.ID       { return toID(r.task.ID) }
+func (r *taskResolver) Title() string        { return r.task.Title }
+func (r *taskResolver) Content() string      { return r.task.Content }
+func (r *taskResolver) Status() string       { return string(r.task.Status) }
+func (r *taskResolver) DueAt() *graphql.Time { return optionalTime(r.task.DueAt) }
+func (r *taskResolver) Rank() float64        { return r.task.Rank }
+
+func (r *taskResolver) CreatedAt() graphql.Time  { return graphql.Time{Time: r.task.CreatedAt} }
+func (r *taskResolver) UpdatedAt() graphql.Time  { return graphql.Time{Time: r.task.UpdatedAt} }
+func (r *taskResolver) DeletedAt() *graphql.Time { return optionalTime(r.task.DeletedAt) }
+
+func (r *taskResolver) Labels() []string {
+	if r.task.Labels == nil {
+		return []string{}
+	}
+	return r.task.Labels
+}
+
+func (r *taskResolver) Recurrence() *string {
+	if r.task.Recurrence == nil {
+		return nil
+	}
+	rule := r.task.Recurrence.String()
+	return &rule
+}
+
+func (r *taskResolver) Project(ctx context.Context) (*projectResolver, error) {
+	if r.task.ProjectID == nil {
+		return nil, nil
+	}
+	project, err := loadersFrom(ctx).projects.Load(ctx, *r.task.ProjectID)()
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	return &projectResolver{project: project}, nil
+}
+
+func (r *taskResolver) Comments(ctx context.Context) ([]*commentResolver, error) {
+	comments, err := loadersFrom(ctx).comments.Load(ctx, r.task.ID)()
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	resolvers := make([]*commentResolver, 0, len(comments))
+	for _, comment := range comments {
+		resolvers = append(resolvers, &commentResolver{comment: comment})
+	}
+	return resolvers, nil
+}
+
+func (r *taskResolver) History(ctx context.Context) ([]*eventResolver, error) {
+	events, err := loadersFrom(ctx).history.Load(ctx, r.task.ID)()
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	resolvers := make([]*eventResolver, 0, len(events))
+	for _, event := range events {
+		resolvers = append(resolvers, &eventResolver{event: event})
+	}
+	return resolvers, nil
+}
+
+type projectResolver struct {
+	project *model.Project
+}
+
+func (r *projectResolver) ID() graphql.ID          { return toID(r.project.ID) }
+func (r *projectResolver) Name() string            { return r.project.Name }
+func (r *projectResolver) Description() string     { return r.project.Description }
+func (r *projectResolver) Archived() bool          { return r.project.Archived }
+func (r *projectResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.project.CreatedAt} }
+func (r *projectResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: r.project.UpdatedAt} }
+
+func (r *projectResolver) Tasks(ctx context.Context) ([]*taskResolver, error) {
+	tasks, err := loadersFrom(ctx).projectTasks.Load(ctx, r.project.ID)()
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	return newTasks(tasks), nil
+}
+
+type commentResolver struct {
+	comment *model.Comment
+}
+
+func (r *commentResolver) ID() graphql.ID          { return toID(r.comment.ID) }
+func (r *commentResolver) Author() string          { return r.comment.Author }
+func (r *commentResolver) Body() string            { return r.comment.Body }
+func (r *commentResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.comment.CreatedAt} }
+func (r *commentResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: r.comment.UpdatedAt} }
+
+func (r *commentResolver) Task(ctx context.Context) (*taskResolver, error) {
+	task, err := loadersFrom(ctx).tasks.Load(ctx, r.comment.TaskID)()
+	if err != nil {
+		return nil, errorFor(err)
+	}
+	return &taskResolver{task: task}, nil
+}
+
+type eventResolver struct {
+	event *model.TaskEvent
+}
+
+func (r *eventResolver) ID() graphql.ID          { return toID(r.event.ID) }
+func (r *eventResolver) Actor() string           { return r.event.Actor }
+func (r *eventResolver) Operation() string       { return string(r.event.Operation) }
+func (r *eventResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.event.CreatedAt} }
+
+// Changes lists the changed fields by name.
+func (r *eventResolver) Changes() []*fieldChangeResolver {
+	fields := make([]string, 0, len(r.event.Changes))
+	for field := range r.event.Changes {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+	changes := make([]*fieldChangeResolver, 0, len(fields))
+	for _, field := range fields {
+		changes = append(changes, &fieldChangeResolver{field: field, change: r.event.Changes[field]})
+	}
+	return changes
+}
+
+type fieldChangeResolver struct {
+	field  string
+	change model.FieldChange
+}
+
+func (r *fieldChangeResolver) Field() string      { return r.field }
+func (r *fieldChangeResolver) Before() *jsonValue { return newJSONValue(r.change.Before) }
+func (r *fieldChangeResolver) After() *jsonValue  { return newJSONValue(r.change.After) }
+
+type labelResolver struct {
+	name  string
+	tasks []*model.Task
+}
+
+func (r *labelResolver) Name() string           { return r.name }
+func (r *labelResolver) Count() int32           { return int32(len(r.tasks)) }
+func (r *labelResolver) Tasks() []*taskResolver { return newTasks(r.tasks) }
+
+type changeResolver struct {
+	task model.Task
+}
+
+func (r *changeResolver) Task() *taskResolver { return &taskResolver{task: &r.task} }
+func (r *changeResolver) Deleted() bool       { return r.task.DeletedAt != nil }
+
+// jsonValue is the JSON scalar: any value, written as is.
+type jsonValue struct {
+	value any
+}
+
+func newJSONValue(value any) *jsonValue {
+	if value == nil {
+		return nil
+	}
+	return &jsonValue{value: value}
+}
+
+func (jsonValue) ImplementsGraphQLType(name string) bool { return name == "JSON" }
+
+func (v *jsonValue) UnmarshalGraphQL(input any) error {
+	v.value = input
+	return nil
+}
+
+func (v jsonValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.value)
+}
+
+// nullID is an ID argument that tells a null apart from an omitted one,
+// like graphql.NullString does for strings.
+type nullID struct {
+	Value *graphql.ID
+	Set   bool
+}
+
+func (nullID) ImplementsGraphQLType(name string) bool { return name == "ID" }
+
+func (id *nullID) UnmarshalGraphQL(input any) error {
+	id.Set = true
+	var value graphql.ID
+	switch v := input.(type) {
+	case nil:
+		return nil
+	case string:
+		value = graphql.ID(v)
+	case int32:
+		value = graphql.ID(strconv.Itoa(int(v)))
+	default:
+		return fmt.Errorf("wrong type for ID: %T", v)
+	}
+	id.Value = &value
+	return nil
+}
+
+func (id *nullID) Nullable() {}
+
+func toID(id int64) graphql.ID {
+	return graphql.ID(strconv.FormatInt(id, 10))
+}
+
+func optionalTime(t *time.Time) *graphql.Time {
+	if t == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *t}
+}
@@ -0,0 +1,26 @@
+package markdown
+
+import (
+	"bytes"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+)
+
+// The parser follows CommonMark and leaves raw HTML in the source out of
+// its output; the policy still cleans what it produces, so a link to a
+// javascript: URL cannot slip through either.
+var (
+	parser = goldmark.New()
+	policy = bluemonday.UGCPolicy()
+)
+
+// ToHTML renders CommonMark source as HTML that is safe to embed in a
+// page.
+func ToHTML(source string) (string, error) {
+	var buf bytes.Buffer
+	if err := parser.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+	return policy.Sanitize(buf.String()), nil
+}
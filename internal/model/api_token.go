@@ -0,0 +1,82 @@
+package model
+
+import (
+	"go-task/pkg"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const apiTokenPrefix = "gt_"
+
+type TokenScope string
+
+const (
+	ScopeRead  TokenScope = "read"
+	ScopeWrite TokenScope = "write"
+)
+
+func (s TokenScope) IsValid() bool {
+	switch s {
+	case ScopeRead, ScopeWrite:
+		return true
+	}
+	return false
+}
+
+// Allows reports whether a token with this scope may issue a request with
+// the given method. Read tokens are limited to safe methods.
+func (s TokenScope) Allows(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return s.IsValid()
+	}
+	return s == ScopeWrite
+}
+
+// APIToken is a personal access token for machine clients. Like sessions,
+// only the SHA-256 of the token is stored.
+type APIToken struct {
+	ID         int64
+	UserID     int64
+	Name       string
+	TokenHash  string
+	Scope      TokenScope
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+// NewAPIToken returns the token record together with the plaintext token,
+// which is shown to the user once and never stored.
+func NewAPIToken(userID int64, name string, scope TokenScope, expiresAt *time.Time) (*APIToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", &pkg.ValidationError{Field: "name", Message: "token name cannot be empty"}
+	}
+	if !scope.IsValid() {
+		return nil, "", &pkg.ValidationError{Field: "scope", Message: "invalid token scope"}
+	}
+	secret, err := NewToken()
+	if err != nil {
+		return nil, "", err
+	}
+	token := apiTokenPrefix + secret
+
+	return &APIToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: HashToken(token),
+		Scope:     scope,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}, token, nil
+}
+
+func (token *APIToken) Active(now time.Time) bool {
+	if token.RevokedAt != nil {
+		return false
+	}
+	return token.ExpiresAt == nil || now.Before(*token.ExpiresAt)
+}
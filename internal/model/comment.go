@@ -0,0 +1,48 @@
+package model
+
+import (
+	"go-task/pkg"
+	"time"
+)
+
+type Comment struct {
+	ID          int64
+	WorkspaceID int64
+	TaskID      int64
+	Author      string
+	Body        string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func NewComment(taskID int64, author string, body string) (*Comment, error) {
+	if author == "" {
+		return nil, &pkg.ValidationError{Field: "author", Message: "author cannot be empty"}
+	}
+	if !validBody(body) {
+		return nil, &pkg.ValidationError{Field: "body", Message: "comment cannot be empty"}
+	}
+	timestamp := time.Now()
+
+	return &Comment{
+		TaskID:    taskID,
+		Author:    author,
+		Body:      body,
+		CreatedAt: timestamp,
+		UpdatedAt: timestamp,
+	}, nil
+}
+
+func (comment *Comment) UpdateBody(body string) error {
+	if !validBody(body) {
+		return &pkg.ValidationError{Field: "body", Message: "comment cannot be empty"}
+	}
+
+	comment.Body = body
+	comment.UpdatedAt = time.Now()
+	return nil
+}
+
+func validBody(body string) bool {
+	return body != ""
+}
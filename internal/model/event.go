@@ -0,0 +1,89 @@
+package model
+
+import (
+	"reflect"
+	"time"
+)
+
+type EventOperation string
+
+const (
+	EventCreate       EventOperation = "CREATE"
+	EventUpdate       EventOperation = "UPDATE"
+	EventStatusChange EventOperation = "STATUS_CHANGE"
+	EventDelete       EventOperation = "DELETE"
+	EventRestore      EventOperation = "RESTORE"
+)
+
+type FieldChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// TaskEvent is one entry of the append-only history of a task.
+type TaskEvent struct {
+	ID          int64
+	WorkspaceID int64
+	TaskID      int64
+	Actor       string
+	Operation   EventOperation
+	Changes     map[string]FieldChange
+	CreatedAt   time.Time
+}
+
+// NewTaskEvent records operation on a task with the fields that differ
+// between before and after. before is nil for a newly created task.
+func NewTaskEvent(actor string, operation EventOperation, before *Task, after *Task) *TaskEvent {
+	return &TaskEvent{
+		WorkspaceID: after.WorkspaceID,
+		TaskID:      after.ID,
+		Actor:       actor,
+		Operation:   operation,
+		Changes:     DiffTasks(before, after),
+		CreatedAt:   time.Now(),
+	}
+}
+
+// DiffTasks returns the auditable fields whose values differ between the
+// two snapshots, keyed by their JSON name.
+func DiffTasks(before *Task, after *Task) map[string]FieldChange {
+	if before == nil {
+		before = &Task{}
+	}
+	old, current := auditFields(before), auditFields(after)
+
+	changes := map[string]FieldChange{}
+	for field, value := range current {
+		if !reflect.DeepEqual(old[field], value) {
+			changes[field] = FieldChange{Before: old[field], After: value}
+		}
+	}
+	return changes
+}
+
+func auditFields(task *Task) map[string]any {
+	fields := map[string]any{
+		"title":      task.Title,
+		"content":    task.Content,
+		"status":     string(task.Status),
+		"labels":     nil,
+		"dueAt":      auditTime(task.DueAt),
+		"recurrence": nil,
+		"rank":       task.Rank,
+		"deletedAt":  auditTime(task.DeletedAt),
+	}
+	if len(task.Labels) > 0 {
+		fields["labels"] = task.Labels
+	}
+	if task.Recurrence != nil {
+		fields["recurrence"] = task.Recurrence.String()
+	}
+	return fields
+}
+
+func auditTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// IdempotencyRecord remembers the response to a request sent with an
+// Idempotency-Key, so a retry of the request is answered with the same
+// response instead of being applied twice. StatusCode stays zero while the
+// first request is still being handled.
+type IdempotencyRecord struct {
+	UserID      int64
+	Key         string
+	RequestHash string
+	StatusCode  int
+	Header      map[string]string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+func NewIdempotencyRecord(userID int64, key string, requestHash string, now time.Time, ttl time.Duration) *IdempotencyRecord {
+	return &IdempotencyRecord{
+		UserID:      userID,
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}
+}
+
+func (record *IdempotencyRecord) Completed() bool {
+	return record.StatusCode != 0
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"go-task/pkg"
+	"time"
+)
+
+// Identity links a local user to the subject an external identity provider
+// authenticated. Subjects are only unique per issuer.
+type Identity struct {
+	ID        int64
+	UserID    int64
+	Issuer    string
+	Subject   string
+	CreatedAt time.Time
+}
+
+func NewIdentity(userID int64, issuer string, subject string) (*Identity, error) {
+	if issuer == "" || subject == "" {
+		return nil, &pkg.ValidationError{Field: "subject", Message: "identity needs an issuer and a subject"}
+	}
+	return &Identity{
+		UserID:    userID,
+		Issuer:    issuer,
+		Subject:   subject,
+		CreatedAt: time.Now(),
+	}, nil
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"go-task/pkg"
+	"strings"
+	"time"
+)
+
+// Project groups the tasks of a workspace into a board. Archived projects
+// stay readable but take no new tasks.
+type Project struct {
+	ID          int64
+	WorkspaceID int64
+	Name        string
+	Description string
+	Archived    bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// StatusCounts is the number of live tasks per status.
+type StatusCounts map[pkg.TaskStatus]int
+
+func NewProject(name string, description string) (*Project, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, &pkg.ValidationError{Field: "name", Message: "project name cannot be empty"}
+	}
+	timestamp := time.Now()
+
+	return &Project{
+		Name:        name,
+		Description: description,
+		CreatedAt:   timestamp,
+		UpdatedAt:   timestamp,
+	}, nil
+}
+
+func (project *Project) Update(name string, description string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return &pkg.ValidationError{Field: "name", Message: "project name cannot be empty"}
+	}
+
+	project.Name = name
+	project.Description = description
+	project.UpdatedAt = time.Now()
+	return nil
+}
+
+func (project *Project) SetArchived(archived bool) {
+	project.Archived = archived
+	project.UpdatedAt = time.Now()
+}
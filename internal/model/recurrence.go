@@ -0,0 +1,242 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"go-task/pkg"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+const untilLayout = "20060102T150405Z"
+
+// maxSearchDays bounds the day-by-day scan used for BYDAY rules so a rule
+// that can never match does not loop forever.
+const maxSearchDays = 5 * 366
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Recurrence is a subset of the RFC 5545 RRULE: FREQ, INTERVAL, BYDAY,
+// UNTIL and COUNT. Count is the number of occurrences left in the series,
+// including the task carrying the rule; zero means unbounded.
+type Recurrence struct {
+	Freq     Frequency
+	Interval int
+	ByDay    []time.Weekday
+	Until    *time.Time
+	Count    int
+}
+
+func ParseRecurrence(rule string) (*Recurrence, error) {
+	r, err := parseRecurrence(rule)
+	if err != nil {
+		return nil, &pkg.ValidationError{Field: "recurrence", Message: err.Error()}
+	}
+	return r, nil
+}
+
+func parseRecurrence(rule string) (*Recurrence, error) {
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	if rule == "" {
+		return nil, errors.New("recurrence rule cannot be empty")
+	}
+
+	r := &Recurrence{Interval: 1}
+	for _, part := range strings.Split(rule, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid recurrence part %q", part)
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.Freq = Frequency(strings.ToUpper(value))
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid recurrence interval %q", value)
+			}
+			r.Interval = interval
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, ok := weekdayCodes[strings.ToUpper(code)]
+				if !ok {
+					return nil, fmt.Errorf("invalid recurrence day %q", code)
+				}
+				r.ByDay = append(r.ByDay, day)
+			}
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return nil, err
+			}
+			r.Until = &until
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 {
+				return nil, fmt.Errorf("invalid recurrence count %q", value)
+			}
+			r.Count = count
+		default:
+			return nil, fmt.Errorf("unsupported recurrence part %q", key)
+		}
+	}
+
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{untilLayout, "20060102", time.RFC3339} {
+		if until, err := time.Parse(layout, value); err == nil {
+			return until, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid recurrence until %q", value)
+}
+
+func (r *Recurrence) validate() error {
+	switch r.Freq {
+	case Daily, Weekly, Monthly:
+	default:
+		return fmt.Errorf("invalid recurrence frequency %q", r.Freq)
+	}
+	if r.Interval < 1 {
+		return errors.New("recurrence interval must be positive")
+	}
+	if r.Until != nil && r.Count > 0 {
+		return errors.New("recurrence cannot have both until and count")
+	}
+	return nil
+}
+
+func (r Recurrence) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			codes = append(codes, strings.ToUpper(day.String()[:2]))
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilLayout))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence strictly after from, or false when the
+// series has ended because of UNTIL or COUNT.
+func (r Recurrence) Next(from time.Time) (time.Time, bool) {
+	if r.Count == 1 {
+		return time.Time{}, false
+	}
+
+	var next time.Time
+	var ok bool
+	if len(r.ByDay) > 0 {
+		next, ok = r.nextByDay(from)
+	} else {
+		next, ok = r.nextByInterval(from)
+	}
+	if !ok {
+		return time.Time{}, false
+	}
+	if r.Until != nil && next.After(*r.Until) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// Advance returns the rule the following occurrence should carry.
+func (r Recurrence) Advance() Recurrence {
+	if r.Count > 0 {
+		r.Count--
+	}
+	r.ByDay = append([]time.Weekday(nil), r.ByDay...)
+	return r
+}
+
+func (r Recurrence) nextByInterval(from time.Time) (time.Time, bool) {
+	switch r.Freq {
+	case Daily:
+		return from.AddDate(0, 0, r.Interval), true
+	case Weekly:
+		return from.AddDate(0, 0, 7*r.Interval), true
+	case Monthly:
+		// Months that do not contain the day are skipped rather than
+		// clamped, so a rule anchored on the 31st keeps landing on the 31st.
+		year, month, day := from.Date()
+		for step := 1; step <= 12; step++ {
+			next := time.Date(year, month+time.Month(step*r.Interval), day,
+				from.Hour(), from.Minute(), from.Second(), from.Nanosecond(), from.Location())
+			if next.Day() == day {
+				return next, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func (r Recurrence) nextByDay(from time.Time) (time.Time, bool) {
+	start := r.period(from)
+	for i := 1; i <= maxSearchDays; i++ {
+		candidate := from.AddDate(0, 0, i)
+		if (r.period(candidate)-start)%r.Interval != 0 {
+			continue
+		}
+		if r.matchesDay(candidate.Weekday()) {
+			return candidate, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// period numbers the day, ISO week or month t falls in, so that INTERVAL can
+// be applied as a modulo on the difference between two periods.
+func (r Recurrence) period(t time.Time) int {
+	year, month, day := t.Date()
+	days := int(time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix() / 86400)
+	switch r.Freq {
+	case Weekly:
+		// 1970-01-01 was a Thursday; shift so weeks start on Monday.
+		return (days + 3) / 7
+	case Monthly:
+		return year*12 + int(month)
+	default:
+		return days
+	}
+}
+
+func (r Recurrence) matchesDay(weekday time.Weekday) bool {
+	for _, day := range r.ByDay {
+		if day == weekday {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package model
+
+type Role string
+
+const (
+	RoleViewer Role = "viewer"
+	RoleMember Role = "member"
+	RoleAdmin  Role = "admin"
+)
+
+type Permission string
+
+const (
+	PermReadTask     Permission = "task:read"
+	PermCreateTask   Permission = "task:create"
+	PermUpdateTask   Permission = "task:update"
+	PermChangeStatus Permission = "task:status"
+	PermDeleteTask   Permission = "task:delete"
+	PermComment      Permission = "comment:create"
+	PermModerate     Permission = "comment:moderate"
+	PermManageUsers  Permission = "user:manage"
+	// PermManageProjects covers creating, renaming and archiving projects.
+	PermManageProjects Permission = "project:manage"
+	PermManageWebhooks Permission = "webhook:manage"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleViewer: {PermReadTask},
+	RoleMember: {PermReadTask, PermCreateTask, PermUpdateTask, PermChangeStatus, PermComment,
+		PermManageProjects},
+	RoleAdmin: {PermReadTask, PermCreateTask, PermUpdateTask, PermChangeStatus, PermComment,
+		PermManageProjects, PermDeleteTask, PermModerate, PermManageUsers, PermManageWebhooks},
+}
+
+func (r Role) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+func (r Role) Can(permission Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+// Session is a server-side login. Only the SHA-256 of the cookie token is
+// stored, so a leaked sessions table cannot be replayed as cookies.
+type Session struct {
+	ID        string
+	UserID    int64
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// NewSession returns the session together with the token to hand to the
+// client; the token itself is not kept.
+func NewSession(userID int64, now time.Time, ttl time.Duration) (*Session, string, error) {
+	token, err := NewToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &Session{
+		ID:        HashToken(token),
+		UserID:    userID,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}, token, nil
+}
+
+func (session *Session) Expired(now time.Time) bool {
+	return !now.Before(session.ExpiresAt)
+}
+
+// NewToken returns 32 random bytes encoded for use in cookies and headers.
+func NewToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,28 +1,47 @@
 package model
 
 import (
-	"errors"
+	"encoding/base64"
 	"go-task/pkg"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// minRankGap is the smallest distance between neighbouring ranks that is
+// still split in two, well above the precision of a float64 rank.
+const minRankGap = 1e-9
+
+var (
+	errTitleEmpty    = &pkg.ValidationError{Field: "title", Message: "title cannot be empty"}
+	errInvalidStatus = &pkg.ValidationError{Field: "status", Message: "invalid status"}
+)
+
 type Task struct {
-	ID        int64
-	Title     string
-	Content   string
-	Status    pkg.TaskStatus
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	DeletedAt *time.Time
+	ID          int64
+	WorkspaceID int64
+	ProjectID   *int64
+	Title       string
+	Content     string
+	Status      pkg.TaskStatus
+	Labels      []string
+	DueAt       *time.Time
+	Recurrence  *Recurrence
+	Rank        float64
+	CreatedBy   *int64
+	UpdatedBy   *int64
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   *time.Time
 }
 
 func NewTask(title string, content string, status pkg.TaskStatus) (*Task, error) {
 	if !validTitle(title) {
-		return nil, errors.New("title cannot be empty")
+		return nil, errTitleEmpty
 	}
 
 	if !status.IsValid() {
-		return nil, errors.New("invalid status")
+		return nil, errInvalidStatus
 	}
 	timestamp := time.Now()
 
@@ -37,7 +56,7 @@ func NewTask(title string, content string, status pkg.TaskStatus) (*Task, error)
 
 func (task *Task) UpdateTitle(title string) error {
 	if !validTitle(title) {
-		return errors.New("title cannot be empyty")
+		return errTitleEmpty
 	}
 
 	task.Title = title
@@ -51,17 +70,182 @@ func (task *Task) UpdateContent(content string) error {
 	return nil
 }
 
+func (task *Task) UpdateStatus(status pkg.TaskStatus) error {
+	if !status.IsValid() {
+		return errInvalidStatus
+	}
+
+	task.Status = status
+	task.UpdatedAt = time.Now()
+	return nil
+}
+
+// NextOccurrence builds the task that follows a completed recurring task.
+// The next due date is computed from the current due date, or from now when
+// the task had none, and occurrences that already lie in the past are
+// skipped so a late completion does not spawn overdue tasks.
+func (task *Task) NextOccurrence(now time.Time) (*Task, bool) {
+	if task.Recurrence == nil {
+		return nil, false
+	}
+
+	rule := *task.Recurrence
+	from := now
+	if task.DueAt != nil {
+		from = *task.DueAt
+	}
+	next, ok := rule.Next(from)
+	for ok && !next.After(now) {
+		rule = rule.Advance()
+		next, ok = rule.Next(next)
+	}
+	if !ok {
+		return nil, false
+	}
+	rule = rule.Advance()
+
+	return &Task{
+		WorkspaceID: task.WorkspaceID,
+		ProjectID:   task.ProjectID,
+		Title:       task.Title,
+		Content:     task.Content,
+		Status:      pkg.TODO,
+		Labels:      append([]string(nil), task.Labels...),
+		DueAt:       &next,
+		Recurrence:  &rule,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}, true
+}
+
+// UpdateFrom replaces the editable fields of task with those of updateTask.
+// The status is left alone; it changes through UpdateStatus so completing a
+// recurring task is handled in one place.
 func (task *Task) UpdateFrom(updateTask Task) (*Task, error) {
 	if err := task.UpdateTitle(updateTask.Title); err != nil {
 		return nil, err
 	}
-	if err := task.UpdateContent(task.Content); err != nil {
+	if err := task.UpdateContent(updateTask.Content); err != nil {
 		return nil, err
 	}
+	task.ProjectID = updateTask.ProjectID
+	task.Labels = updateTask.Labels
+	task.DueAt = updateTask.DueAt
+	task.Recurrence = updateTask.Recurrence
 	task.UpdatedAt = time.Now()
 	return task, nil
 }
 
+// TaskPatch is a partial update of a task: only the fields that are Set
+// change, and a field set to its zero value is cleared.
+type TaskPatch struct {
+	Title      pkg.Optional[string]
+	Content    pkg.Optional[string]
+	Status     pkg.Optional[pkg.TaskStatus]
+	Labels     pkg.Optional[[]string]
+	DueAt      pkg.Optional[*time.Time]
+	Recurrence pkg.Optional[*Recurrence]
+	ProjectID  pkg.Optional[*int64]
+}
+
+// ChangesFields reports whether the patch sets anything besides the status.
+func (patch TaskPatch) ChangesFields() bool {
+	return patch.Title.Set || patch.Content.Set || patch.Labels.Set || patch.DueAt.Set ||
+		patch.Recurrence.Set || patch.ProjectID.Set
+}
+
+// Patch applies the set fields of patch with the same validation as the
+// full update. Nothing changes when a field is invalid.
+func (task *Task) Patch(patch TaskPatch) error {
+	patched := *task
+	if patch.Title.Set {
+		if err := patched.UpdateTitle(patch.Title.Value); err != nil {
+			return err
+		}
+	}
+	if patch.Content.Set {
+		if err := patched.UpdateContent(patch.Content.Value); err != nil {
+			return err
+		}
+	}
+	if patch.Status.Set {
+		if err := patched.UpdateStatus(patch.Status.Value); err != nil {
+			return err
+		}
+	}
+	if patch.Labels.Set {
+		patched.Labels = patch.Labels.Value
+	}
+	if patch.DueAt.Set {
+		patched.DueAt = patch.DueAt.Value
+	}
+	if patch.Recurrence.Set {
+		patched.Recurrence = patch.Recurrence.Value
+	}
+	if patch.ProjectID.Set {
+		patched.ProjectID = patch.ProjectID.Value
+	}
+	patched.UpdatedAt = time.Now()
+	*task = patched
+	return nil
+}
+
+// TaskCursor is a position in the rank order of tasks: the rank and id of
+// the last task of a page. It stays valid when tasks before it are moved
+// or deleted.
+type TaskCursor struct {
+	Rank float64
+	ID   int64
+}
+
+// String encodes the cursor as an opaque token for URLs.
+func (cursor TaskCursor) String() string {
+	raw := strconv.FormatFloat(cursor.Rank, 'g', -1, 64) + ":" + strconv.FormatInt(cursor.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func ParseTaskCursor(token string) (*TaskCursor, error) {
+	errInvalid := &pkg.ValidationError{Field: "after", Message: "invalid cursor"}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errInvalid
+	}
+	rank, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, errInvalid
+	}
+	var cursor TaskCursor
+	if cursor.Rank, err = strconv.ParseFloat(rank, 64); err != nil {
+		return nil, errInvalid
+	}
+	if cursor.ID, err = strconv.ParseInt(id, 10, 64); err != nil {
+		return nil, errInvalid
+	}
+	return &cursor, nil
+}
+
+// RankBetween returns a rank that sorts between the ranks of the two
+// neighbours a task is dropped between; tasks are ordered by ascending
+// rank. A nil neighbour is the edge of the list; with no neighbours at all
+// current is kept. Ranks are fractional so a move never renumbers other
+// tasks, until repeated halving leaves no room between two neighbours:
+// then false is returned and the list needs rebalancing first.
+func RankBetween(before *float64, after *float64, current float64) (float64, bool) {
+	switch {
+	case before != nil && after != nil:
+		if *after-*before < minRankGap {
+			return 0, false
+		}
+		return *before + (*after-*before)/2, true
+	case before != nil:
+		return *before + 1, true
+	case after != nil:
+		return *after - 1, true
+	default:
+		return current, true
+	}
+}
+
 func validTitle(title string) bool {
 	return title != ""
 }
@@ -0,0 +1,85 @@
+package model
+
+import (
+	"go-task/pkg"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const minPasswordLength = 8
+
+// dummyPasswordHash is a bcrypt hash of the same cost as those of NewUser
+// that no login password matches. Checking against it makes a login for
+// an unknown user or one without a password as slow as a wrong password,
+// so the response time does not reveal which usernames exist.
+const dummyPasswordHash = "$2a$10$t.p4Azqk9Yr8wcQKRR7Uw.iPyQ.Ke.Rh4YFtQNLG.rQcE60fYG5jy"
+
+type User struct {
+	ID           int64
+	WorkspaceID  int64
+	Username     string
+	PasswordHash string
+	Role         Role
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func NewUser(username string, password string) (*User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, &pkg.ValidationError{Field: "username", Message: "username cannot be empty"}
+	}
+	if len(password) < minPasswordLength {
+		return nil, &pkg.ValidationError{Field: "password", Message: "password must be at least 8 characters"}
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := time.Now()
+
+	return &User{
+		Username:     username,
+		PasswordHash: string(hash),
+		Role:         RoleMember,
+		CreatedAt:    timestamp,
+		UpdatedAt:    timestamp,
+	}, nil
+}
+
+// NewExternalUser creates an account that signs in through an identity
+// provider. It has no password, so password login always fails for it.
+func NewExternalUser(username string) (*User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, &pkg.ValidationError{Field: "username", Message: "username cannot be empty"}
+	}
+	timestamp := time.Now()
+
+	return &User{
+		Username:  username,
+		Role:      RoleMember,
+		CreatedAt: timestamp,
+		UpdatedAt: timestamp,
+	}, nil
+}
+
+func (user *User) CheckPassword(password string) bool {
+	if user.PasswordHash == "" {
+		return RejectPassword(password)
+	}
+	return bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) == nil
+}
+
+// RejectPassword spends as long as CheckPassword on a password and reports
+// it as wrong, for logins that cannot succeed.
+func RejectPassword(password string) bool {
+	_ = bcrypt.CompareHashAndPassword([]byte(dummyPasswordHash), []byte(password))
+	return false
+}
+
+func (user *User) Can(permission Permission) bool {
+	return user.Role.Can(permission)
+}
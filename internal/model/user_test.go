@@ -0,0 +1,52 @@
+package model
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The dummy hash only hides unknown usernames while it costs as much to
+// check as the hashes of real accounts.
+func TestDummyPasswordHashCost(t *testing.T) {
+	cost, err := bcrypt.Cost([]byte(dummyPasswordHash))
+	if err != nil {
+		t.Fatalf("dummy hash: %v", err)
+	}
+	if cost != bcrypt.DefaultCost {
+		t.Fatalf("dummy hash cost = %d, want %d as in NewUser", cost, bcrypt.DefaultCost)
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	user, err := NewUser("ada", "correct horse")
+	if err != nil {
+		t.Fatalf("NewUser: %v", err)
+	}
+	external, err := NewExternalUser("grace")
+	if err != nil {
+		t.Fatalf("NewExternalUser: %v", err)
+	}
+	tests := []struct {
+		name     string
+		user     *User
+		password string
+		want     bool
+	}{
+		{"right password", user, "correct horse", true},
+		{"wrong password", user, "battery staple", false},
+		{"empty password", user, "", false},
+		{"account without password", external, "", false},
+		{"account without password, any password", external, "correct horse", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.CheckPassword(tt.password); got != tt.want {
+				t.Errorf("CheckPassword(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+	if RejectPassword("correct horse") {
+		t.Error("RejectPassword accepted a password")
+	}
+}
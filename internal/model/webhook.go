@@ -0,0 +1,164 @@
+package model
+
+import (
+	"fmt"
+	"go-task/pkg"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+type WebhookEvent string
+
+const (
+	WebhookTaskCreated       WebhookEvent = "task.created"
+	WebhookTaskUpdated       WebhookEvent = "task.updated"
+	WebhookTaskDeleted       WebhookEvent = "task.deleted"
+	WebhookTaskStatusChanged WebhookEvent = "task.status_changed"
+)
+
+var webhookEvents = []WebhookEvent{WebhookTaskCreated, WebhookTaskUpdated, WebhookTaskDeleted, WebhookTaskStatusChanged}
+
+func (e WebhookEvent) IsValid() bool {
+	return slices.Contains(webhookEvents, e)
+}
+
+// WebhookEventOf names the webhook event a task history entry is
+// delivered as. Restoring a task from the trash counts as an update.
+func WebhookEventOf(operation EventOperation) WebhookEvent {
+	switch operation {
+	case EventCreate:
+		return WebhookTaskCreated
+	case EventStatusChange:
+		return WebhookTaskStatusChanged
+	case EventDelete:
+		return WebhookTaskDeleted
+	}
+	return WebhookTaskUpdated
+}
+
+// Webhook subscribes a URL to task events of a workspace. Deliveries are
+// signed with Secret, which is kept in plaintext because signing needs it.
+type Webhook struct {
+	ID          int64
+	WorkspaceID int64
+	URL         string
+	Secret      string
+	Events      []WebhookEvent
+	CreatedAt   time.Time
+}
+
+// NewWebhook subscribes rawURL to events; no events means all of them. An
+// empty secret is replaced by a random one. URLs naming a non-public
+// address or localhost are refused up front; names resolving to such an
+// address are refused by the dispatcher when it dials them.
+func NewWebhook(rawURL string, secret string, events []WebhookEvent) (*Webhook, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return nil, &pkg.ValidationError{Field: "url", Message: "webhook url must be an absolute http or https url"}
+	}
+	if !publicHost(target.Hostname()) {
+		return nil, &pkg.ValidationError{Field: "url", Message: "webhook url must point to a public address"}
+	}
+	for _, event := range events {
+		if !event.IsValid() {
+			return nil, &pkg.ValidationError{Field: "events", Message: fmt.Sprintf("unknown webhook event %q", event)}
+		}
+	}
+	if len(events) == 0 {
+		events = slices.Clone(webhookEvents)
+	}
+	if secret == "" {
+		if secret, err = NewToken(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Webhook{
+		URL:       target.String(),
+		Secret:    secret,
+		Events:    events,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// nonPublicPrefixes are the ranges besides loopback, private, link-local,
+// multicast and unspecified addresses that webhooks may not be sent to.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+// PublicAddr reports whether addr is reachable on the public internet
+// rather than on the host itself or its internal network.
+func PublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// publicHost reports whether host may be public: an address must be, and
+// of names only localhost is known not to be.
+func publicHost(host string) bool {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return PublicAddr(addr)
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	return host != "localhost" && !strings.HasSuffix(host, ".localhost")
+}
+
+func (webhook *Webhook) Subscribes(event WebhookEvent) bool {
+	return slices.Contains(webhook.Events, event)
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// DeliveryDead marks a delivery whose retries ran out or that did not
+	// fit in the delivery queue; it waits in the log for the next replay.
+	DeliveryDead DeliveryStatus = "dead"
+	// DeliveryFailed marks a delivery that is no longer replayed.
+	DeliveryFailed DeliveryStatus = "failed"
+)
+
+// WebhookDelivery is one entry of the delivery log. ResponseCode and Error
+// describe the latest attempt.
+type WebhookDelivery struct {
+	ID           int64
+	WebhookID    int64
+	Event        WebhookEvent
+	Payload      []byte
+	Status       DeliveryStatus
+	Attempts     int
+	ResponseCode *int
+	Error        string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func NewWebhookDelivery(webhookID int64, event WebhookEvent, payload []byte) *WebhookDelivery {
+	timestamp := time.Now()
+	return &WebhookDelivery{
+		WebhookID: webhookID,
+		Event:     event,
+		Payload:   payload,
+		Status:    DeliveryPending,
+		CreatedAt: timestamp,
+		UpdatedAt: timestamp,
+	}
+}
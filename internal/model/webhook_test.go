@@ -0,0 +1,44 @@
+package model
+
+import (
+	"errors"
+	"go-task/pkg"
+	"testing"
+)
+
+func TestNewWebhookURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://hooks.example.com/go-task", true},
+		{"http://93.184.215.14:8080/hook", true},
+		{"https://[2606:2800:21f:cb07:6820:80da:af6b:8b2c]/hook", true},
+		{"ftp://hooks.example.com/go-task", false},
+		{"/relative", false},
+		{"https://", false},
+		{"http://localhost:8080/hook", false},
+		{"http://LOCALHOST./hook", false},
+		{"http://api.localhost/hook", false},
+		{"http://127.0.0.1/hook", false},
+		{"http://[::1]/hook", false},
+		{"http://10.0.0.5/hook", false},
+		{"http://192.168.0.10/hook", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://[fe80::1]/hook", false},
+		{"http://0.0.0.0/hook", false},
+		{"http://[::ffff:10.0.0.5]/hook", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			webhook, err := NewWebhook(tt.url, "secret", nil)
+			if tt.want && err != nil {
+				t.Fatalf("got %v, want the url accepted", err)
+			}
+			var validationErr *pkg.ValidationError
+			if !tt.want && (!errors.As(err, &validationErr) || validationErr.Field != "url") {
+				t.Fatalf("got %v, %v; want a validation error on url", webhook, err)
+			}
+		})
+	}
+}
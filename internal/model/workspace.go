@@ -0,0 +1,26 @@
+package model
+
+import (
+	"go-task/pkg"
+	"strings"
+	"time"
+)
+
+// Workspace is the tenant that owns users, tasks and everything attached
+// to them. Nothing is shared between workspaces.
+type Workspace struct {
+	ID        int64
+	Name      string
+	CreatedAt time.Time
+}
+
+func NewWorkspace(name string) (*Workspace, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, &pkg.ValidationError{Field: "workspace", Message: "workspace name cannot be empty"}
+	}
+	return &Workspace{
+		Name:      name,
+		CreatedAt: time.Now(),
+	}, nil
+}
@@ -0,0 +1,210 @@
+package openapi
+
+import (
+	"fmt"
+	"go-task/pkg"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const jsonContentType = "application/json"
+
+// Document is an OpenAPI 3.0 description of the HTTP API. Its schemas are
+// generated from the Go types the handlers decode and encode, so the
+// document cannot drift from them.
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security,omitempty"`
+
+	enums map[reflect.Type][]string
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps the lower-case HTTP methods of a path to their operations.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+// Route describes one operation. Request and Response are values of the
+// body types, nil when there is no body; path parameters are taken from
+// the braces in Path and are integer ids.
+type Route struct {
+	Method      string
+	Path        string
+	ID          string
+	Summary     string
+	Tag         string
+	Query       []*Parameter
+	Request     any
+	RequestType string
+	Response    any
+	Status      int
+}
+
+func New(info Info) *Document {
+	return &Document{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas:         make(map[string]*Schema),
+			SecuritySchemes: make(map[string]*SecurityScheme),
+		},
+		enums: make(map[reflect.Type][]string),
+	}
+}
+
+// Enum lists the values of a named string type, such as a status, for
+// every schema the type appears in.
+func (doc *Document) Enum(value any, values ...string) {
+	doc.enums[reflect.TypeOf(value)] = values
+}
+
+// Secure requires one of the given security schemes on every operation.
+func (doc *Document) Secure(name string, scheme *SecurityScheme) {
+	doc.Components.SecuritySchemes[name] = scheme
+	doc.Security = append(doc.Security, map[string][]string{name: {}})
+}
+
+func (doc *Document) Add(route Route) {
+	operation := &Operation{
+		OperationID: route.ID,
+		Summary:     route.Summary,
+		Responses:   make(map[string]*Response),
+	}
+	if route.Tag != "" {
+		operation.Tags = []string{route.Tag}
+	}
+	for _, name := range pathParams(route.Path) {
+		operation.Parameters = append(operation.Parameters, &Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "integer", Format: "int64"},
+		})
+	}
+	for _, query := range route.Query {
+		query.In = "query"
+		query.Schema = &Schema{Type: "string"}
+		operation.Parameters = append(operation.Parameters, query)
+	}
+	if route.Request != nil {
+		contentType := route.RequestType
+		if contentType == "" {
+			contentType = jsonContentType
+		}
+		operation.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{contentType: {Schema: doc.schemaOf(reflect.TypeOf(route.Request), true)}},
+		}
+	}
+
+	success := &Response{Description: http.StatusText(route.Status)}
+	if route.Response != nil {
+		success.Content = map[string]MediaType{jsonContentType: {Schema: doc.schemaOf(reflect.TypeOf(route.Response), false)}}
+	}
+	operation.Responses[strconv.Itoa(route.Status)] = success
+	operation.Responses["default"] = &Response{
+		Description: "Error",
+		Content:     map[string]MediaType{pkg.ProblemContentType: {Schema: doc.schemaOf(reflect.TypeOf(pkg.Problem{}), false)}},
+	}
+
+	item, ok := doc.Paths[route.Path]
+	if !ok {
+		item = make(PathItem)
+		doc.Paths[route.Path] = item
+	}
+	method := strings.ToLower(route.Method)
+	if _, ok := item[method]; ok {
+		panic(fmt.Sprintf("openapi: %s %s is described twice", route.Method, route.Path))
+	}
+	item[method] = operation
+}
+
+// operations lists the paths and methods of the document, literal paths
+// first so they take precedence over the templated ones when matching.
+func (doc *Document) operations() []*matcher {
+	var matchers []*matcher
+	for path, item := range doc.Paths {
+		for method, operation := range item {
+			matchers = append(matchers, &matcher{
+				method:    strings.ToUpper(method),
+				segments:  strings.Split(strings.Trim(path, "/"), "/"),
+				operation: operation,
+			})
+		}
+	}
+	sort.SliceStable(matchers, func(i, j int) bool {
+		return matchers[i].literals() > matchers[j].literals()
+	})
+	return matchers
+}
+
+func pathParams(path string) []string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if name, ok := paramName(segment); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func paramName(segment string) (string, bool) {
+	if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+		return segment[1 : len(segment)-1], true
+	}
+	return "", false
+}
@@ -0,0 +1,127 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of the OpenAPI schema object the API needs.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties any                `json:"additionalProperties,omitempty"`
+}
+
+const refPrefix = "#/components/schemas/"
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaOf describes t. Named structs become components referenced by
+// name. Request bodies are strict: their objects allow no other members,
+// which is what the decoders of the patch endpoints enforce as well.
+func (doc *Document) schemaOf(t reflect.Type, request bool) *Schema {
+	if values, ok := doc.enums[t]; ok {
+		return &Schema{Type: "string", Enum: values}
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	case isOptional(t):
+		schema := doc.schemaOf(t.Field(0).Type, request)
+		if schema.Ref == "" {
+			schema.Nullable = true
+		}
+		return schema
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := doc.schemaOf(t.Elem(), request)
+		if schema.Ref == "" {
+			schema.Nullable = true
+		}
+		return schema
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: doc.schemaOf(t.Elem(), request)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: doc.schemaOf(t.Elem(), request)}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return doc.objectOf(t, request)
+		}
+		if _, ok := doc.Components.Schemas[t.Name()]; !ok {
+			// Registered before the fields are described so a type that
+			// refers to itself ends in a reference.
+			doc.Components.Schemas[t.Name()] = &Schema{}
+			*doc.Components.Schemas[t.Name()] = *doc.objectOf(t, request)
+		}
+		return &Schema{Ref: refPrefix + t.Name()}
+	}
+	return &Schema{}
+}
+
+// objectOf describes the exported fields of a struct by their JSON names.
+// Fields without omitempty are required, except pointers and optional
+// fields, which may be left out. An enum tag lists the allowed values of a
+// string field.
+func (doc *Document) objectOf(t reflect.Type, request bool) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	if request {
+		schema.AdditionalProperties = false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		property := doc.schemaOf(field.Type, request)
+		if enum := field.Tag.Get("enum"); enum != "" {
+			values := strings.Split(enum, ",")
+			if property.Type == "array" {
+				property.Items = &Schema{Type: "string", Enum: values}
+			} else {
+				property.Enum = values
+			}
+		}
+		schema.Properties[name] = property
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Pointer && !isOptional(field.Type) {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+// isOptional reports whether t is an instance of pkg.Optional.
+func isOptional(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.PkgPath() == "go-task/pkg" && strings.HasPrefix(t.Name(), "Optional[")
+}
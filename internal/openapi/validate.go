@@ -0,0 +1,224 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go-task/pkg"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type matcher struct {
+	method    string
+	segments  []string
+	operation *Operation
+}
+
+func (m *matcher) literals() int {
+	count := 0
+	for _, segment := range m.segments {
+		if _, ok := paramName(segment); !ok {
+			count++
+		}
+	}
+	return count
+}
+
+func (m *matcher) match(method string, path string) (map[string]string, bool) {
+	if method != m.method {
+		return nil, false
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) != len(m.segments) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, segment := range m.segments {
+		if name, ok := paramName(segment); ok {
+			params[name] = segments[i]
+		} else if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// Validate checks the requests of the operations in doc against it before
+// they reach next: path and query parameters, and JSON bodies against
+// their schemas. Invalid requests are answered with a validation problem
+// listing every field that failed. Requests for paths the document does
+// not describe pass unchecked.
+func Validate(doc *Document, next http.Handler) http.Handler {
+	matchers := doc.operations()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, m := range matchers {
+			params, ok := m.match(r.Method, r.URL.Path)
+			if !ok {
+				continue
+			}
+			problems, err := validateRequest(doc, m.operation, params, r)
+			if err != nil {
+				pkg.WriteProblem(w, pkg.NewProblem(http.StatusBadRequest, err.Error()))
+				return
+			}
+			if len(problems) > 0 {
+				problem := pkg.NewProblem(http.StatusBadRequest, "request does not match the API description")
+				problem.Type = pkg.ValidationProblemType
+				problem.Title = "Invalid request"
+				problem.Errors = problems
+				pkg.WriteProblem(w, problem)
+				return
+			}
+			break
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func validateRequest(doc *Document, operation *Operation, params map[string]string, r *http.Request) ([]pkg.FieldProblem, error) {
+	var problems []pkg.FieldProblem
+	for _, param := range operation.Parameters {
+		var value string
+		switch param.In {
+		case "path":
+			value = params[param.Name]
+		case "query":
+			value = strings.TrimSpace(r.URL.Query().Get(param.Name))
+		}
+		if value == "" {
+			if param.Required {
+				problems = append(problems, pkg.FieldProblem{Field: param.Name, Detail: "is required"})
+			}
+			continue
+		}
+		if param.Schema.Type == "integer" {
+			if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+				problems = append(problems, pkg.FieldProblem{Field: param.Name, Detail: "must be an integer"})
+			}
+		}
+	}
+	if operation.RequestBody == nil {
+		return problems, nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if len(bytes.TrimSpace(body)) == 0 {
+		return append(problems, pkg.FieldProblem{Detail: "request body is required"}), nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("request body is not valid JSON: %w", err)
+	}
+	for _, media := range operation.RequestBody.Content {
+		problems = doc.validate(media.Schema, value, "", problems)
+	}
+	return problems, nil
+}
+
+// validate appends the ways value fails schema to problems. Fields are
+// named by their path, such as operations[0].task.title.
+func (doc *Document) validate(schema *Schema, value any, field string, problems []pkg.FieldProblem) []pkg.FieldProblem {
+	if schema.Ref != "" {
+		schema = doc.Components.Schemas[strings.TrimPrefix(schema.Ref, refPrefix)]
+	}
+	if value == nil {
+		if schema.Nullable || schema.Type == "" {
+			return problems
+		}
+		return append(problems, pkg.FieldProblem{Field: field, Detail: "must not be null"})
+	}
+
+	fail := func(detail string) []pkg.FieldProblem {
+		return append(problems, pkg.FieldProblem{Field: field, Detail: detail})
+	}
+	switch schema.Type {
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fail("must be a string")
+		}
+		if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, s) {
+			return fail("must be one of " + strings.Join(schema.Enum, ", "))
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				return fail("must be an RFC 3339 date-time")
+			}
+		}
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return fail("must be an integer")
+		}
+		if _, err := n.Int64(); err != nil {
+			return fail("must be an integer")
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return fail("must be a number")
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fail("must be a boolean")
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fail("must be an array")
+		}
+		for i, item := range items {
+			problems = doc.validate(schema.Items, item, fmt.Sprintf("%s[%d]", field, i), problems)
+		}
+	case "object":
+		members, ok := value.(map[string]any)
+		if !ok {
+			return fail("must be an object")
+		}
+		for _, name := range schema.Required {
+			if _, ok := members[name]; !ok {
+				problems = append(problems, pkg.FieldProblem{Field: join(field, name), Detail: "is required"})
+			}
+		}
+		for _, name := range sortedKeys(members) {
+			property, ok := schema.Properties[name]
+			if !ok {
+				property, ok = schema.AdditionalProperties.(*Schema)
+			}
+			if !ok {
+				if schema.AdditionalProperties == false {
+					problems = append(problems, pkg.FieldProblem{Field: join(field, name), Detail: "is not a known field"})
+				}
+				continue
+			}
+			problems = doc.validate(property, members[name], join(field, name), problems)
+		}
+	}
+	return problems
+}
+
+func join(field string, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
+
+func sortedKeys(members map[string]any) []string {
+	keys := make([]string, 0, len(members))
+	for key := range members {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
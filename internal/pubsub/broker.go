@@ -0,0 +1,107 @@
+package pubsub
+
+import (
+	"go-task/internal/model"
+	"log"
+	"sync"
+)
+
+// subscriptionBuffer is how many changes a subscriber may fall behind
+// before further changes are dropped for it.
+const subscriptionBuffer = 64
+
+// Broker fans the task changes written by the datastore out to the search
+// sync and to live subscribers such as open browser tabs.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription receives copies of the changed tasks of one workspace,
+// optionally narrowed to one project.
+type Subscription struct {
+	C           <-chan model.Task
+	ch          chan model.Task
+	workspaceID int64
+	projectID   *int64
+}
+
+// NewBroker publishes the tasks of every batch of changes read from
+// changes to the subscribers and forwards the batch to sink. Neither waits
+// for the other: when sink is full the batch is handed to overflow instead,
+// such as a dead letter queue the search sync replays later. sink is
+// closed once changes is.
+func NewBroker(changes <-chan []*model.Task, sink chan<- []*model.Task, overflow func(tasks []*model.Task)) *Broker {
+	broker := &Broker{subscribers: make(map[*Subscription]struct{})}
+	go broker.run(changes, sink, overflow)
+	return broker
+}
+
+func (broker *Broker) run(changes <-chan []*model.Task, sink chan<- []*model.Task, overflow func(tasks []*model.Task)) {
+	log.Println("starting task change broker")
+	for tasks := range changes {
+		for _, task := range tasks {
+			broker.publish(*task)
+		}
+		select {
+		case sink <- tasks:
+		default:
+			log.Printf("search sync is behind, dead-lettering %d changed tasks", len(tasks))
+			overflow(tasks)
+		}
+	}
+	close(sink)
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	for sub := range broker.subscribers {
+		close(sub.ch)
+		delete(broker.subscribers, sub)
+	}
+}
+
+// publish never blocks on a slow subscriber; the change is dropped for it
+// instead and the next change of the same task brings it up to date.
+func (broker *Broker) publish(task model.Task) {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	for sub := range broker.subscribers {
+		if !sub.matches(task) {
+			continue
+		}
+		select {
+		case sub.ch <- task:
+		default:
+			log.Printf("dropping change of task %d for a slow subscriber", task.ID)
+		}
+	}
+}
+
+// Subscribe starts receiving the changes of workspaceID, or only of the
+// tasks in projectID when it is set.
+func (broker *Broker) Subscribe(workspaceID int64, projectID *int64) *Subscription {
+	ch := make(chan model.Task, subscriptionBuffer)
+	sub := &Subscription{C: ch, ch: ch, workspaceID: workspaceID, projectID: projectID}
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	broker.subscribers[sub] = struct{}{}
+	return sub
+}
+
+func (broker *Broker) Unsubscribe(sub *Subscription) {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	if _, ok := broker.subscribers[sub]; ok {
+		delete(broker.subscribers, sub)
+		close(sub.ch)
+	}
+}
+
+func (sub *Subscription) matches(task model.Task) bool {
+	if task.WorkspaceID != sub.workspaceID {
+		return false
+	}
+	if sub.projectID == nil {
+		return true
+	}
+	return task.ProjectID != nil && *task.ProjectID == *sub.projectID
+}
@@ -0,0 +1,65 @@
+package pubsub
+
+import (
+	"go-task/internal/model"
+	"testing"
+	"time"
+)
+
+// A search sync that has stopped reading must not hold up live
+// subscribers; the changes it cannot take go to the overflow instead.
+func TestStalledSinkDoesNotBlockSubscribers(t *testing.T) {
+	changes := make(chan []*model.Task)
+	sink := make(chan []*model.Task)
+	overflowed := make(chan []*model.Task, 3)
+	broker := NewBroker(changes, sink, func(tasks []*model.Task) { overflowed <- tasks })
+	sub := broker.Subscribe(1, nil)
+
+	for id := int64(1); id <= 3; id++ {
+		changes <- []*model.Task{{ID: id, WorkspaceID: 1}}
+		select {
+		case task := <-sub.C:
+			if task.ID != id {
+				t.Fatalf("got task %d, want %d", task.ID, id)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("change of task %d never reached the subscriber", id)
+		}
+		select {
+		case tasks := <-overflowed:
+			if len(tasks) != 1 || tasks[0].ID != id {
+				t.Fatalf("overflow got %v, want task %d", tasks, id)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("change of task %d was neither synced nor dead-lettered", id)
+		}
+	}
+	close(changes)
+	if _, ok := <-sink; ok {
+		t.Fatal("sink got a change after it was full")
+	}
+}
+
+func TestSubscriptionFilters(t *testing.T) {
+	project := int64(5)
+	other := int64(6)
+	tests := []struct {
+		name string
+		sub  Subscription
+		task model.Task
+		want bool
+	}{
+		{"workspace", Subscription{workspaceID: 1}, model.Task{WorkspaceID: 1}, true},
+		{"other workspace", Subscription{workspaceID: 1}, model.Task{WorkspaceID: 2}, false},
+		{"project", Subscription{workspaceID: 1, projectID: &project}, model.Task{WorkspaceID: 1, ProjectID: &project}, true},
+		{"other project", Subscription{workspaceID: 1, projectID: &project}, model.Task{WorkspaceID: 1, ProjectID: &other}, false},
+		{"no project", Subscription{workspaceID: 1, projectID: &project}, model.Task{WorkspaceID: 1}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sub.matches(tt.task); got != tt.want {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"go-task/internal/service"
+	"go-task/pkg"
+	"log"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// methodScopes maps the calls that change state to the HTTP method of the
+// same change, so a token scope allows the same calls over both APIs.
+// Calls not listed only read.
+var methodScopes = map[string]string{
+	"/tasks.v1.TaskService/Create": http.MethodPost,
+	"/tasks.v1.TaskService/Update": http.MethodPatch,
+	"/tasks.v1.TaskService/Delete": http.MethodDelete,
+}
+
+// authenticator admits calls carrying an API token in the authorization
+// metadata, "Bearer <token>" as in the HTTP API. Reflection is public so
+// tools like grpcurl can list the services without a token.
+type authenticator struct {
+	tokens *service.TokenService
+}
+
+func (auth *authenticator) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := auth.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (auth *authenticator) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if strings.HasPrefix(info.FullMethod, "/grpc.reflection.") {
+		return handler(srv, ss)
+	}
+	ctx, err := auth.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+func (auth *authenticator) authenticate(ctx context.Context, fullMethod string) (context.Context, error) {
+	bearer, ok := bearerToken(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+	user, token, err := auth.tokens.Authenticate(bearer)
+	if err != nil {
+		if !errors.Is(err, pkg.ErrNotFound) {
+			log.Println("Failed to authenticate api token:", err)
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+		return nil, status.Error(codes.Unauthenticated, "invalid or revoked token")
+	}
+	method, ok := methodScopes[fullMethod]
+	if !ok {
+		method = http.MethodGet
+	}
+	if !token.Scope.Allows(method) {
+		return nil, status.Error(codes.PermissionDenied, "token scope does not allow "+fullMethod)
+	}
+	return service.WithUser(ctx, user), nil
+}
+
+func bearerToken(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return "", false
+	}
+	scheme, token, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// authenticatedStream carries the context of the authenticated user into
+// a streaming handler.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *authenticatedStream) Context() context.Context {
+	return stream.ctx
+}
+
+// recoverUnary and recoverStream answer a panicking call with Internal, as
+// pkg.HandleError does for HTTP requests.
+func recoverUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(info.FullMethod, r)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func recoverStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(info.FullMethod, r)
+		}
+	}()
+	return handler(srv, ss)
+}
+
+func recovered(method string, r any) error {
+	log.Printf("panic serving %s: %v", method, r)
+	return status.Error(codes.Internal, "internal error")
+}
@@ -0,0 +1,157 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/pkg"
+	tasksv1 "go-task/pkg/proto/tasks/v1"
+	"log"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	statusToProto = map[pkg.TaskStatus]tasksv1.Status{
+		pkg.TODO:      tasksv1.Status_STATUS_TODO,
+		pkg.PENDING:   tasksv1.Status_STATUS_PENDING,
+		pkg.COMPLETED: tasksv1.Status_STATUS_COMPLETED,
+	}
+	statusFromProto = map[tasksv1.Status]pkg.TaskStatus{
+		tasksv1.Status_STATUS_TODO:      pkg.TODO,
+		tasksv1.Status_STATUS_PENDING:   pkg.PENDING,
+		tasksv1.Status_STATUS_COMPLETED: pkg.COMPLETED,
+	}
+)
+
+// editablePaths are the update mask paths an empty mask stands for.
+var editablePaths = []string{"title", "content", "status", "labels", "due_at", "recurrence", "project_id"}
+
+func toProto(task *model.Task) *tasksv1.Task {
+	res := &tasksv1.Task{
+		Id:        task.ID,
+		ProjectId: task.ProjectID,
+		Title:     task.Title,
+		Content:   task.Content,
+		Status:    statusToProto[task.Status],
+		Labels:    task.Labels,
+		DueAt:     timestamp(task.DueAt),
+		Rank:      task.Rank,
+		CreatedAt: timestamppb.New(task.CreatedAt),
+		UpdatedAt: timestamppb.New(task.UpdatedAt),
+		DeletedAt: timestamp(task.DeletedAt),
+	}
+	if task.Recurrence != nil {
+		res.Recurrence = task.Recurrence.String()
+	}
+	return res
+}
+
+// fromProto builds a new task like the HTTP API does from a task request.
+// An unspecified status is invalid there as well.
+func fromProto(req *tasksv1.Task) (*model.Task, error) {
+	if req == nil {
+		return nil, &pkg.ValidationError{Field: "task", Message: "task is required"}
+	}
+	task, err := model.NewTask(req.GetTitle(), req.GetContent(), statusFromProto[req.GetStatus()])
+	if err != nil {
+		return nil, err
+	}
+	task.Labels = req.GetLabels()
+	task.DueAt = timeOf(req.GetDueAt())
+	task.ProjectID = req.ProjectId
+	if req.GetRecurrence() != "" {
+		task.Recurrence, err = model.ParseRecurrence(req.GetRecurrence())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return task, nil
+}
+
+// patchFromProto sets the fields of the patch named by paths to their
+// values in req, which clears the fields req leaves empty.
+func patchFromProto(req *tasksv1.Task, paths []string) (model.TaskPatch, error) {
+	if req == nil {
+		req = &tasksv1.Task{}
+	}
+	if len(paths) == 0 {
+		paths = editablePaths
+	}
+	var patch model.TaskPatch
+	for _, path := range paths {
+		switch path {
+		case "title":
+			patch.Title = pkg.Optional[string]{Value: req.GetTitle(), Set: true}
+		case "content":
+			patch.Content = pkg.Optional[string]{Value: req.GetContent(), Set: true}
+		case "status":
+			patch.Status = pkg.Optional[pkg.TaskStatus]{Value: statusFromProto[req.GetStatus()], Set: true}
+		case "labels":
+			patch.Labels = pkg.Optional[[]string]{Value: req.GetLabels(), Set: true}
+		case "due_at":
+			patch.DueAt = pkg.Optional[*time.Time]{Value: timeOf(req.GetDueAt()), Set: true}
+		case "project_id":
+			patch.ProjectID = pkg.Optional[*int64]{Value: req.ProjectId, Set: true}
+		case "recurrence":
+			patch.Recurrence.Set = true
+			if req.GetRecurrence() != "" {
+				recurrence, err := model.ParseRecurrence(req.GetRecurrence())
+				if err != nil {
+					return model.TaskPatch{}, err
+				}
+				patch.Recurrence.Value = recurrence
+			}
+		default:
+			return model.TaskPatch{}, &pkg.ValidationError{Field: "update_mask", Message: fmt.Sprintf("%q is not an editable field", path)}
+		}
+	}
+	return patch, nil
+}
+
+func timestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func timeOf(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+// statusFor maps the error classes of the service layer to status codes,
+// as pkg.ProblemFor does to HTTP statuses. A validation error carries the
+// field that failed as a BadRequest detail.
+func statusFor(err error) error {
+	var validationErr *pkg.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		st := status.New(codes.InvalidArgument, validationErr.Message)
+		detailed, detailErr := st.WithDetails(&errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: validationErr.Field, Description: validationErr.Message}},
+		})
+		if detailErr != nil {
+			return st.Err()
+		}
+		return detailed.Err()
+	case errors.Is(err, pkg.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, pkg.ErrConflict):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, pkg.ErrForbidden):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, pkg.ErrUnauthorized):
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	log.Println("internal error:", err)
+	return status.Error(codes.Internal, "internal error")
+}
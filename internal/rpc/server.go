@@ -0,0 +1,136 @@
+package rpc
+
+import (
+	"context"
+	"go-task/internal/model"
+	"go-task/internal/pubsub"
+	"go-task/internal/service"
+	"go-task/pkg"
+	tasksv1 "go-task/pkg/proto/tasks/v1"
+	"log"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// TaskServer serves tasks.v1.TaskService on top of the same services as
+// the HTTP API, so both enforce the same permissions and validation.
+type TaskServer struct {
+	tasksv1.UnimplementedTaskServiceServer
+	tasks    *service.Service
+	projects *service.ProjectService
+	broker   *pubsub.Broker
+}
+
+func NewTaskServer(tasks *service.Service, projects *service.ProjectService, broker *pubsub.Broker) *TaskServer {
+	return &TaskServer{
+		tasks:    tasks,
+		projects: projects,
+		broker:   broker,
+	}
+}
+
+// NewServer returns a gRPC server with the task service and server
+// reflection registered. Every call is authenticated with an API token.
+func NewServer(tasks *TaskServer, tokens *service.TokenService) *grpc.Server {
+	auth := &authenticator{tokens: tokens}
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(recoverUnary, auth.unary),
+		grpc.ChainStreamInterceptor(recoverStream, auth.stream),
+	)
+	tasksv1.RegisterTaskServiceServer(server, tasks)
+	reflection.Register(server)
+	return server
+}
+
+func (server *TaskServer) Create(ctx context.Context, req *tasksv1.CreateRequest) (*tasksv1.Task, error) {
+	task, err := fromProto(req.GetTask())
+	if err != nil {
+		return nil, statusFor(err)
+	}
+	created, err := server.tasks.Create(ctx, task)
+	if err != nil {
+		return nil, statusFor(err)
+	}
+	return toProto(created), nil
+}
+
+func (server *TaskServer) Get(ctx context.Context, req *tasksv1.GetRequest) (*tasksv1.Task, error) {
+	task, err := server.tasks.FindById(ctx, req.GetId())
+	if err != nil {
+		return nil, statusFor(err)
+	}
+	return toProto(task), nil
+}
+
+func (server *TaskServer) List(ctx context.Context, req *tasksv1.ListRequest) (*tasksv1.ListResponse, error) {
+	var tasks []*model.Task
+	var err error
+	if req.ProjectId != nil {
+		tasks, err = server.projects.Tasks(ctx, req.GetProjectId())
+	} else {
+		tasks, err = server.tasks.FindAll(ctx)
+	}
+	if err != nil {
+		return nil, statusFor(err)
+	}
+	res := &tasksv1.ListResponse{Tasks: make([]*tasksv1.Task, 0, len(tasks))}
+	for _, task := range tasks {
+		res.Tasks = append(res.Tasks, toProto(task))
+	}
+	return res, nil
+}
+
+func (server *TaskServer) Update(ctx context.Context, req *tasksv1.UpdateRequest) (*tasksv1.Task, error) {
+	patch, err := patchFromProto(req.GetTask(), req.GetUpdateMask().GetPaths())
+	if err != nil {
+		return nil, statusFor(err)
+	}
+	task, err := server.tasks.Patch(ctx, req.GetId(), patch)
+	if err != nil {
+		return nil, statusFor(err)
+	}
+	return toProto(task), nil
+}
+
+func (server *TaskServer) Delete(ctx context.Context, req *tasksv1.DeleteRequest) (*emptypb.Empty, error) {
+	if err := server.tasks.Delete(ctx, req.GetId()); err != nil {
+		return nil, statusFor(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// Watch sends the changes the broker publishes for the caller's workspace
+// until the client cancels or the server shuts down. Like the live page
+// feed, a slow client misses changes rather than holding up the others.
+func (server *TaskServer) Watch(req *tasksv1.WatchRequest, stream grpc.ServerStreamingServer[tasksv1.TaskChange]) error {
+	user, ok := service.UserFrom(stream.Context())
+	if !ok {
+		return statusFor(pkg.ErrUnauthorized)
+	}
+	if req.ProjectId != nil {
+		// Fails for a project outside the workspace.
+		if _, err := server.projects.FindById(stream.Context(), req.GetProjectId()); err != nil {
+			return statusFor(err)
+		}
+	}
+
+	sub := server.broker.Subscribe(user.WorkspaceID, req.ProjectId)
+	defer server.broker.Unsubscribe(sub)
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case task, ok := <-sub.C:
+			if !ok {
+				return nil
+			}
+			change := &tasksv1.TaskChange{Task: toProto(&task), Deleted: task.DeletedAt != nil}
+			if err := stream.Send(change); err != nil {
+				log.Printf("stopping task watch: %s", err)
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/pkg"
+)
+
+const anonymousActor = "anonymous"
+
+type userKey struct{}
+
+// WithUser returns a context carrying the signed-in user.
+func WithUser(ctx context.Context, user *model.User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+func UserFrom(ctx context.Context) (*model.User, bool) {
+	user, ok := ctx.Value(userKey{}).(*model.User)
+	return user, ok && user != nil
+}
+
+func ActorFrom(ctx context.Context) string {
+	if user, ok := UserFrom(ctx); ok {
+		return user.Username
+	}
+	return anonymousActor
+}
+
+func userID(ctx context.Context) *int64 {
+	if user, ok := UserFrom(ctx); ok {
+		id := user.ID
+		return &id
+	}
+	return nil
+}
+
+// workspaceFrom returns the workspace of the signed-in user in ctx. Every
+// tenant-scoped lookup goes through it, so a caller without a user cannot
+// reach any workspace's data.
+func workspaceFrom(ctx context.Context) (int64, error) {
+	if user, ok := UserFrom(ctx); ok {
+		return user.WorkspaceID, nil
+	}
+	return 0, fmt.Errorf("%s has no workspace: %w", ActorFrom(ctx), pkg.ErrForbidden)
+}
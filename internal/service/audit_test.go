@@ -0,0 +1,115 @@
+package service
+
+import (
+	"errors"
+	"go-task/internal/model"
+	"go-task/pkg"
+	"testing"
+	"time"
+)
+
+func TestMutationsRecordEvents(t *testing.T) {
+	now := date(2024, time.January, 1, 9)
+	service := newTestService(t, now)
+	ctx := userContext(1, 1, model.RoleAdmin)
+
+	task, err := service.Create(ctx, &model.Task{Title: "write report", Status: pkg.TODO})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := service.UpdateStatus(ctx, task.ID, pkg.PENDING); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+	if err := service.Delete(ctx, task.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := service.Restore(ctx, task.ID); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	history, err := service.History(ctx, task.ID)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	want := []model.EventOperation{model.EventCreate, model.EventStatusChange, model.EventDelete, model.EventRestore}
+	if len(history) != len(want) {
+		t.Fatalf("got %d events, want %d", len(history), len(want))
+	}
+	for i, event := range history {
+		if event.Operation != want[i] {
+			t.Errorf("event %d = %s, want %s", i, event.Operation, want[i])
+		}
+		if event.Actor != "user1" {
+			t.Errorf("event %d actor = %q, want user1", i, event.Actor)
+		}
+		if !event.CreatedAt.Equal(now) {
+			t.Errorf("event %d at %v, want %v", i, event.CreatedAt, now)
+		}
+	}
+	if len(service.notifier.events) != len(want) {
+		t.Errorf("notified %d events, want %d", len(service.notifier.events), len(want))
+	}
+}
+
+// A write whose event cannot be appended must not happen at all, so the
+// history never misses a change.
+func TestFailedAppendRollsBackWrite(t *testing.T) {
+	ctx := userContext(1, 1, model.RoleAdmin)
+	rule, _ := model.ParseRecurrence("FREQ=DAILY")
+	due := date(2024, time.January, 1, 9)
+	existing := model.Task{WorkspaceID: 1, Title: "water plants", Status: pkg.TODO, Recurrence: rule, DueAt: &due, Rank: 1}
+	// The existing task is the first one of every fresh store.
+	const id = 1
+
+	tests := []struct {
+		name   string
+		mutate func(service *testService) error
+	}{
+		{"create", func(service *testService) error {
+			_, err := service.Create(ctx, &model.Task{Title: "new", Status: pkg.TODO})
+			return err
+		}},
+		{"update", func(service *testService) error {
+			_, err := service.Update(ctx, model.Task{Title: "renamed"}, id)
+			return err
+		}},
+		{"patch", func(service *testService) error {
+			_, err := service.Patch(ctx, id, model.TaskPatch{Title: pkg.Optional[string]{Value: "renamed", Set: true}})
+			return err
+		}},
+		{"status with next occurrence", func(service *testService) error {
+			_, err := service.UpdateStatus(ctx, id, pkg.COMPLETED)
+			return err
+		}},
+		{"move", func(service *testService) error {
+			_, err := service.Move(ctx, id, pkg.COMPLETED, nil, nil)
+			return err
+		}},
+		{"delete", func(service *testService) error {
+			return service.Delete(ctx, id)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := existing
+			service := newTestService(t, date(2024, time.January, 1, 8), &task)
+			before := service.store.snapshot()
+			service.store.failAppend = true
+
+			err := tt.mutate(service)
+			if !errors.Is(err, pkg.ErrInternal) {
+				t.Fatalf("got %v, want the append failure", err)
+			}
+			if len(service.store.tasks) != len(before.tasks) {
+				t.Errorf("got %d tasks, want %d", len(service.store.tasks), len(before.tasks))
+			}
+			stored, _ := service.store.FindById(1, id)
+			if stored.Title != task.Title || stored.Status != task.Status || stored.DeletedAt != nil {
+				t.Errorf("task changed to %+v although its event was not recorded", stored)
+			}
+			if len(service.notifier.events) != 0 {
+				t.Errorf("notified %d events of a rolled back write", len(service.notifier.events))
+			}
+		})
+	}
+}
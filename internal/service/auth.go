@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/pkg"
+	"strings"
+	"time"
+)
+
+const SessionTTL = 7 * 24 * time.Hour
+
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+type UserStore interface {
+	Save(user *model.User) (*model.User, error)
+	FindById(id int64) (*model.User, error)
+	FindByUsername(username string) (*model.User, error)
+	FindAll(workspaceID int64) ([]*model.User, error)
+}
+
+type WorkspaceStore interface {
+	Save(workspace *model.Workspace) (*model.Workspace, error)
+	FindById(id int64) (*model.Workspace, error)
+}
+
+type SessionStore interface {
+	Save(session *model.Session) (*model.Session, error)
+	FindById(id string) (*model.Session, error)
+	Delete(id string) error
+}
+
+type AuthService struct {
+	users      UserStore
+	sessions   SessionStore
+	workspaces WorkspaceStore
+	clock      Clock
+}
+
+func NewAuthService(users UserStore, sessions SessionStore, workspaces WorkspaceStore) *AuthService {
+	return &AuthService{
+		users:      users,
+		sessions:   sessions,
+		workspaces: workspaces,
+		clock:      systemClock{},
+	}
+}
+
+// Register signs up a new team: it creates a workspace named
+// workspaceName, or after the user when empty, with the new account as its
+// admin. Joining an existing workspace goes through CreateUser instead.
+func (service *AuthService) Register(username string, password string, workspaceName string) (*model.User, error) {
+	user, err := model.NewUser(username, password)
+	if err != nil {
+		return nil, err
+	}
+	if err := service.checkUsernameFree(user.Username); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(workspaceName) == "" {
+		workspaceName = user.Username
+	}
+	workspace, err := model.NewWorkspace(workspaceName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := service.workspaces.Save(workspace); err != nil {
+		return nil, err
+	}
+	user.WorkspaceID = workspace.ID
+	user.Role = model.RoleAdmin
+	return service.users.Save(user)
+}
+
+// CreateUser adds an account with role to the caller's workspace.
+func (service *AuthService) CreateUser(ctx context.Context, username string, password string, role model.Role) (*model.User, error) {
+	if err := authorize(ctx, model.PermManageUsers); err != nil {
+		return nil, err
+	}
+	workspaceID, err := workspaceFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !role.IsValid() {
+		return nil, &pkg.ValidationError{Field: "role", Message: fmt.Sprintf("invalid role %q", role)}
+	}
+	user, err := model.NewUser(username, password)
+	if err != nil {
+		return nil, err
+	}
+	if err := service.checkUsernameFree(user.Username); err != nil {
+		return nil, err
+	}
+	user.WorkspaceID = workspaceID
+	user.Role = role
+	return service.users.Save(user)
+}
+
+func (service *AuthService) checkUsernameFree(username string) error {
+	_, err := service.users.FindByUsername(username)
+	if err == nil {
+		return fmt.Errorf("username already taken: %w", pkg.ErrConflict)
+	}
+	if errors.Is(err, pkg.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// Users lists the accounts of the caller's workspace.
+func (service *AuthService) Users(ctx context.Context) ([]*model.User, error) {
+	if err := authorize(ctx, model.PermManageUsers); err != nil {
+		return nil, err
+	}
+	workspaceID, err := workspaceFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return service.users.FindAll(workspaceID)
+}
+
+// Workspace returns the workspace the caller in ctx belongs to.
+func (service *AuthService) Workspace(ctx context.Context) (*model.Workspace, error) {
+	workspaceID, err := workspaceFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return service.workspaces.FindById(workspaceID)
+}
+
+// SetRole changes the role of a user. Admins cannot change their own role,
+// which keeps at least one admin around.
+func (service *AuthService) SetRole(ctx context.Context, id int64, role model.Role) (*model.User, error) {
+	if err := authorize(ctx, model.PermManageUsers); err != nil {
+		return nil, err
+	}
+	if !role.IsValid() {
+		return nil, &pkg.ValidationError{Field: "role", Message: fmt.Sprintf("invalid role %q", role)}
+	}
+	if caller, ok := UserFrom(ctx); ok && caller.ID == id {
+		return nil, fmt.Errorf("cannot change your own role: %w", pkg.ErrConflict)
+	}
+	workspaceID, err := workspaceFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user, err := service.users.FindById(id)
+	if err != nil {
+		return nil, err
+	}
+	if user.WorkspaceID != workspaceID {
+		return nil, fmt.Errorf("user %d: %w", id, pkg.ErrNotFound)
+	}
+	user.Role = role
+	return service.users.Save(user)
+}
+
+// Login checks the credentials and opens a session, returning the token
+// to store in the client's cookie.
+func (service *AuthService) Login(username string, password string) (*model.User, string, error) {
+	user, err := service.users.FindByUsername(username)
+	if err != nil {
+		if errors.Is(err, pkg.ErrNotFound) {
+			model.RejectPassword(password)
+			return nil, "", ErrInvalidCredentials
+		}
+		return nil, "", err
+	}
+	if !user.CheckPassword(password) {
+		return nil, "", ErrInvalidCredentials
+	}
+
+	token, err := service.openSession(user)
+	if err != nil {
+		return nil, "", err
+	}
+	return user, token, nil
+}
+
+func (service *AuthService) openSession(user *model.User) (string, error) {
+	session, token, err := model.NewSession(user.ID, service.clock.Now(), SessionTTL)
+	if err != nil {
+		return "", err
+	}
+	if _, err := service.sessions.Save(session); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (service *AuthService) Logout(token string) error {
+	return service.sessions.Delete(model.HashToken(token))
+}
+
+// Authenticate resolves a session token to its user. Expired sessions are
+// removed as they are found.
+func (service *AuthService) Authenticate(token string) (*model.User, error) {
+	session, err := service.sessions.FindById(model.HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if session.Expired(service.clock.Now()) {
+		if err := service.sessions.Delete(session.ID); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("session expired: %w", pkg.ErrNotFound)
+	}
+	return service.users.FindById(session.UserID)
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/pkg"
+)
+
+// authorize checks that the signed-in user in ctx holds permission. A
+// context without a user holds none.
+func authorize(ctx context.Context, permission model.Permission) error {
+	if user, ok := UserFrom(ctx); ok {
+		if user.Can(permission) {
+			return nil
+		}
+		return fmt.Errorf("%s lacks %s: %w", user.Username, permission, pkg.ErrForbidden)
+	}
+	return fmt.Errorf("anonymous caller lacks %s: %w", permission, pkg.ErrForbidden)
+}
+
+// authorizeOwner is authorize with an exception for the user who owns the
+// resource, provided their role lets them write at all.
+func authorizeOwner(ctx context.Context, permission model.Permission, ownerID *int64) error {
+	err := authorize(ctx, permission)
+	if err == nil {
+		return nil
+	}
+	user, ok := UserFrom(ctx)
+	if ok && ownerID != nil && *ownerID == user.ID && user.Can(model.PermCreateTask) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go-task/internal/model"
+	"go-task/pkg"
+	"testing"
+	"time"
+)
+
+func TestAuthorize(t *testing.T) {
+	// The matrix is spelled out rather than read from the roles so a
+	// change to what a role may do shows up here.
+	granted := map[model.Permission][]model.Role{
+		model.PermReadTask:       {model.RoleViewer, model.RoleMember, model.RoleAdmin},
+		model.PermCreateTask:     {model.RoleMember, model.RoleAdmin},
+		model.PermUpdateTask:     {model.RoleMember, model.RoleAdmin},
+		model.PermChangeStatus:   {model.RoleMember, model.RoleAdmin},
+		model.PermComment:        {model.RoleMember, model.RoleAdmin},
+		model.PermManageProjects: {model.RoleMember, model.RoleAdmin},
+		model.PermDeleteTask:     {model.RoleAdmin},
+		model.PermModerate:       {model.RoleAdmin},
+		model.PermManageUsers:    {model.RoleAdmin},
+		model.PermManageWebhooks: {model.RoleAdmin},
+	}
+	roles := []model.Role{model.RoleViewer, model.RoleMember, model.RoleAdmin}
+
+	for permission, allowed := range granted {
+		for _, role := range roles {
+			want := false
+			for _, r := range allowed {
+				want = want || r == role
+			}
+			t.Run(string(role)+"/"+string(permission), func(t *testing.T) {
+				err := authorize(userContext(1, 1, role), permission)
+				if want && err != nil {
+					t.Fatalf("got %v, want permission granted", err)
+				}
+				if !want && !errors.Is(err, pkg.ErrForbidden) {
+					t.Fatalf("got %v, want %v", err, pkg.ErrForbidden)
+				}
+			})
+		}
+	}
+}
+
+func TestAuthorizeWithoutUser(t *testing.T) {
+	contexts := map[string]context.Context{
+		"empty":         context.Background(),
+		"nil user":      WithUser(context.Background(), nil),
+		"unknown value": context.WithValue(context.Background(), struct{}{}, "system"),
+	}
+	for name, ctx := range contexts {
+		t.Run(name, func(t *testing.T) {
+			if err := authorize(ctx, model.PermReadTask); !errors.Is(err, pkg.ErrForbidden) {
+				t.Fatalf("got %v, want %v", err, pkg.ErrForbidden)
+			}
+		})
+	}
+}
+
+func TestAuthorizeOwner(t *testing.T) {
+	owner := int64(1)
+	other := int64(2)
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		ownerID *int64
+		want    bool
+	}{
+		{"admin on own task", userContext(1, 1, model.RoleAdmin), &owner, true},
+		{"admin on other's task", userContext(2, 1, model.RoleAdmin), &owner, true},
+		{"admin on task without owner", userContext(2, 1, model.RoleAdmin), nil, true},
+		{"member on own task", userContext(1, 1, model.RoleMember), &owner, true},
+		{"member on other's task", userContext(1, 1, model.RoleMember), &other, false},
+		{"member on task without owner", userContext(1, 1, model.RoleMember), nil, false},
+		{"viewer on own task", userContext(1, 1, model.RoleViewer), &owner, false},
+		{"viewer on other's task", userContext(1, 1, model.RoleViewer), &other, false},
+		{"anonymous", context.Background(), &owner, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := authorizeOwner(tt.ctx, model.PermDeleteTask, tt.ownerID)
+			if tt.want && err != nil {
+				t.Fatalf("got %v, want permission granted", err)
+			}
+			if !tt.want && !errors.Is(err, pkg.ErrForbidden) {
+				t.Fatalf("got %v, want %v", err, pkg.ErrForbidden)
+			}
+		})
+	}
+}
+
+func TestServiceEnforcesRoles(t *testing.T) {
+	owner := int64(1)
+	task := &model.Task{WorkspaceID: 1, Title: "write report", Status: pkg.TODO, CreatedBy: &owner}
+	service := newTestService(t, time.Now(), task)
+	viewer := userContext(3, 1, model.RoleViewer)
+	otherMember := userContext(2, 1, model.RoleMember)
+
+	if _, err := service.Create(viewer, &model.Task{Title: "new", Status: pkg.TODO}); !errors.Is(err, pkg.ErrForbidden) {
+		t.Errorf("viewer Create: got %v, want %v", err, pkg.ErrForbidden)
+	}
+	if _, err := service.UpdateStatus(viewer, task.ID, pkg.COMPLETED); !errors.Is(err, pkg.ErrForbidden) {
+		t.Errorf("viewer UpdateStatus: got %v, want %v", err, pkg.ErrForbidden)
+	}
+	if err := service.Delete(otherMember, task.ID); !errors.Is(err, pkg.ErrForbidden) {
+		t.Errorf("member Delete of other's task: got %v, want %v", err, pkg.ErrForbidden)
+	}
+	if err := service.Delete(userContext(owner, 1, model.RoleMember), task.ID); err != nil {
+		t.Errorf("member Delete of own task: %v", err)
+	}
+	if len(service.store.events) != 1 {
+		t.Errorf("got %d events, want only the owner's delete", len(service.store.events))
+	}
+}
+
+// Reads need PermReadTask, so a user whose role grants nothing sees
+// nothing of the workspace.
+func TestReadsNeedReadPermission(t *testing.T) {
+	task := &model.Task{WorkspaceID: 1, Title: "write report", Status: pkg.TODO}
+	service := newTestService(t, time.Now(), task)
+	projects := NewProjectService(fakeProjects{}, service.store)
+	ctx := userContext(1, 1, model.Role("guest"))
+
+	reads := map[string]func() error{
+		"FindAll":  func() error { _, err := service.FindAll(ctx); return err },
+		"FindPage": func() error { _, _, err := service.FindPage(ctx, nil, 10); return err },
+		"FindById": func() error { _, err := service.FindById(ctx, task.ID); return err },
+		"History":  func() error { _, err := service.History(ctx, task.ID); return err },
+		"Trash":    func() error { _, err := service.Trash(ctx); return err },
+		"Search":   func() error { _, err := service.Search(ctx, nil, "report"); return err },
+		"Projects": func() error { _, err := projects.FindAll(ctx, false); return err },
+		"Tasks":    func() error { _, err := projects.Tasks(ctx, 1); return err },
+	}
+	for name, read := range reads {
+		if err := read(); !errors.Is(err, pkg.ErrForbidden) {
+			t.Errorf("%s: got %v, want %v", name, err, pkg.ErrForbidden)
+		}
+	}
+	if tasks, err := service.FindAll(userContext(2, 1, model.RoleViewer)); err != nil || len(tasks) != 1 {
+		t.Errorf("viewer FindAll: got %v, %v; want the task", tasks, err)
+	}
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/pkg"
+)
+
+// maxBatchSize bounds the operations of one batch, which all run in a
+// single transaction.
+const maxBatchSize = 100
+
+type BatchOp string
+
+const (
+	BatchCreate BatchOp = "create"
+	BatchUpdate BatchOp = "update"
+	BatchStatus BatchOp = "status"
+	BatchDelete BatchOp = "delete"
+)
+
+// ErrBatchAborted is reported for the operations of an atomic batch that
+// were rolled back, or never run, because another operation failed.
+var ErrBatchAborted = errors.New("operation rolled back because another operation of the batch failed")
+
+// errRollback ends the transaction of an atomic batch with a failed
+// operation.
+var errRollback = errors.New("batch rolled back")
+
+// batchSavepoint is set before each operation of a batch that is not
+// atomic, so a failing operation leaves none of its writes behind.
+const batchSavepoint = "batch_operation"
+
+// BatchOperation is one change of a batch. Task is the task to create,
+// Patch the update and Status the new status; ID names the task for all
+// but create. Err is set when the operation could not be read, so it fails
+// in its place like any other invalid operation.
+type BatchOperation struct {
+	Op     BatchOp
+	ID     int64
+	Task   *model.Task
+	Patch  model.TaskPatch
+	Status pkg.TaskStatus
+	Err    error
+}
+
+// BatchResult is the outcome of one operation: the task it wrote, nil for
+// a delete, or the error it failed with.
+type BatchResult struct {
+	Task *model.Task
+	Err  error
+}
+
+// Batch runs ops in one transaction and reports whether it was committed.
+// An atomic batch is rolled back as a whole once an operation fails;
+// otherwise each failing operation is rolled back to a savepoint set
+// before it, which also undoes what it wrote before failing, and the rest
+// committed. Internal failures abort the batch in either mode and are
+// returned.
+func (service *Service) Batch(ctx context.Context, ops []BatchOperation, atomic bool) ([]BatchResult, bool, error) {
+	if len(ops) == 0 {
+		return nil, false, &pkg.ValidationError{Field: "operations", Message: "batch has no operations"}
+	}
+	if len(ops) > maxBatchSize {
+		return nil, false, &pkg.ValidationError{Field: "operations", Message: fmt.Sprintf("batch has more than %d operations", maxBatchSize)}
+	}
+
+	results := make([]BatchResult, len(ops))
+	err := service.transaction(func(tx *Service) error {
+		for i, op := range ops {
+			var rollback func() error
+			if !atomic {
+				var err error
+				if rollback, err = tx.savepoint(batchSavepoint); err != nil {
+					return err
+				}
+			}
+			task, err := tx.apply(ctx, op)
+			if errors.Is(err, pkg.ErrInternal) {
+				return err
+			}
+			results[i] = BatchResult{Task: task, Err: err}
+			switch {
+			case err == nil:
+			case atomic:
+				return errRollback
+			default:
+				if err := rollback(); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errRollback) {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i] = BatchResult{Err: ErrBatchAborted}
+			}
+		}
+		return results, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return results, true, nil
+}
+
+func (service *Service) apply(ctx context.Context, op BatchOperation) (*model.Task, error) {
+	if op.Err != nil {
+		return nil, op.Err
+	}
+	switch op.Op {
+	case BatchCreate:
+		return service.Create(ctx, op.Task)
+	case BatchUpdate:
+		return service.Patch(ctx, op.ID, op.Patch)
+	case BatchStatus:
+		return service.UpdateStatus(ctx, op.ID, op.Status)
+	case BatchDelete:
+		return nil, service.Delete(ctx, op.ID)
+	}
+	return nil, &pkg.ValidationError{Field: "op", Message: fmt.Sprintf("unknown operation %q", op.Op)}
+}
+
+// pendingNotifier holds the notifications of a transaction back until it
+// has been committed.
+type pendingNotifier struct {
+	events []*model.TaskEvent
+	tasks  []*model.Task
+}
+
+func (notifier *pendingNotifier) TaskChanged(event *model.TaskEvent, task *model.Task) {
+	notifier.events = append(notifier.events, event)
+	notifier.tasks = append(notifier.tasks, task)
+}
+
+// truncate forgets the notifications after the first n.
+func (notifier *pendingNotifier) truncate(n int) {
+	notifier.events = notifier.events[:n]
+	notifier.tasks = notifier.tasks[:n]
+}
+
+func (notifier *pendingNotifier) flush(to Notifier) {
+	for i, event := range notifier.events {
+		to.TaskChanged(event, notifier.tasks[i])
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"errors"
+	"go-task/internal/model"
+	"go-task/pkg"
+	"testing"
+	"time"
+)
+
+// batchFixture is a workspace with a plain task and a recurring task whose
+// project is gone. Completing the recurring task saves it and then fails
+// to create its next occurrence, so the operation fails after writing.
+func batchFixture(t *testing.T) (*testService, *model.Task, *model.Task) {
+	t.Helper()
+	rule, err := model.ParseRecurrence("FREQ=DAILY")
+	if err != nil {
+		t.Fatalf("ParseRecurrence: %v", err)
+	}
+	gone := int64(9)
+	due := date(2024, time.January, 1, 9)
+	plain := &model.Task{WorkspaceID: 1, Title: "write report", Status: pkg.TODO, Rank: 1}
+	recurring := &model.Task{WorkspaceID: 1, ProjectID: &gone, Title: "water plants", Status: pkg.TODO, Recurrence: rule, DueAt: &due, Rank: 2}
+	return newTestService(t, date(2024, time.January, 1, 8), plain, recurring), plain, recurring
+}
+
+func batchOps(plain *model.Task, recurring *model.Task) []BatchOperation {
+	return []BatchOperation{
+		{Op: BatchCreate, Task: &model.Task{Title: "book venue", Status: pkg.TODO}},
+		{Op: BatchStatus, ID: recurring.ID, Status: pkg.COMPLETED},
+		{Op: BatchStatus, ID: plain.ID, Status: pkg.PENDING},
+		{Op: BatchDelete, ID: 42},
+	}
+}
+
+// Without atomic, a failing operation is rolled back to its savepoint,
+// writes it made before failing included, and the others are committed.
+func TestBatchRollsBackFailedOperation(t *testing.T) {
+	service, plain, recurring := batchFixture(t)
+
+	results, committed, err := service.Batch(userContext(1, 1, model.RoleAdmin), batchOps(plain, recurring), false)
+	if err != nil || !committed {
+		t.Fatalf("Batch: committed %v, %v; want committed", committed, err)
+	}
+	wantErr := []error{nil, pkg.ErrNotFound, nil, pkg.ErrNotFound}
+	for i, result := range results {
+		if !errors.Is(result.Err, wantErr[i]) || (wantErr[i] == nil) != (result.Err == nil) {
+			t.Errorf("operation %d: got %v, want %v", i, result.Err, wantErr[i])
+		}
+	}
+
+	stored, _ := service.store.FindById(1, recurring.ID)
+	if stored.Status != pkg.TODO {
+		t.Errorf("recurring task is %s, want its completion rolled back", stored.Status)
+	}
+	if stored, _ := service.store.FindById(1, plain.ID); stored.Status != pkg.PENDING {
+		t.Errorf("plain task is %s, want %s", stored.Status, pkg.PENDING)
+	}
+	if tasks, _ := service.store.FindAll(1); len(tasks) != 3 {
+		t.Errorf("got %d tasks, want the two and the created one", len(tasks))
+	}
+	wantEvents := []model.EventOperation{model.EventCreate, model.EventStatusChange}
+	if len(service.store.events) != len(wantEvents) {
+		t.Fatalf("got %d events, want %d", len(service.store.events), len(wantEvents))
+	}
+	for i, event := range service.store.events {
+		if event.Operation != wantEvents[i] || event.TaskID == recurring.ID {
+			t.Errorf("event %d: %s of task %d", i, event.Operation, event.TaskID)
+		}
+	}
+	if len(service.notifier.events) != len(wantEvents) {
+		t.Errorf("notified %d events, want only the committed %d", len(service.notifier.events), len(wantEvents))
+	}
+}
+
+func TestAtomicBatchRollsBackAll(t *testing.T) {
+	service, plain, recurring := batchFixture(t)
+	before := service.store.snapshot()
+
+	results, committed, err := service.Batch(userContext(1, 1, model.RoleAdmin), batchOps(plain, recurring), true)
+	if err != nil || committed {
+		t.Fatalf("Batch: committed %v, %v; want rolled back", committed, err)
+	}
+	wantErr := []error{ErrBatchAborted, pkg.ErrNotFound, ErrBatchAborted, ErrBatchAborted}
+	for i, result := range results {
+		if !errors.Is(result.Err, wantErr[i]) || result.Task != nil {
+			t.Errorf("operation %d: got %v, %v; want %v", i, result.Task, result.Err, wantErr[i])
+		}
+	}
+
+	if len(service.store.tasks) != len(before.tasks) || len(service.store.events) != 0 {
+		t.Fatalf("got %d tasks and %d events, want the batch undone", len(service.store.tasks), len(service.store.events))
+	}
+	for id, task := range before.tasks {
+		if stored := service.store.tasks[id]; stored.Status != task.Status {
+			t.Errorf("task %d is %s, want %s", id, stored.Status, task.Status)
+		}
+	}
+	if len(service.notifier.events) != 0 {
+		t.Errorf("notified %d events of a rolled back batch", len(service.notifier.events))
+	}
+}
+
+func TestBatchCommitsEveryOperation(t *testing.T) {
+	for _, atomic := range []bool{true, false} {
+		service, plain, _ := batchFixture(t)
+		ops := []BatchOperation{
+			{Op: BatchCreate, Task: &model.Task{Title: "book venue", Status: pkg.TODO}},
+			{Op: BatchUpdate, ID: plain.ID, Patch: model.TaskPatch{Title: pkg.Optional[string]{Value: "write the report", Set: true}}},
+			{Op: BatchDelete, ID: plain.ID},
+		}
+		results, committed, err := service.Batch(userContext(1, 1, model.RoleAdmin), ops, atomic)
+		if err != nil || !committed {
+			t.Fatalf("atomic %v: committed %v, %v; want committed", atomic, committed, err)
+		}
+		for i, result := range results {
+			if result.Err != nil {
+				t.Errorf("atomic %v: operation %d: %v", atomic, i, result.Err)
+			}
+		}
+		if len(service.notifier.events) != len(ops) {
+			t.Errorf("atomic %v: notified %d events, want %d", atomic, len(service.notifier.events), len(ops))
+		}
+	}
+}
+
+func TestBatchInternalErrorAborts(t *testing.T) {
+	for _, atomic := range []bool{true, false} {
+		service, plain, _ := batchFixture(t)
+		service.store.failAppend = true
+		_, committed, err := service.Batch(userContext(1, 1, model.RoleAdmin), []BatchOperation{{Op: BatchDelete, ID: plain.ID}}, atomic)
+		if !errors.Is(err, pkg.ErrInternal) || committed {
+			t.Errorf("atomic %v: committed %v, %v; want %v", atomic, committed, err, pkg.ErrInternal)
+		}
+	}
+}
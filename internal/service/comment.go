@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/pkg"
+)
+
+type CommentStore interface {
+	Save(comment *model.Comment) (*model.Comment, error)
+	FindById(workspaceID int64, id int64) (*model.Comment, error)
+	FindByTaskId(workspaceID int64, taskID int64) ([]*model.Comment, error)
+	FindByTaskIds(workspaceID int64, taskIDs []int64) ([]*model.Comment, error)
+	Delete(workspaceID int64, id int64) error
+}
+
+// CommentService manages the discussion thread of a task. Every change
+// re-indexes the owning task so comment text stays searchable.
+type CommentService struct {
+	comments CommentStore
+	tasks    DataStore
+}
+
+func NewCommentService(comments CommentStore, tasks DataStore) *CommentService {
+	return &CommentService{
+		comments: comments,
+		tasks:    tasks,
+	}
+}
+
+// Create adds a comment authored by the caller in ctx.
+func (service *CommentService) Create(ctx context.Context, taskID int64, body string) (*model.Comment, error) {
+	if err := authorize(ctx, model.PermComment); err != nil {
+		return nil, err
+	}
+	task, err := service.findTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := model.NewComment(taskID, ActorFrom(ctx), body)
+	if err != nil {
+		return nil, err
+	}
+	comment.WorkspaceID = task.WorkspaceID
+
+	savedComment, err := service.comments.Save(comment)
+	if err != nil {
+		return nil, err
+	}
+	service.tasks.Reindex(task)
+	return savedComment, nil
+}
+
+func (service *CommentService) Update(ctx context.Context, taskID int64, id int64, body string) (*model.Comment, error) {
+	task, err := service.findTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := service.FindById(ctx, taskID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeAuthor(ctx, comment); err != nil {
+		return nil, err
+	}
+	if err := comment.UpdateBody(body); err != nil {
+		return nil, err
+	}
+
+	savedComment, err := service.comments.Save(comment)
+	if err != nil {
+		return nil, err
+	}
+	service.tasks.Reindex(task)
+	return savedComment, nil
+}
+
+func (service *CommentService) Delete(ctx context.Context, taskID int64, id int64) error {
+	task, err := service.findTask(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	comment, err := service.FindById(ctx, taskID, id)
+	if err != nil {
+		return err
+	}
+	if err := authorizeAuthor(ctx, comment); err != nil {
+		return err
+	}
+
+	if err := service.comments.Delete(task.WorkspaceID, id); err != nil {
+		return err
+	}
+	service.tasks.Reindex(task)
+	return nil
+}
+
+func (service *CommentService) FindByTaskId(ctx context.Context, taskID int64) ([]*model.Comment, error) {
+	task, err := service.findTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return service.comments.FindByTaskId(task.WorkspaceID, taskID)
+}
+
+// FindByTaskIds returns the comments on several tasks of the caller's
+// workspace keyed by task id, oldest first.
+func (service *CommentService) FindByTaskIds(ctx context.Context, taskIDs []int64) (map[int64][]*model.Comment, error) {
+	workspaceID, err := workspaceFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := service.comments.FindByTaskIds(workspaceID, taskIDs)
+	if err != nil {
+		return nil, err
+	}
+	byTask := make(map[int64][]*model.Comment, len(taskIDs))
+	for _, comment := range comments {
+		byTask[comment.TaskID] = append(byTask[comment.TaskID], comment)
+	}
+	return byTask, nil
+}
+
+// FindById returns a comment only when it belongs to taskID, so a comment
+// cannot be read or changed through another task's URL.
+func (service *CommentService) FindById(ctx context.Context, taskID int64, id int64) (*model.Comment, error) {
+	workspaceID, err := workspaceFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := service.comments.FindById(workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.TaskID != taskID {
+		return nil, fmt.Errorf("comment %d: %w", id, pkg.ErrNotFound)
+	}
+	return comment, nil
+}
+
+func (service *CommentService) findTask(ctx context.Context, taskID int64) (*model.Task, error) {
+	workspaceID, err := workspaceFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	task, err := service.tasks.FindById(workspaceID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if task.DeletedAt != nil {
+		return nil, fmt.Errorf("task %d: %w", taskID, pkg.ErrNotFound)
+	}
+	return task, nil
+}
+
+// authorizeAuthor lets commenters edit their own comments; changing
+// anyone else's requires moderation rights.
+func authorizeAuthor(ctx context.Context, comment *model.Comment) error {
+	if err := authorize(ctx, model.PermComment); err != nil {
+		return err
+	}
+	if ActorFrom(ctx) == comment.Author {
+		return nil
+	}
+	return authorize(ctx, model.PermModerate)
+}
@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/pkg"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fixedClock is a Clock that always reads the same time.
+type fixedClock struct {
+	now time.Time
+}
+
+func (clock fixedClock) Now() time.Time { return clock.now }
+
+// fakeStore keeps tasks and their events in memory. It stands in for both
+// the datastore and the event store, scoping lookups by workspace as the
+// MySQL stores do, and its transactions are rolled back by restoring a
+// copy of the data.
+type fakeStore struct {
+	tasks  map[int64]*model.Task
+	events []*model.TaskEvent
+	nextID int64
+
+	// failAppend makes Append fail, as a broken events table would.
+	failAppend bool
+	savepoints map[string]fakeSnapshot
+}
+
+func newFakeStore(tasks ...*model.Task) *fakeStore {
+	store := &fakeStore{tasks: map[int64]*model.Task{}}
+	for _, task := range tasks {
+		if _, err := store.Save(task); err != nil {
+			panic(err)
+		}
+	}
+	return store
+}
+
+func (store *fakeStore) Save(task *model.Task) (*model.Task, error) {
+	if task.ID == 0 {
+		store.nextID++
+		task.ID = store.nextID
+	}
+	stored := *task
+	store.tasks[task.ID] = &stored
+	return task, nil
+}
+
+func (store *fakeStore) FindById(workspaceID int64, id int64) (*model.Task, error) {
+	task, ok := store.tasks[id]
+	if !ok || task.WorkspaceID != workspaceID {
+		return nil, fmt.Errorf("task %d: %w", id, pkg.ErrNotFound)
+	}
+	found := *task
+	return &found, nil
+}
+
+func (store *fakeStore) FindAll(workspaceID int64) ([]*model.Task, error) {
+	return store.find(func(task *model.Task) bool {
+		return task.WorkspaceID == workspaceID && task.DeletedAt == nil
+	}), nil
+}
+
+func (store *fakeStore) FindPage(workspaceID int64, after *model.TaskCursor, limit int) ([]*model.Task, error) {
+	tasks, _ := store.FindAll(workspaceID)
+	if after != nil {
+		tasks = slices.DeleteFunc(tasks, func(task *model.Task) bool {
+			return task.Rank < after.Rank || (task.Rank == after.Rank && task.ID <= after.ID)
+		})
+	}
+	return tasks[:min(limit, len(tasks))], nil
+}
+
+func (store *fakeStore) FindByProject(workspaceID int64, projectID int64) ([]*model.Task, error) {
+	return store.find(func(task *model.Task) bool {
+		return task.WorkspaceID == workspaceID && task.DeletedAt == nil && sameID(task.ProjectID, &projectID)
+	}), nil
+}
+
+func (store *fakeStore) FindByIds(workspaceID int64, ids []int64) ([]*model.Task, error) {
+	return store.find(func(task *model.Task) bool {
+		return task.WorkspaceID == workspaceID && slices.Contains(ids, task.ID)
+	}), nil
+}
+
+func (store *fakeStore) FindByProjects(workspaceID int64, projectIDs []int64) ([]*model.Task, error) {
+	return store.find(func(task *model.Task) bool {
+		return task.WorkspaceID == workspaceID && task.DeletedAt == nil &&
+			task.ProjectID != nil && slices.Contains(projectIDs, *task.ProjectID)
+	}), nil
+}
+
+func (store *fakeStore) LastRank(workspaceID int64) (float64, error) {
+	var rank float64
+	for _, task := range store.tasks {
+		if task.WorkspaceID == workspaceID {
+			rank = max(rank, task.Rank)
+		}
+	}
+	return rank, nil
+}
+
+func (store *fakeStore) Rebalance(workspaceID int64) error {
+	tasks := store.find(func(task *model.Task) bool { return task.WorkspaceID == workspaceID })
+	for i, task := range tasks {
+		store.tasks[task.ID].Rank = float64(i + 1)
+	}
+	return nil
+}
+
+func (store *fakeStore) FindDeleted(workspaceID int64) ([]*model.Task, error) {
+	return store.find(func(task *model.Task) bool {
+		return task.WorkspaceID == workspaceID && task.DeletedAt != nil
+	}), nil
+}
+
+func (store *fakeStore) Purge(cutoff time.Time) (int64, error) {
+	var purged int64
+	for id, task := range store.tasks {
+		if task.DeletedAt != nil && task.DeletedAt.Before(cutoff) {
+			delete(store.tasks, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (store *fakeStore) Reindex(*model.Task) {}
+
+func (store *fakeStore) Append(event *model.TaskEvent) (*model.TaskEvent, error) {
+	if store.failAppend {
+		return nil, &pkg.TaskError{Message: "Failed to append event", Err: errors.New("events table is unavailable")}
+	}
+	event.ID = int64(len(store.events) + 1)
+	store.events = append(store.events, event)
+	return event, nil
+}
+
+func (store *fakeStore) FindByTaskId(workspaceID int64, taskID int64) ([]*model.TaskEvent, error) {
+	return store.FindByTaskIds(workspaceID, []int64{taskID})
+}
+
+func (store *fakeStore) FindByTaskIds(workspaceID int64, taskIDs []int64) ([]*model.TaskEvent, error) {
+	var events []*model.TaskEvent
+	for _, event := range store.events {
+		if event.WorkspaceID == workspaceID && slices.Contains(taskIDs, event.TaskID) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// find returns copies of the matching tasks in rank order.
+func (store *fakeStore) find(match func(task *model.Task) bool) []*model.Task {
+	var tasks []*model.Task
+	for _, task := range store.tasks {
+		if match(task) {
+			found := *task
+			tasks = append(tasks, &found)
+		}
+	}
+	slices.SortFunc(tasks, func(a, b *model.Task) int {
+		if a.Rank != b.Rank {
+			if a.Rank < b.Rank {
+				return -1
+			}
+			return 1
+		}
+		return int(a.ID - b.ID)
+	})
+	return tasks
+}
+
+// fakeSnapshot is the data of a fakeStore at one point in time.
+type fakeSnapshot struct {
+	tasks  map[int64]*model.Task
+	events []*model.TaskEvent
+	nextID int64
+}
+
+func (store *fakeStore) snapshot() fakeSnapshot {
+	return fakeSnapshot{tasks: store.tasks, events: store.events, nextID: store.nextID}.copy()
+}
+
+func (snapshot fakeSnapshot) copy() fakeSnapshot {
+	tasks := make(map[int64]*model.Task, len(snapshot.tasks))
+	for id, task := range snapshot.tasks {
+		copied := *task
+		tasks[id] = &copied
+	}
+	return fakeSnapshot{tasks: tasks, events: slices.Clone(snapshot.events), nextID: snapshot.nextID}
+}
+
+// restore copies the snapshot back, so it can be restored again.
+func (store *fakeStore) restore(snapshot fakeSnapshot) {
+	copied := snapshot.copy()
+	store.tasks, store.events, store.nextID = copied.tasks, copied.events, copied.nextID
+}
+
+// Transaction runs fn against the store itself and undoes its writes when
+// it fails.
+func (store *fakeStore) Transaction(fn func(datastore DataStore, events EventStore, savepoints Savepoints) error) error {
+	snapshot := store.snapshot()
+	store.savepoints = map[string]fakeSnapshot{}
+	if err := fn(store, store, store); err != nil {
+		store.restore(snapshot)
+		return err
+	}
+	return nil
+}
+
+func (store *fakeStore) Savepoint(name string) error {
+	store.savepoints[name] = store.snapshot()
+	return nil
+}
+
+func (store *fakeStore) RollbackTo(name string) error {
+	snapshot, ok := store.savepoints[name]
+	if !ok {
+		return &pkg.TaskError{Message: "Failed to roll back to savepoint", Err: fmt.Errorf("savepoint %q is not set", name)}
+	}
+	store.restore(snapshot)
+	return nil
+}
+
+// fakeProjects is a ProjectStore of a fixed set of projects.
+type fakeProjects map[int64]*model.Project
+
+func (projects fakeProjects) Save(project *model.Project) (*model.Project, error) {
+	projects[project.ID] = project
+	return project, nil
+}
+
+func (projects fakeProjects) FindById(workspaceID int64, id int64) (*model.Project, error) {
+	project, ok := projects[id]
+	if !ok || project.WorkspaceID != workspaceID {
+		return nil, fmt.Errorf("project %d: %w", id, pkg.ErrNotFound)
+	}
+	return project, nil
+}
+
+func (projects fakeProjects) FindByIds(workspaceID int64, ids []int64) ([]*model.Project, error) {
+	var found []*model.Project
+	for _, id := range ids {
+		if project, err := projects.FindById(workspaceID, id); err == nil {
+			found = append(found, project)
+		}
+	}
+	return found, nil
+}
+
+func (projects fakeProjects) FindAll(workspaceID int64, includeArchived bool) ([]*model.Project, error) {
+	var found []*model.Project
+	for _, project := range projects {
+		if project.WorkspaceID == workspaceID && (includeArchived || !project.Archived) {
+			found = append(found, project)
+		}
+	}
+	return found, nil
+}
+
+func (projects fakeProjects) CountByStatus(int64) (map[int64]model.StatusCounts, error) {
+	return map[int64]model.StatusCounts{}, nil
+}
+
+type fakeIndex struct{}
+
+func (fakeIndex) Search(context.Context, int64, *int64, string) ([]int64, error) {
+	return nil, nil
+}
+
+// fakeNotifier records the events it is told about.
+type fakeNotifier struct {
+	events []*model.TaskEvent
+}
+
+func (notifier *fakeNotifier) TaskChanged(event *model.TaskEvent, _ *model.Task) {
+	notifier.events = append(notifier.events, event)
+}
+
+// testService is a Service over in-memory stores.
+type testService struct {
+	*Service
+	store    *fakeStore
+	notifier *fakeNotifier
+}
+
+func newTestService(t *testing.T, now time.Time, tasks ...*model.Task) *testService {
+	t.Helper()
+	store := newFakeStore(tasks...)
+	notifier := &fakeNotifier{}
+	service := NewService(store, store, fakeProjects{}, fakeIndex{}, notifier, store).WithClock(fixedClock{now: now})
+	return &testService{Service: service, store: store, notifier: notifier}
+}
+
+// userContext returns a context signed in as a user of workspaceID with
+// role.
+func userContext(id int64, workspaceID int64, role model.Role) context.Context {
+	return WithUser(context.Background(), &model.User{
+		ID:          id,
+		WorkspaceID: workspaceID,
+		Username:    fmt.Sprintf("user%d", id),
+		Role:        role,
+	})
+}
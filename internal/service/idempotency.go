@@ -0,0 +1,107 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/pkg"
+	"log"
+	"time"
+)
+
+// IdempotencyTTL is how long the response to a request with an
+// Idempotency-Key is kept for replay.
+const IdempotencyTTL = 24 * time.Hour
+
+// ErrIdempotencyKeyReused rejects a request that reuses the key of an
+// earlier request with a different method, path or body.
+var ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+
+// errRequestInProgress answers a retry that arrives while the first
+// request with the same key is still being handled.
+var errRequestInProgress = fmt.Errorf("a request with this idempotency key is still in progress: %w", pkg.ErrConflict)
+
+type IdempotencyStore interface {
+	Reserve(record *model.IdempotencyRecord) error
+	Find(userID int64, key string) (*model.IdempotencyRecord, error)
+	Complete(record *model.IdempotencyRecord) error
+	Delete(userID int64, key string) error
+	Purge(cutoff time.Time) (int64, error)
+}
+
+type IdempotencyService struct {
+	records IdempotencyStore
+	clock   Clock
+}
+
+// NewIdempotencyService also starts purging expired records every
+// interval.
+func NewIdempotencyService(records IdempotencyStore, interval time.Duration) *IdempotencyService {
+	service := &IdempotencyService{
+		records: records,
+		clock:   systemClock{},
+	}
+	go service.run(interval)
+	return service
+}
+
+func (service *IdempotencyService) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := service.records.Purge(service.clock.Now()); err != nil {
+			log.Println("Failed to purge idempotency keys:", err)
+		}
+	}
+}
+
+// Begin claims key for a request of user. It returns nil when the request
+// is new and has to be handled, followed by Complete or Release, and the
+// stored record when the request is a retry whose response can be
+// replayed.
+func (service *IdempotencyService) Begin(userID int64, key string, requestHash string) (*model.IdempotencyRecord, error) {
+	now := service.clock.Now()
+	// The second round covers a record that expired or was released
+	// between the failed reservation and the lookup.
+	for range 2 {
+		err := service.records.Reserve(model.NewIdempotencyRecord(userID, key, requestHash, now, IdempotencyTTL))
+		if err == nil {
+			return nil, nil
+		}
+		if !errors.Is(err, pkg.ErrConflict) {
+			return nil, err
+		}
+		record, err := service.records.Find(userID, key)
+		if errors.Is(err, pkg.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !record.ExpiresAt.After(now) {
+			if err := service.records.Delete(userID, key); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if record.RequestHash != requestHash {
+			return nil, ErrIdempotencyKeyReused
+		}
+		if !record.Completed() {
+			return nil, errRequestInProgress
+		}
+		return record, nil
+	}
+	return nil, errRequestInProgress
+}
+
+// Complete stores the response of a request begun with Begin.
+func (service *IdempotencyService) Complete(record *model.IdempotencyRecord) error {
+	return service.records.Complete(record)
+}
+
+// Release forgets key so the request can be retried, for responses that
+// should not be replayed such as server errors.
+func (service *IdempotencyService) Release(userID int64, key string) error {
+	return service.records.Delete(userID, key)
+}
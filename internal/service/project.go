@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"go-task/internal/model"
+)
+
+type ProjectStore interface {
+	Save(project *model.Project) (*model.Project, error)
+	FindById(workspaceID int64, id int64) (*model.Project, error)
+	FindByIds(workspaceID int64, ids []int64) ([]*model.Project, error)
+	FindAll(workspaceID int64, includeArchived bool) ([]*model.Project, error)
+	CountByStatus(workspaceID int64) (map[int64]model.StatusCounts, error)
+}
+
+// ProjectService manages the projects of the caller's workspace.
+type ProjectService struct {
+	projects ProjectStore
+	tasks    DataStore
+}
+
+func NewProjectService(projects ProjectStore, tasks DataStore) *ProjectService {
+	return &ProjectService{
+		projects: projects,
+		tasks:    tasks,
+	}
+}
+
+func (service *ProjectService) Create(ctx context.Context, project *model.Project) (*model.Project, error) {
+	if err := authorize(ctx, model.PermManageProjects); err != nil {
+		return nil, err
+	}
+	workspaceID, err := workspaceFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	project.WorkspaceID = workspaceID
+	return service.projects.Save(project)
+}
+
+// Update renames a project and moves it in or out of the archive.
+func (service *ProjectService) Update(ctx context.Context, id int64, name string, description string, archived bool) (*model.Project, error) {
+	if err := authorize(ctx, model.PermManageProjects); err != nil {
+		return nil, err
+	}
+	project, err := service.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := project.Update(name, description); err != nil {
+		return nil, err
+	}
+	project.SetArchived(archived)
+	return service.projects.Save(project)
+}
+
+func (service *ProjectService) FindAll(ctx context.Context, includeArchived bool) ([]*model.Project, error) {
+	if err := authorize(ctx, model.PermReadTask); err != nil {
+		return nil, err
+	}
+	workspaceID, err := workspaceFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return service.projects.FindAll(workspaceID, includeArchived)
+}
+
+func (service *ProjectService) FindById(ctx context.Context, id int64) (*model.Project, error) {
+	if err := authorize(ctx, model.PermReadTask); err != nil {
+		return nil, err
+	}
+	workspaceID, err := workspaceFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return service.projects.FindById(workspaceID, id)
+}
+
+// Tasks lists the live tasks of a project.
+func (service *ProjectService) Tasks(ctx context.Context, id int64) ([]*model.Task, error) {
+	project, err := service.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return service.tasks.FindByProject(project.WorkspaceID, project.ID)
+}
+
+// FindByIds returns the projects of the caller's workspace with the given
+// ids keyed by id. Ids that are not found are absent.
+func (service *ProjectService) FindByIds(ctx context.Context, ids []int64) (map[int64]*model.Project, error) {
+	if err := authorize(ctx, model.PermReadTask); err != nil {
+		return nil, err
+	}
+	workspaceID, err := workspaceFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	projects, err := service.projects.FindByIds(workspaceID, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]*model.Project, len(projects))
+	for _, project := range projects {
+		byID[project.ID] = project
+	}
+	return byID, nil
+}
+
+// TasksOf lists the live tasks of several projects keyed by project id.
+func (service *ProjectService) TasksOf(ctx context.Context, ids []int64) (map[int64][]*model.Task, error) {
+	if err := authorize(ctx, model.PermReadTask); err != nil {
+		return nil, err
+	}
+	workspaceID, err := workspaceFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := service.tasks.FindByProjects(workspaceID, ids)
+	if err != nil {
+		return nil, err
+	}
+	byProject := make(map[int64][]*model.Task, len(ids))
+	for _, task := range tasks {
+		byProject[*task.ProjectID] = append(byProject[*task.ProjectID], task)
+	}
+	return byProject, nil
+}
+
+// Counts returns the number of live tasks per status for every project of
+// the workspace, keyed by project id.
+func (service *ProjectService) Counts(ctx context.Context) (map[int64]model.StatusCounts, error) {
+	if err := authorize(ctx, model.PermReadTask); err != nil {
+		return nil, err
+	}
+	workspaceID, err := workspaceFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return service.projects.CountByStatus(workspaceID)
+}
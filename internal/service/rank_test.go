@@ -0,0 +1,137 @@
+package service
+
+import (
+	"errors"
+	"go-task/internal/model"
+	"go-task/pkg"
+	"testing"
+	"time"
+)
+
+// rankedTasks returns tasks of workspace 1 with the given ranks, ids
+// numbered from 1 in the same order.
+func rankedTasks(ranks ...float64) []*model.Task {
+	tasks := make([]*model.Task, len(ranks))
+	for i, rank := range ranks {
+		tasks[i] = &model.Task{WorkspaceID: 1, Title: "task", Status: pkg.TODO, Rank: rank}
+	}
+	return tasks
+}
+
+func (service *testService) ranks(t *testing.T) map[int64]float64 {
+	t.Helper()
+	ranks := map[int64]float64{}
+	for id, task := range service.store.tasks {
+		ranks[id] = task.Rank
+	}
+	return ranks
+}
+
+func TestMove(t *testing.T) {
+	ptr := func(id int64) *int64 { return &id }
+	tests := []struct {
+		name   string
+		ranks  []float64
+		id     int64
+		before *int64
+		after  *int64
+		// want is the new rank of the moved task.
+		want          float64
+		wantRebalance bool
+	}{
+		{"between neighbours", []float64{1, 2, 3}, 3, ptr(1), ptr(2), 1.5, false},
+		{"to the top", []float64{1, 2, 3}, 3, nil, ptr(1), 0, false},
+		{"to the bottom", []float64{1, 2, 3}, 1, ptr(3), nil, 4, false},
+		{"tied neighbours", []float64{1, 1, 3}, 3, ptr(1), ptr(2), 1.5, true},
+		{"neighbours too close", []float64{1, 1 + 1e-10, 3}, 3, ptr(1), ptr(2), 1.5, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestService(t, time.Now(), rankedTasks(tt.ranks...)...)
+			before := service.ranks(t)
+
+			moved, err := service.Move(userContext(1, 1, model.RoleMember), tt.id, "", tt.before, tt.after)
+			if err != nil {
+				t.Fatalf("Move: %v", err)
+			}
+			if moved.Rank != tt.want {
+				t.Errorf("rank = %v, want %v", moved.Rank, tt.want)
+			}
+			rebalanced := false
+			for id, rank := range service.ranks(t) {
+				if id != tt.id && rank != before[id] {
+					rebalanced = true
+				}
+			}
+			if rebalanced != tt.wantRebalance {
+				t.Errorf("rebalanced = %v, want %v", rebalanced, tt.wantRebalance)
+			}
+		})
+	}
+}
+
+// Neighbours out of order mean the caller's list is stale; nothing is
+// renumbered to make room for a move that cannot be right.
+func TestMoveRefusesWithoutRebalancing(t *testing.T) {
+	ptr := func(id int64) *int64 { return &id }
+	tests := []struct {
+		name          string
+		ranks         []float64
+		id            int64
+		before, after *int64
+		wantErr       func(error) bool
+	}{
+		{"swapped neighbours", []float64{1, 2, 3}, 3, ptr(2), ptr(1), isConflict},
+		{"swapped tied neighbours", []float64{1, 1, 3}, 3, ptr(2), ptr(1), isConflict},
+		{"swapped close neighbours", []float64{1, 1 + 1e-10, 3}, 3, ptr(2), ptr(1), isConflict},
+		{"next to itself", []float64{1, 2, 3}, 3, ptr(3), ptr(1), isValidation},
+		{"same neighbour twice", []float64{1, 2, 3}, 3, ptr(1), ptr(1), isValidation},
+		{"unknown neighbour", []float64{1, 2, 3}, 3, ptr(1), ptr(42), isNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestService(t, time.Now(), rankedTasks(tt.ranks...)...)
+			before := service.ranks(t)
+
+			moved, err := service.Move(userContext(1, 1, model.RoleMember), tt.id, "", tt.before, tt.after)
+			if !tt.wantErr(err) {
+				t.Fatalf("got %v, %v; want the move refused", moved, err)
+			}
+			for id, rank := range service.ranks(t) {
+				if rank != before[id] {
+					t.Errorf("task %d renumbered from %v to %v", id, before[id], rank)
+				}
+			}
+			if len(service.store.events) != 0 {
+				t.Errorf("got %d events, want none", len(service.store.events))
+			}
+		})
+	}
+}
+
+// The rebalance is part of the move: when the move fails, the old ranks
+// are back.
+func TestRebalanceRollsBackWithMove(t *testing.T) {
+	service := newTestService(t, time.Now(), rankedTasks(1, 1+1e-10, 3)...)
+	before := service.ranks(t)
+	service.store.failAppend = true
+
+	first, second := int64(1), int64(2)
+	if _, err := service.Move(userContext(1, 1, model.RoleMember), 3, "", &first, &second); err == nil {
+		t.Fatal("Move succeeded without its event")
+	}
+	for id, rank := range service.ranks(t) {
+		if rank != before[id] {
+			t.Errorf("task %d left at rank %v, want %v", id, rank, before[id])
+		}
+	}
+}
+
+func isConflict(err error) bool { return errors.Is(err, pkg.ErrConflict) }
+
+func isNotFound(err error) bool { return errors.Is(err, pkg.ErrNotFound) }
+
+func isValidation(err error) bool {
+	var validationErr *pkg.ValidationError
+	return errors.As(err, &validationErr)
+}
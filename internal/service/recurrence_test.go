@@ -0,0 +1,197 @@
+package service
+
+import (
+	"go-task/internal/model"
+	"go-task/pkg"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int, hour int) time.Time {
+	return time.Date(year, month, day, hour, 0, 0, 0, time.UTC)
+}
+
+func TestCompletingRecurringTask(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+		due  time.Time
+		now  time.Time
+		// wantDue is the due date of the next occurrence; the zero time
+		// means the series has ended and no task is created.
+		wantDue  time.Time
+		wantRule string
+	}{
+		{
+			name:     "daily",
+			rule:     "FREQ=DAILY",
+			due:      date(2024, time.January, 1, 9),
+			now:      date(2024, time.January, 1, 8),
+			wantDue:  date(2024, time.January, 2, 9),
+			wantRule: "FREQ=DAILY",
+		},
+		{
+			name:     "every other day",
+			rule:     "FREQ=DAILY;INTERVAL=2",
+			due:      date(2024, time.January, 1, 9),
+			now:      date(2024, time.January, 1, 8),
+			wantDue:  date(2024, time.January, 3, 9),
+			wantRule: "FREQ=DAILY;INTERVAL=2",
+		},
+		{
+			name:     "every third week",
+			rule:     "FREQ=WEEKLY;INTERVAL=3",
+			due:      date(2024, time.January, 1, 9),
+			now:      date(2024, time.January, 1, 8),
+			wantDue:  date(2024, time.January, 22, 9),
+			wantRule: "FREQ=WEEKLY;INTERVAL=3",
+		},
+		{
+			name:     "by day within the week",
+			rule:     "FREQ=WEEKLY;BYDAY=MO,WE,FR",
+			due:      date(2024, time.January, 3, 9), // Wednesday
+			now:      date(2024, time.January, 3, 8),
+			wantDue:  date(2024, time.January, 5, 9),
+			wantRule: "FREQ=WEEKLY;BYDAY=MO,WE,FR",
+		},
+		{
+			name:     "by day into the next period",
+			rule:     "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR",
+			due:      date(2024, time.January, 5, 9), // Friday
+			now:      date(2024, time.January, 5, 8),
+			wantDue:  date(2024, time.January, 15, 9),
+			wantRule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR",
+		},
+		{
+			name:     "before until",
+			rule:     "FREQ=DAILY;UNTIL=20240102T090000Z",
+			due:      date(2024, time.January, 1, 9),
+			now:      date(2024, time.January, 1, 8),
+			wantDue:  date(2024, time.January, 2, 9),
+			wantRule: "FREQ=DAILY;UNTIL=20240102T090000Z",
+		},
+		{
+			name: "past until",
+			rule: "FREQ=DAILY;UNTIL=20240102T080000Z",
+			due:  date(2024, time.January, 1, 9),
+			now:  date(2024, time.January, 1, 8),
+		},
+		{
+			name:     "count left",
+			rule:     "FREQ=DAILY;COUNT=3",
+			due:      date(2024, time.January, 1, 9),
+			now:      date(2024, time.January, 1, 8),
+			wantDue:  date(2024, time.January, 2, 9),
+			wantRule: "FREQ=DAILY;COUNT=2",
+		},
+		{
+			name: "count used up",
+			rule: "FREQ=DAILY;COUNT=1",
+			due:  date(2024, time.January, 1, 9),
+			now:  date(2024, time.January, 1, 8),
+		},
+		{
+			name:     "monthly on the 31st skips February",
+			rule:     "FREQ=MONTHLY",
+			due:      date(2024, time.January, 31, 9),
+			now:      date(2024, time.January, 31, 8),
+			wantDue:  date(2024, time.March, 31, 9),
+			wantRule: "FREQ=MONTHLY",
+		},
+		{
+			name:     "monthly on the 31st skips April",
+			rule:     "FREQ=MONTHLY",
+			due:      date(2024, time.March, 31, 9),
+			now:      date(2024, time.March, 31, 8),
+			wantDue:  date(2024, time.May, 31, 9),
+			wantRule: "FREQ=MONTHLY",
+		},
+		{
+			name:     "completed after the due date",
+			rule:     "FREQ=DAILY",
+			due:      date(2024, time.January, 1, 9),
+			now:      date(2024, time.January, 5, 12),
+			wantDue:  date(2024, time.January, 6, 9),
+			wantRule: "FREQ=DAILY",
+		},
+		{
+			name:     "completed late uses up the skipped count",
+			rule:     "FREQ=DAILY;COUNT=5",
+			due:      date(2024, time.January, 1, 9),
+			now:      date(2024, time.January, 3, 12),
+			wantDue:  date(2024, time.January, 4, 9),
+			wantRule: "FREQ=DAILY;COUNT=2",
+		},
+		{
+			name: "completed after the series ended",
+			rule: "FREQ=DAILY;COUNT=3",
+			due:  date(2024, time.January, 1, 9),
+			now:  date(2024, time.January, 5, 12),
+		},
+		{
+			name:     "without a due date",
+			rule:     "FREQ=WEEKLY",
+			now:      date(2024, time.January, 1, 8),
+			wantDue:  date(2024, time.January, 8, 8),
+			wantRule: "FREQ=WEEKLY",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := model.ParseRecurrence(tt.rule)
+			if err != nil {
+				t.Fatalf("ParseRecurrence(%q): %v", tt.rule, err)
+			}
+			task := &model.Task{WorkspaceID: 1, Title: "water plants", Status: pkg.TODO, Recurrence: rule, Rank: 1}
+			if !tt.due.IsZero() {
+				task.DueAt = &tt.due
+			}
+			service := newTestService(t, tt.now, task)
+
+			if _, err := service.UpdateStatus(userContext(1, 1, model.RoleMember), task.ID, pkg.COMPLETED); err != nil {
+				t.Fatalf("UpdateStatus: %v", err)
+			}
+
+			tasks, _ := service.store.FindAll(1)
+			if tt.wantDue.IsZero() {
+				if len(tasks) != 1 {
+					t.Fatalf("got %d tasks, want no next occurrence", len(tasks))
+				}
+				return
+			}
+			if len(tasks) != 2 {
+				t.Fatalf("got %d tasks, want the completed one and its next occurrence", len(tasks))
+			}
+			next := tasks[1]
+			if next.Status != pkg.TODO {
+				t.Errorf("next status = %s, want %s", next.Status, pkg.TODO)
+			}
+			if next.DueAt == nil || !next.DueAt.Equal(tt.wantDue) {
+				t.Errorf("next due = %v, want %v", next.DueAt, tt.wantDue)
+			}
+			if got := next.Recurrence.String(); got != tt.wantRule {
+				t.Errorf("next rule = %q, want %q", got, tt.wantRule)
+			}
+			if !next.CreatedAt.Equal(tt.now) {
+				t.Errorf("next created at %v, want the clock's %v", next.CreatedAt, tt.now)
+			}
+		})
+	}
+}
+
+func TestReopeningDoesNotSpawn(t *testing.T) {
+	rule, _ := model.ParseRecurrence("FREQ=DAILY")
+	due := date(2024, time.January, 1, 9)
+	task := &model.Task{WorkspaceID: 1, Title: "water plants", Status: pkg.COMPLETED, Recurrence: rule, DueAt: &due}
+	service := newTestService(t, date(2024, time.January, 1, 8), task)
+	ctx := userContext(1, 1, model.RoleMember)
+
+	for _, status := range []pkg.TaskStatus{pkg.COMPLETED, pkg.TODO} {
+		if _, err := service.UpdateStatus(ctx, task.ID, status); err != nil {
+			t.Fatalf("UpdateStatus(%s): %v", status, err)
+		}
+	}
+	if tasks, _ := service.store.FindAll(1); len(tasks) != 1 {
+		t.Fatalf("got %d tasks, want no occurrence for a task that was already completed", len(tasks))
+	}
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"log"
+	"time"
+)
+
+// Retention permanently purges tasks that have stayed in the trash for
+// longer than the retention period.
+type Retention struct {
+	datastore DataStore
+	period    time.Duration
+	clock     Clock
+}
+
+func NewRetention(datastore DataStore, period time.Duration, interval time.Duration) *Retention {
+	retention := &Retention{
+		datastore: datastore,
+		period:    period,
+		clock:     systemClock{},
+	}
+	go retention.run(interval)
+	return retention
+}
+
+func (retention *Retention) run(interval time.Duration) {
+	log.Printf("starting retention job, purging tasks deleted more than %s ago", retention.period)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := retention.Purge(); err != nil {
+			log.Println("Failed to purge deleted tasks:", err)
+		}
+	}
+}
+
+// Purge removes every task deleted before the retention cutoff.
+func (retention *Retention) Purge() (int64, error) {
+	cutoff := retention.clock.Now().Add(-retention.period)
+	purged, err := retention.datastore.Purge(cutoff)
+	if err != nil {
+		return 0, err
+	}
+	if purged > 0 {
+		log.Printf("purged %d tasks deleted before %s", purged, cutoff.Format(time.RFC3339))
+	}
+	return purged, nil
+}
@@ -1,74 +1,497 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"go-task/internal/model"
+	"go-task/pkg"
+	"log"
 	"time"
 )
 
+// DataStore scopes every lookup to a workspace; tasks carry their own
+// workspace when saved.
 type DataStore interface {
 	Save(task *model.Task) (*model.Task, error)
-	FindById(id int64) (*model.Task, error)
-	FindAll() ([]*model.Task, error)
+	FindById(workspaceID int64, id int64) (*model.Task, error)
+	FindAll(workspaceID int64) ([]*model.Task, error)
+	FindPage(workspaceID int64, after *model.TaskCursor, limit int) ([]*model.Task, error)
+	FindByProject(workspaceID int64, projectID int64) ([]*model.Task, error)
+	FindByIds(workspaceID int64, ids []int64) ([]*model.Task, error)
+	FindByProjects(workspaceID int64, projectIDs []int64) ([]*model.Task, error)
+	LastRank(workspaceID int64) (float64, error)
+	Rebalance(workspaceID int64) error
+	FindDeleted(workspaceID int64) ([]*model.Task, error)
+	Purge(cutoff time.Time) (int64, error)
+	Reindex(task *model.Task)
 }
 
+type EventStore interface {
+	Append(event *model.TaskEvent) (*model.TaskEvent, error)
+	FindByTaskId(workspaceID int64, taskID int64) ([]*model.TaskEvent, error)
+	FindByTaskIds(workspaceID int64, taskIDs []int64) ([]*model.TaskEvent, error)
+}
+
+// Transactor runs fn with a datastore and event store whose writes share
+// one transaction: they are all committed when fn returns nil and rolled
+// back otherwise. savepoints rolls back part of the transaction.
+type Transactor interface {
+	Transaction(fn func(datastore DataStore, events EventStore, savepoints Savepoints) error) error
+}
+
+// Savepoints marks points of a transaction that it can be rolled back to,
+// undoing the writes made after the point and keeping the earlier ones.
+type Savepoints interface {
+	Savepoint(name string) error
+	RollbackTo(name string) error
+}
+
+// SearchIndex answers full-text queries with the ids of matching tasks,
+// best match first.
+type SearchIndex interface {
+	Search(ctx context.Context, workspaceID int64, projectID *int64, query string) ([]int64, error)
+}
+
+// Notifier hears about every task event once it is part of the history.
+type Notifier interface {
+	TaskChanged(event *model.TaskEvent, task *model.Task)
+}
+
+// errProjectArchived rejects new tasks in an archived project.
+var errProjectArchived = fmt.Errorf("project is archived: %w", pkg.ErrConflict)
+
+// Clock is the source of the current time for the service, replaced by a
+// fixed clock when the recurrence generator is exercised.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
 type Service struct {
 	datastore DataStore
+	events    EventStore
+	projects  ProjectStore
+	index     SearchIndex
+	notifier  Notifier
+	tx        Transactor
+	clock     Clock
+	// inTx is set on the copy of the service that runs inside a
+	// transaction, whose writes then join it; savepoints and pending, the
+	// notifications held back until commit, belong to that transaction.
+	inTx       bool
+	savepoints Savepoints
+	pending    *pendingNotifier
 }
 
-func NewService(datastore DataStore) *Service {
+func NewService(datastore DataStore, events EventStore, projects ProjectStore, index SearchIndex, notifier Notifier, tx Transactor) *Service {
 	return &Service{
 		datastore: datastore,
+		events:    events,
+		projects:  projects,
+		index:     index,
+		notifier:  notifier,
+		tx:        tx,
+		clock:     systemClock{},
 	}
 }
 
-func (service *Service) Create(task *model.Task) (*model.Task, error) {
+func (service *Service) WithClock(clock Clock) *Service {
+	service.clock = clock
+	return service
+}
 
-	savedTask, err := service.datastore.Save(task)
+func (service *Service) Create(ctx context.Context, task *model.Task) (*model.Task, error) {
+	if err := authorize(ctx, model.PermCreateTask); err != nil {
+		return nil, err
+	}
+	workspaceID, err := workspaceFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	task.WorkspaceID = workspaceID
+	if err := service.checkProject(task); err != nil {
+		return nil, err
+	}
+	task.CreatedBy = userID(ctx)
+	task.UpdatedBy = task.CreatedBy
+
+	var savedTask *model.Task
+	err = service.transaction(func(tx *Service) error {
+		lastRank, err := tx.datastore.LastRank(workspaceID)
+		if err != nil {
+			return err
+		}
+		task.Rank = lastRank + 1
+		savedTask, err = tx.save(ctx, model.EventCreate, nil, task)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 	return savedTask, nil
 }
 
-func (service *Service) Update(task model.Task, id int64) (*model.Task, error) {
-	oldTask, err := service.datastore.FindById(id)
+func (service *Service) Update(ctx context.Context, task model.Task, id int64) (*model.Task, error) {
+	if err := authorize(ctx, model.PermUpdateTask); err != nil {
+		return nil, err
+	}
+	oldTask, err := service.FindById(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
+	before := *oldTask
 	oldTask, err = oldTask.UpdateFrom(task)
 	if err != nil {
 		return nil, err
 	}
+	if !sameID(before.ProjectID, oldTask.ProjectID) {
+		if err := service.checkProject(oldTask); err != nil {
+			return nil, err
+		}
+	}
+	oldTask.UpdatedBy = userID(ctx)
 
-	savedTask, err := service.datastore.Save(oldTask)
+	var savedTask *model.Task
+	err = service.transaction(func(tx *Service) error {
+		savedTask, err = tx.save(ctx, model.EventUpdate, &before, oldTask)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
+	return savedTask, nil
+}
+
+// Patch changes only the fields set in patch. Changing the status needs
+// the permission to do so, and completing a recurring task creates its
+// next occurrence just as UpdateStatus does.
+func (service *Service) Patch(ctx context.Context, id int64, patch model.TaskPatch) (*model.Task, error) {
+	task, err := service.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	operation := model.EventUpdate
+	if patch.Status.Set && patch.Status.Value != task.Status {
+		if err := authorize(ctx, model.PermChangeStatus); err != nil {
+			return nil, err
+		}
+		operation = model.EventStatusChange
+	}
+	if patch.ChangesFields() || operation == model.EventUpdate {
+		if err := authorize(ctx, model.PermUpdateTask); err != nil {
+			return nil, err
+		}
+	}
+
+	before := *task
+	if err := task.Patch(patch); err != nil {
+		return nil, err
+	}
+	if !sameID(before.ProjectID, task.ProjectID) {
+		if err := service.checkProject(task); err != nil {
+			return nil, err
+		}
+	}
+	task.UpdatedBy = userID(ctx)
 
+	var savedTask *model.Task
+	err = service.transaction(func(tx *Service) error {
+		savedTask, err = tx.save(ctx, operation, &before, task)
+		if err != nil {
+			return err
+		}
+		if before.Status != pkg.COMPLETED && savedTask.Status == pkg.COMPLETED {
+			return tx.spawnNextOccurrence(ctx, savedTask)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 	return savedTask, nil
 }
 
-func (service *Service) Delete(id int64) error {
-	task, err := service.datastore.FindById(id)
+// UpdateStatus moves a task to status. When a recurring task becomes
+// COMPLETED the next occurrence of the series is created as a new task.
+func (service *Service) UpdateStatus(ctx context.Context, id int64, status pkg.TaskStatus) (*model.Task, error) {
+	if err := authorize(ctx, model.PermChangeStatus); err != nil {
+		return nil, err
+	}
+	task, err := service.FindById(ctx, id)
 	if err != nil {
+		return nil, err
+	}
+
+	before := *task
+	if err := task.UpdateStatus(status); err != nil {
+		return nil, err
+	}
+	task.UpdatedBy = userID(ctx)
+
+	var savedTask *model.Task
+	err = service.transaction(func(tx *Service) error {
+		savedTask, err = tx.save(ctx, model.EventStatusChange, &before, task)
+		if err != nil {
+			return err
+		}
+		if before.Status != pkg.COMPLETED && status == pkg.COMPLETED {
+			return tx.spawnNextOccurrence(ctx, savedTask)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return savedTask, nil
+}
+
+// Move places a task between two neighbours, changing its status as well
+// when status differs from the current one; an empty status keeps it.
+// before and after are the ids of the tasks that end up directly above and
+// below it; either is nil at the edge of the list. Neighbours that are no
+// longer in that order are a conflict, since the caller's view of the list
+// is out of date. Only the moved task gets a new rank, unless its
+// neighbours are too close to fit it in between, in which case the
+// workspace is rebalanced first in the same transaction.
+func (service *Service) Move(ctx context.Context, id int64, status pkg.TaskStatus, before *int64, after *int64) (*model.Task, error) {
+	if sameID(before, &id) || sameID(after, &id) {
+		return nil, &pkg.ValidationError{Field: "before", Message: "a task cannot be placed next to itself"}
+	}
+	if before != nil && sameID(before, after) {
+		return nil, &pkg.ValidationError{Field: "after", Message: "before and after must be different tasks"}
+	}
+	task, err := service.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if status == "" {
+		status = task.Status
+	}
+	operation, permission := model.EventUpdate, model.PermUpdateTask
+	if status != task.Status {
+		operation, permission = model.EventStatusChange, model.PermChangeStatus
+	}
+	if err := authorize(ctx, permission); err != nil {
+		return nil, err
+	}
+
+	var savedTask *model.Task
+	err = service.transaction(func(tx *Service) error {
+		rank, err := tx.rankBetween(ctx, before, after, task)
+		if err != nil {
+			return err
+		}
+		previous := *task
+		if err := task.UpdateStatus(status); err != nil {
+			return err
+		}
+		task.Rank = rank
+		task.UpdatedBy = userID(ctx)
+
+		savedTask, err = tx.save(ctx, operation, &previous, task)
+		if err != nil {
+			return err
+		}
+		if previous.Status != pkg.COMPLETED && status == pkg.COMPLETED {
+			return tx.spawnNextOccurrence(ctx, savedTask)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return savedTask, nil
+}
+
+// rankBetween finds the rank for task between its new neighbours. The
+// workspace is rebalanced only when the neighbours are in order but too
+// close to split; neighbours out of order are refused as they are.
+func (service *Service) rankBetween(ctx context.Context, before *int64, after *int64, task *model.Task) (float64, error) {
+	for attempt := 0; ; attempt++ {
+		beforeTask, err := service.neighbour(ctx, before)
+		if err != nil {
+			return 0, err
+		}
+		afterTask, err := service.neighbour(ctx, after)
+		if err != nil {
+			return 0, err
+		}
+		var beforeRank, afterRank *float64
+		if beforeTask != nil {
+			beforeRank = &beforeTask.Rank
+		}
+		if afterTask != nil {
+			afterRank = &afterTask.Rank
+		}
+		if beforeTask != nil && afterTask != nil && !rankedAbove(beforeTask, afterTask) {
+			return 0, fmt.Errorf("task %d is no longer above task %d: %w", beforeTask.ID, afterTask.ID, pkg.ErrConflict)
+		}
+		if rank, ok := model.RankBetween(beforeRank, afterRank, task.Rank); ok {
+			return rank, nil
+		}
+		if attempt > 0 {
+			return 0, fmt.Errorf("task %d cannot be placed between %d and %d: %w", task.ID, *before, *after, pkg.ErrConflict)
+		}
+		log.Printf("rebalancing ranks of workspace %d", task.WorkspaceID)
+		if err := service.datastore.Rebalance(task.WorkspaceID); err != nil {
+			return 0, err
+		}
+		// The task itself has been renumbered too.
+		refreshed, err := service.FindById(ctx, task.ID)
+		if err != nil {
+			return 0, err
+		}
+		task.Rank = refreshed.Rank
+	}
+}
+
+// rankedAbove reports whether a comes before b in list order, which breaks
+// rank ties by id.
+func rankedAbove(a *model.Task, b *model.Task) bool {
+	if a.Rank != b.Rank {
+		return a.Rank < b.Rank
+	}
+	return a.ID < b.ID
+}
+
+func (service *Service) neighbour(ctx context.Context, id *int64) (*model.Task, error) {
+	if id == nil {
+		return nil, nil
+	}
+	return service.FindById(ctx, *id)
+}
+
+func (service *Service) spawnNextOccurrence(ctx context.Context, task *model.Task) error {
+	next, ok := task.NextOccurrence(service.clock.Now())
+	if !ok {
+		return nil
+	}
+	created, err := service.Create(ctx, next)
+	if errors.Is(err, errProjectArchived) {
+		log.Printf("task %d completed, series ends with its archived project", task.ID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("task %d completed, next occurrence %d due %s", task.ID, created.ID, created.DueAt.Format(time.RFC3339))
+	return nil
+}
+
+// Delete moves a task to the trash. Admins may delete any task, members
+// only the ones they created.
+func (service *Service) Delete(ctx context.Context, id int64) error {
+	task, err := service.FindById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := authorizeOwner(ctx, model.PermDeleteTask, task.CreatedBy); err != nil {
 		return err
 	}
-	now := time.Now()
+	before := *task
+	now := service.clock.Now()
 	task.DeletedAt = &now
-	_, err = service.datastore.Save(task)
+	task.UpdatedBy = userID(ctx)
+	return service.transaction(func(tx *Service) error {
+		_, err := tx.save(ctx, model.EventDelete, &before, task)
+		return err
+	})
+}
+
+// Restore takes a task out of the trash. Saving it queues the task for
+// indexing again, since deleted tasks are dropped from the search index.
+func (service *Service) Restore(ctx context.Context, id int64) (*model.Task, error) {
+	task, err := service.FindById(ctx, id)
 	if err != nil {
+		return nil, err
+	}
+	if err := authorizeOwner(ctx, model.PermDeleteTask, task.CreatedBy); err != nil {
+		return nil, err
+	}
+	if task.DeletedAt == nil {
+		return nil, fmt.Errorf("task %d is not deleted: %w", id, pkg.ErrConflict)
+	}
+
+	before := *task
+	task.DeletedAt = nil
+	task.UpdatedAt = service.clock.Now()
+	task.UpdatedBy = userID(ctx)
+
+	var savedTask *model.Task
+	err = service.transaction(func(tx *Service) error {
+		savedTask, err = tx.save(ctx, model.EventRestore, &before, task)
 		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return savedTask, nil
+}
+
+// Trash lists the soft-deleted tasks of the caller's workspace that can
+// still be restored.
+func (service *Service) Trash(ctx context.Context) ([]*model.Task, error) {
+	if err := authorize(ctx, model.PermReadTask); err != nil {
+		return nil, err
+	}
+	workspaceID, err := workspaceFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return service.datastore.FindDeleted(workspaceID)
+}
+
+func (service *Service) FindAll(ctx context.Context) ([]*model.Task, error) {
+	if err := authorize(ctx, model.PermReadTask); err != nil {
+		return nil, err
+	}
+	workspaceID, err := workspaceFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return service.datastore.FindAll(workspaceID)
 }
 
-func (service *Service) FindAll() ([]*model.Task, error) {
-	return service.datastore.FindAll()
+// FindPage returns up to limit live tasks of the caller's workspace in rank
+// order, starting after the cursor when it is set, with the cursor of the
+// next page. The next cursor is nil on the last page.
+func (service *Service) FindPage(ctx context.Context, after *model.TaskCursor, limit int) ([]*model.Task, *model.TaskCursor, error) {
+	if err := authorize(ctx, model.PermReadTask); err != nil {
+		return nil, nil, err
+	}
+	workspaceID, err := workspaceFrom(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	// One more than asked tells whether another page follows.
+	tasks, err := service.datastore.FindPage(workspaceID, after, limit+1)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tasks) <= limit {
+		return tasks, nil, nil
+	}
+	tasks = tasks[:limit]
+	last := tasks[limit-1]
+	return tasks, &model.TaskCursor{Rank: last.Rank, ID: last.ID}, nil
 }
 
-func (service *Service) FindById(id int64) (*model.Task, error) {
-	task, err := service.datastore.FindById(id)
+// FindById returns a task of the caller's workspace. Tasks of other
+// workspaces are not found.
+func (service *Service) FindById(ctx context.Context, id int64) (*model.Task, error) {
+	if err := authorize(ctx, model.PermReadTask); err != nil {
+		return nil, err
+	}
+	workspaceID, err := workspaceFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	task, err := service.datastore.FindById(workspaceID, id)
 
 	if err != nil {
 		return nil, err
@@ -76,3 +499,173 @@ func (service *Service) FindById(id int64) (*model.Task, error) {
 
 	return task, nil
 }
+
+// FindByIds returns the tasks of the caller's workspace with the given ids,
+// deleted ones included, keyed by id. Ids that are not found are absent.
+func (service *Service) FindByIds(ctx context.Context, ids []int64) (map[int64]*model.Task, error) {
+	if err := authorize(ctx, model.PermReadTask); err != nil {
+		return nil, err
+	}
+	workspaceID, err := workspaceFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := service.datastore.FindByIds(workspaceID, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]*model.Task, len(tasks))
+	for _, task := range tasks {
+		byID[task.ID] = task
+	}
+	return byID, nil
+}
+
+// Search runs a full-text query over the tasks of the caller's workspace,
+// narrowed to one project when projectID is set. Hits are loaded from the
+// database so results reflect the current task, and tasks the index still
+// knows about but that were deleted meanwhile are skipped.
+func (service *Service) Search(ctx context.Context, projectID *int64, query string) ([]*model.Task, error) {
+	if err := authorize(ctx, model.PermReadTask); err != nil {
+		return nil, err
+	}
+	workspaceID, err := workspaceFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := service.index.Search(ctx, workspaceID, projectID, query)
+	if err != nil {
+		return nil, &pkg.TaskError{Message: fmt.Sprintf("Failed to search tasks: %s", err.Error()), Err: err}
+	}
+
+	tasks := make([]*model.Task, 0, len(ids))
+	for _, id := range ids {
+		task, err := service.datastore.FindById(workspaceID, id)
+		if errors.Is(err, pkg.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if task.DeletedAt == nil {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+// History returns the recorded events of a task, oldest first.
+func (service *Service) History(ctx context.Context, id int64) ([]*model.TaskEvent, error) {
+	task, err := service.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return service.events.FindByTaskId(task.WorkspaceID, id)
+}
+
+// Histories returns the events of several tasks of the caller's workspace
+// keyed by task id, oldest first.
+func (service *Service) Histories(ctx context.Context, ids []int64) (map[int64][]*model.TaskEvent, error) {
+	if err := authorize(ctx, model.PermReadTask); err != nil {
+		return nil, err
+	}
+	workspaceID, err := workspaceFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	events, err := service.events.FindByTaskIds(workspaceID, ids)
+	if err != nil {
+		return nil, err
+	}
+	byTask := make(map[int64][]*model.TaskEvent, len(ids))
+	for _, event := range events {
+		byTask[event.TaskID] = append(byTask[event.TaskID], event)
+	}
+	return byTask, nil
+}
+
+// checkProject makes sure a task is only filed under a live project of its
+// own workspace.
+func (service *Service) checkProject(task *model.Task) error {
+	if task.ProjectID == nil {
+		return nil
+	}
+	project, err := service.projects.FindById(task.WorkspaceID, *task.ProjectID)
+	if err != nil {
+		return err
+	}
+	if project.Archived {
+		return fmt.Errorf("project %d: %w", project.ID, errProjectArchived)
+	}
+	return nil
+}
+
+func sameID(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// transaction runs fn with a copy of the service whose stores share one
+// transaction, so a task and the event recording its change are written
+// together or not at all. Notifications are held back until the
+// transaction has been committed. Called within a transaction, such as a
+// batch, fn joins it instead.
+func (service *Service) transaction(fn func(tx *Service) error) error {
+	if service.inTx {
+		return fn(service)
+	}
+	notifications := &pendingNotifier{}
+	err := service.tx.Transaction(func(datastore DataStore, events EventStore, savepoints Savepoints) error {
+		tx := *service
+		tx.datastore, tx.events, tx.notifier, tx.inTx = datastore, events, notifications, true
+		tx.savepoints, tx.pending = savepoints, notifications
+		return fn(&tx)
+	})
+	if err != nil {
+		return err
+	}
+	notifications.flush(service.notifier)
+	return nil
+}
+
+// savepoint sets the savepoint name in the transaction the service runs
+// in. The returned rollback undoes the writes made since, and drops the
+// notifications they would have sent on commit.
+func (service *Service) savepoint(name string) (rollback func() error, err error) {
+	if err := service.savepoints.Savepoint(name); err != nil {
+		return nil, err
+	}
+	mark := len(service.pending.events)
+	return func() error {
+		if err := service.savepoints.RollbackTo(name); err != nil {
+			return err
+		}
+		service.pending.truncate(mark)
+		return nil
+	}, nil
+}
+
+// save writes task and records operation on it in the history. It is
+// called within a transaction.
+func (service *Service) save(ctx context.Context, operation model.EventOperation, before *model.Task, task *model.Task) (*model.Task, error) {
+	savedTask, err := service.datastore.Save(task)
+	if err != nil {
+		return nil, err
+	}
+	if err := service.record(ctx, operation, before, savedTask); err != nil {
+		return nil, err
+	}
+	return savedTask, nil
+}
+
+func (service *Service) record(ctx context.Context, operation model.EventOperation, before *model.Task, after *model.Task) error {
+	event := model.NewTaskEvent(ActorFrom(ctx), operation, before, after)
+	event.CreatedAt = service.clock.Now()
+	if _, err := service.events.Append(event); err != nil {
+		return err
+	}
+	service.notifier.TaskChanged(event, after)
+	return nil
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/pkg"
+)
+
+type IdentityStore interface {
+	Save(identity *model.Identity) (*model.Identity, error)
+	FindBySubject(issuer string, subject string) (*model.Identity, error)
+}
+
+// ExternalIdentity is what an identity provider asserted about the person
+// signing in.
+type ExternalIdentity struct {
+	Issuer   string
+	Subject  string
+	Username string
+}
+
+// SSOService maps identities asserted by an OpenID Connect provider to
+// local users. The first sign-in of an unknown subject provisions a member
+// in the configured workspace; existing local accounts are never linked by
+// username, since the provider does not vouch for them.
+type SSOService struct {
+	auth        *AuthService
+	identities  IdentityStore
+	workspaceID int64
+}
+
+func NewSSOService(auth *AuthService, identities IdentityStore, workspaceID int64) *SSOService {
+	return &SSOService{
+		auth:        auth,
+		identities:  identities,
+		workspaceID: workspaceID,
+	}
+}
+
+// Login opens a session for identity, returning the token to store in the
+// client's cookie.
+func (service *SSOService) Login(identity ExternalIdentity) (*model.User, string, error) {
+	user, err := service.findUser(identity)
+	if errors.Is(err, pkg.ErrNotFound) {
+		user, err = service.provision(identity)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := service.auth.openSession(user)
+	if err != nil {
+		return nil, "", err
+	}
+	return user, token, nil
+}
+
+func (service *SSOService) findUser(identity ExternalIdentity) (*model.User, error) {
+	linked, err := service.identities.FindBySubject(identity.Issuer, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	return service.auth.users.FindById(linked.UserID)
+}
+
+func (service *SSOService) provision(identity ExternalIdentity) (*model.User, error) {
+	if service.workspaceID == 0 {
+		return nil, fmt.Errorf("no account for %s and no workspace to provision into: %w", identity.Subject, pkg.ErrForbidden)
+	}
+	user, err := model.NewExternalUser(identity.Username)
+	if err != nil {
+		return nil, err
+	}
+	if err := service.auth.checkUsernameFree(user.Username); err != nil {
+		return nil, err
+	}
+	user.WorkspaceID = service.workspaceID
+	if _, err := service.auth.users.Save(user); err != nil {
+		return nil, err
+	}
+
+	link, err := model.NewIdentity(user.ID, identity.Issuer, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := service.identities.Save(link); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/internal/sso"
+	"go-task/pkg"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const (
+	ssoClientID    = "go-task"
+	ssoRedirectURL = "http://go-task.test/auth/oidc/callback"
+	ssoWorkspaceID = 7
+)
+
+// fakeAccounts keeps users, sessions and linked identities in memory.
+type fakeAccounts struct {
+	users      map[int64]*model.User
+	sessions   map[string]*model.Session
+	identities []*model.Identity
+}
+
+func newFakeAccounts() *fakeAccounts {
+	return &fakeAccounts{users: map[int64]*model.User{}, sessions: map[string]*model.Session{}}
+}
+
+type fakeUsers struct{ *fakeAccounts }
+
+func (users fakeUsers) Save(user *model.User) (*model.User, error) {
+	if user.ID == 0 {
+		user.ID = int64(len(users.users) + 1)
+	}
+	users.users[user.ID] = user
+	return user, nil
+}
+
+func (users fakeUsers) FindById(id int64) (*model.User, error) {
+	if user, ok := users.users[id]; ok {
+		return user, nil
+	}
+	return nil, fmt.Errorf("user %d: %w", id, pkg.ErrNotFound)
+}
+
+func (users fakeUsers) FindByUsername(username string) (*model.User, error) {
+	for _, user := range users.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return nil, fmt.Errorf("user %s: %w", username, pkg.ErrNotFound)
+}
+
+func (users fakeUsers) FindAll(workspaceID int64) ([]*model.User, error) {
+	var found []*model.User
+	for _, user := range users.users {
+		if user.WorkspaceID == workspaceID {
+			found = append(found, user)
+		}
+	}
+	return found, nil
+}
+
+type fakeSessions struct{ *fakeAccounts }
+
+func (sessions fakeSessions) Save(session *model.Session) (*model.Session, error) {
+	sessions.sessions[session.ID] = session
+	return session, nil
+}
+
+func (sessions fakeSessions) FindById(id string) (*model.Session, error) {
+	if session, ok := sessions.sessions[id]; ok {
+		return session, nil
+	}
+	return nil, fmt.Errorf("session: %w", pkg.ErrNotFound)
+}
+
+func (sessions fakeSessions) Delete(id string) error {
+	delete(sessions.sessions, id)
+	return nil
+}
+
+type fakeIdentities struct{ *fakeAccounts }
+
+func (identities fakeIdentities) Save(identity *model.Identity) (*model.Identity, error) {
+	identity.ID = int64(len(identities.identities) + 1)
+	identities.identities = append(identities.identities, identity)
+	return identity, nil
+}
+
+func (identities fakeIdentities) FindBySubject(issuer string, subject string) (*model.Identity, error) {
+	for _, identity := range identities.identities {
+		if identity.Issuer == issuer && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return nil, fmt.Errorf("identity %s: %w", subject, pkg.ErrNotFound)
+}
+
+type fakeWorkspaces struct{}
+
+func (fakeWorkspaces) Save(workspace *model.Workspace) (*model.Workspace, error) {
+	return workspace, nil
+}
+
+func (fakeWorkspaces) FindById(id int64) (*model.Workspace, error) {
+	return &model.Workspace{ID: id}, nil
+}
+
+// ssoFixture is the stub provider served over HTTP, a client of it and
+// the sign-in service behind the callback.
+type ssoFixture struct {
+	client   *sso.Client
+	accounts *fakeAccounts
+	auth     *AuthService
+	service  *SSOService
+	// browser follows no redirects so each step of the flow can be seen.
+	browser *http.Client
+}
+
+func newSSOFixture(t *testing.T) *ssoFixture {
+	t.Helper()
+	var stub *sso.Stub
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stub.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	stub, err := sso.NewStub(server.URL, ssoClientID)
+	if err != nil {
+		t.Fatalf("NewStub: %v", err)
+	}
+
+	accounts := newFakeAccounts()
+	auth := NewAuthService(fakeUsers{accounts}, fakeSessions{accounts}, fakeWorkspaces{})
+	return &ssoFixture{
+		client:   sso.NewClient(sso.Config{Issuer: server.URL, ClientID: ssoClientID, RedirectURL: ssoRedirectURL}),
+		accounts: accounts,
+		auth:     auth,
+		service:  NewSSOService(auth, fakeIdentities{accounts}, ssoWorkspaceID),
+		browser: &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}},
+	}
+}
+
+func newFlow(t *testing.T) sso.Flow {
+	t.Helper()
+	var values [3]string
+	for i := range values {
+		value, err := model.NewToken()
+		if err != nil {
+			t.Fatalf("NewToken: %v", err)
+		}
+		values[i] = value
+	}
+	return sso.Flow{State: values[0], Nonce: values[1], Verifier: values[2]}
+}
+
+// authorize sends the browser to the provider as username and returns the
+// query of the redirect back to the application.
+func (fixture *ssoFixture) authorize(t *testing.T, flow sso.Flow, username string, edit func(url.Values)) url.Values {
+	t.Helper()
+	target, err := fixture.client.AuthCodeURL(context.Background(), flow.State, flow.Nonce, flow.Verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	authorizeURL, _ := url.Parse(target)
+	query := authorizeURL.Query()
+	query.Set("login_hint", username)
+	if edit != nil {
+		edit(query)
+	}
+	authorizeURL.RawQuery = query.Encode()
+
+	resp, err := fixture.browser.Get(authorizeURL.String())
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize answered %d, want a redirect", resp.StatusCode)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("redirect: %v", err)
+	}
+	if !strings.HasPrefix(location.String(), ssoRedirectURL+"?") {
+		t.Fatalf("redirected to %s, want the callback", location)
+	}
+	return location.Query()
+}
+
+func TestSSOFlowProvisionsFirstTimeUser(t *testing.T) {
+	fixture := newSSOFixture(t)
+	flow := newFlow(t)
+
+	target, err := fixture.client.AuthCodeURL(context.Background(), flow.State, flow.Nonce, flow.Verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	params, _ := url.Parse(target)
+	challenge := sha256.Sum256([]byte(flow.Verifier))
+	if got := params.Query().Get("code_challenge_method"); got != "S256" {
+		t.Errorf("code_challenge_method = %q, want S256", got)
+	}
+	if got, want := params.Query().Get("code_challenge"), base64.RawURLEncoding.EncodeToString(challenge[:]); got != want {
+		t.Errorf("code_challenge = %q, want the S256 of the verifier %q", got, want)
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		flow := newFlow(t)
+		claims, err := fixture.client.Callback(context.Background(), flow, fixture.authorize(t, flow, "ada", nil))
+		if err != nil {
+			t.Fatalf("Callback: %v", err)
+		}
+		if claims.Username() != "ada" {
+			t.Fatalf("signed in as %q, want ada", claims.Username())
+		}
+		user, token, err := fixture.service.Login(ExternalIdentity{Issuer: claims.Issuer, Subject: claims.Subject, Username: claims.Username()})
+		if err != nil {
+			t.Fatalf("Login: %v", err)
+		}
+		if user.Username != "ada" || user.WorkspaceID != ssoWorkspaceID || user.Role != model.RoleMember {
+			t.Fatalf("provisioned %+v, want member ada of workspace %d", user, ssoWorkspaceID)
+		}
+		if user.PasswordHash != "" {
+			t.Fatal("provisioned user has a password")
+		}
+		authenticated, err := fixture.auth.Authenticate(token)
+		if err != nil || authenticated.ID != user.ID {
+			t.Fatalf("session token authenticates %v, %v; want ada", authenticated, err)
+		}
+	}
+	// The second sign-in finds the linked identity instead of provisioning
+	// again.
+	if len(fixture.accounts.users) != 1 || len(fixture.accounts.identities) != 1 {
+		t.Fatalf("got %d users and %d identities, want one of each", len(fixture.accounts.users), len(fixture.accounts.identities))
+	}
+}
+
+func TestSSOFlowRejectsTamperedCallback(t *testing.T) {
+	tests := []struct {
+		name string
+		// edit changes the authorization request, tamper the pending flow
+		// at the callback.
+		edit    func(url.Values)
+		tamper  func(flow *sso.Flow)
+		wantErr func(error) bool
+	}{
+		{
+			name:    "state mismatch",
+			tamper:  func(flow *sso.Flow) { flow.State = "forged" },
+			wantErr: func(err error) bool { return errors.Is(err, sso.ErrStateMismatch) },
+		},
+		{
+			name:    "nonce mismatch",
+			tamper:  func(flow *sso.Flow) { flow.Nonce = "replayed" },
+			wantErr: func(err error) bool { return err != nil && strings.Contains(err.Error(), "nonce") },
+		},
+		{
+			name:    "wrong PKCE verifier",
+			tamper:  func(flow *sso.Flow) { flow.Verifier = strings.Repeat("x", 43) },
+			wantErr: func(err error) bool { return err != nil && strings.Contains(err.Error(), "invalid_grant") },
+		},
+		{
+			name: "plain PKCE challenge",
+			edit: func(query url.Values) { query.Set("code_challenge_method", "plain") },
+			wantErr: func(err error) bool {
+				var providerErr *sso.ProviderError
+				return errors.As(err, &providerErr) && providerErr.Code == "invalid_request"
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixture := newSSOFixture(t)
+			flow := newFlow(t)
+			callback := fixture.authorize(t, flow, "ada", tt.edit)
+			if tt.tamper != nil {
+				tt.tamper(&flow)
+			}
+
+			claims, err := fixture.client.Callback(context.Background(), flow, callback)
+			if !tt.wantErr(err) {
+				t.Fatalf("got %v, %v; want the callback rejected", claims, err)
+			}
+			if len(fixture.accounts.users) != 0 {
+				t.Fatal("a rejected callback provisioned a user")
+			}
+		})
+	}
+}
+
+// A code is redeemed once; a replayed callback fails even with the right
+// flow.
+func TestSSOCodeIsSingleUse(t *testing.T) {
+	fixture := newSSOFixture(t)
+	flow := newFlow(t)
+	callback := fixture.authorize(t, flow, "ada", nil)
+	if _, err := fixture.client.Callback(context.Background(), flow, callback); err != nil {
+		t.Fatalf("first Callback: %v", err)
+	}
+	if _, err := fixture.client.Callback(context.Background(), flow, callback); err == nil {
+		t.Fatal("second Callback with the same code succeeded")
+	}
+}
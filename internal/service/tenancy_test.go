@@ -0,0 +1,73 @@
+package service
+
+import (
+	"errors"
+	"go-task/internal/model"
+	"go-task/pkg"
+	"testing"
+	"time"
+)
+
+const (
+	workspaceA int64 = 1
+	workspaceB int64 = 2
+)
+
+// A user of one workspace must never reach a task of another, whatever
+// their role; the task is reported as not found so its existence is not
+// leaked either.
+func TestTaskOfOtherWorkspaceIsNotFound(t *testing.T) {
+	task := &model.Task{WorkspaceID: workspaceA, Title: "quarterly numbers", Status: pkg.TODO}
+	service := newTestService(t, time.Now(), task)
+
+	if found, err := service.FindById(userContext(1, workspaceA, model.RoleViewer), task.ID); err != nil || found.ID != task.ID {
+		t.Fatalf("owner workspace: got %v, %v; want the task", found, err)
+	}
+
+	for _, role := range []model.Role{model.RoleViewer, model.RoleMember, model.RoleAdmin} {
+		t.Run(string(role), func(t *testing.T) {
+			ctx := userContext(2, workspaceB, role)
+
+			found, err := service.FindById(ctx, task.ID)
+			if !errors.Is(err, pkg.ErrNotFound) {
+				t.Fatalf("FindById: got %v, %v; want %v", found, err, pkg.ErrNotFound)
+			}
+			if _, err := service.History(ctx, task.ID); !errors.Is(err, pkg.ErrNotFound) {
+				t.Errorf("History: got %v, want %v", err, pkg.ErrNotFound)
+			}
+			if tasks, err := service.FindByIds(ctx, []int64{task.ID}); err != nil || len(tasks) != 0 {
+				t.Errorf("FindByIds: got %v, %v; want no tasks", tasks, err)
+			}
+			if tasks, err := service.FindAll(ctx); err != nil || len(tasks) != 0 {
+				t.Errorf("FindAll: got %v, %v; want no tasks", tasks, err)
+			}
+			if role == model.RoleViewer {
+				return
+			}
+			if _, err := service.UpdateStatus(ctx, task.ID, pkg.COMPLETED); !errors.Is(err, pkg.ErrNotFound) {
+				t.Errorf("UpdateStatus: got %v, want %v", err, pkg.ErrNotFound)
+			}
+			if err := service.Delete(ctx, task.ID); !errors.Is(err, pkg.ErrNotFound) {
+				t.Errorf("Delete: got %v, want %v", err, pkg.ErrNotFound)
+			}
+		})
+	}
+
+	stored, _ := service.store.FindById(workspaceA, task.ID)
+	if stored.Status != pkg.TODO || stored.DeletedAt != nil || len(service.store.events) != 0 {
+		t.Fatalf("task of workspace A was changed from workspace B: %+v", stored)
+	}
+}
+
+// Tasks created by a user are filed under the user's workspace whatever
+// the request says.
+func TestCreateUsesCallerWorkspace(t *testing.T) {
+	service := newTestService(t, time.Now())
+	task, err := service.Create(userContext(2, workspaceB, model.RoleMember), &model.Task{WorkspaceID: workspaceA, Title: "sneaky", Status: pkg.TODO})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if task.WorkspaceID != workspaceB {
+		t.Fatalf("task filed under workspace %d, want %d", task.WorkspaceID, workspaceB)
+	}
+}
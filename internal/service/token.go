@@ -0,0 +1,77 @@
+package service
+
+import (
+	"fmt"
+	"go-task/internal/model"
+	"go-task/pkg"
+	"log"
+	"time"
+)
+
+type APITokenStore interface {
+	Save(token *model.APIToken) (*model.APIToken, error)
+	FindByHash(hash string) (*model.APIToken, error)
+	FindByUserId(userID int64) ([]*model.APIToken, error)
+	Revoke(userID int64, id int64, at time.Time) error
+	Touch(id int64, at time.Time) error
+}
+
+type TokenService struct {
+	tokens APITokenStore
+	users  UserStore
+	clock  Clock
+}
+
+func NewTokenService(tokens APITokenStore, users UserStore) *TokenService {
+	return &TokenService{
+		tokens: tokens,
+		users:  users,
+		clock:  systemClock{},
+	}
+}
+
+// Issue creates a token for user and returns the plaintext value, which
+// cannot be recovered afterwards.
+func (service *TokenService) Issue(user *model.User, name string, scope model.TokenScope, expiresAt *time.Time) (*model.APIToken, string, error) {
+	if expiresAt != nil && !expiresAt.After(service.clock.Now()) {
+		return nil, "", &pkg.ValidationError{Field: "expiresAt", Message: "token expiry must be in the future"}
+	}
+	token, plaintext, err := model.NewAPIToken(user.ID, name, scope, expiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+	token, err = service.tokens.Save(token)
+	if err != nil {
+		return nil, "", err
+	}
+	return token, plaintext, nil
+}
+
+func (service *TokenService) List(user *model.User) ([]*model.APIToken, error) {
+	return service.tokens.FindByUserId(user.ID)
+}
+
+func (service *TokenService) Revoke(user *model.User, id int64) error {
+	return service.tokens.Revoke(user.ID, id, service.clock.Now())
+}
+
+// Authenticate resolves a bearer token to its owner. The token is looked
+// up on every request, so revocation takes effect immediately.
+func (service *TokenService) Authenticate(plaintext string) (*model.User, *model.APIToken, error) {
+	token, err := service.tokens.FindByHash(model.HashToken(plaintext))
+	if err != nil {
+		return nil, nil, err
+	}
+	now := service.clock.Now()
+	if !token.Active(now) {
+		return nil, nil, fmt.Errorf("api token %d is revoked or expired: %w", token.ID, pkg.ErrNotFound)
+	}
+	user, err := service.users.FindById(token.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := service.tokens.Touch(token.ID, now); err != nil {
+		log.Println("Failed to record api token use:", err)
+	}
+	return user, token, nil
+}
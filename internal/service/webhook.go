@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"go-task/internal/model"
+)
+
+// deliveryLogLimit is how many deliveries of a webhook the log shows.
+const deliveryLogLimit = 100
+
+type WebhookStore interface {
+	Save(webhook *model.Webhook) (*model.Webhook, error)
+	FindById(workspaceID int64, id int64) (*model.Webhook, error)
+	FindAll(workspaceID int64) ([]*model.Webhook, error)
+	Delete(workspaceID int64, id int64) error
+	FindDeliveries(webhookID int64, limit int) ([]*model.WebhookDelivery, error)
+}
+
+// WebhookService manages the webhook subscriptions of the caller's
+// workspace; only admins may see or change them since they hold secrets.
+type WebhookService struct {
+	webhooks WebhookStore
+}
+
+func NewWebhookService(webhooks WebhookStore) *WebhookService {
+	return &WebhookService{
+		webhooks: webhooks,
+	}
+}
+
+func (service *WebhookService) Create(ctx context.Context, webhook *model.Webhook) (*model.Webhook, error) {
+	workspaceID, err := service.workspace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	webhook.WorkspaceID = workspaceID
+	return service.webhooks.Save(webhook)
+}
+
+func (service *WebhookService) FindAll(ctx context.Context) ([]*model.Webhook, error) {
+	workspaceID, err := service.workspace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return service.webhooks.FindAll(workspaceID)
+}
+
+func (service *WebhookService) Delete(ctx context.Context, id int64) error {
+	workspaceID, err := service.workspace(ctx)
+	if err != nil {
+		return err
+	}
+	return service.webhooks.Delete(workspaceID, id)
+}
+
+// Deliveries returns the latest entries of the delivery log of a webhook.
+func (service *WebhookService) Deliveries(ctx context.Context, id int64) ([]*model.WebhookDelivery, error) {
+	workspaceID, err := service.workspace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := service.webhooks.FindById(workspaceID, id); err != nil {
+		return nil, err
+	}
+	return service.webhooks.FindDeliveries(id, deliveryLogLimit)
+}
+
+func (service *WebhookService) workspace(ctx context.Context) (int64, error) {
+	if err := authorize(ctx, model.PermManageWebhooks); err != nil {
+		return 0, err
+	}
+	return workspaceFrom(ctx)
+}
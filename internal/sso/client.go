@@ -0,0 +1,171 @@
+package sso
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// Config describes the relying party registration at the identity
+// provider.
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// WorkspaceID is the workspace users signing in for the first time are
+	// provisioned into.
+	WorkspaceID int64
+}
+
+// ConfigFromEnv reads the OIDC_* variables. Single sign-on is disabled
+// when OIDC_ISSUER is not set.
+func ConfigFromEnv() (Config, bool) {
+	config := Config{
+		Issuer:       os.Getenv("OIDC_ISSUER"),
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+	}
+	config.WorkspaceID, _ = strconv.ParseInt(os.Getenv("OIDC_WORKSPACE_ID"), 10, 64)
+	return config, config.Issuer != ""
+}
+
+// Claims are the ID token claims used to map the caller to a local user.
+type Claims struct {
+	Issuer            string `json:"iss"`
+	Subject           string `json:"sub"`
+	Email             string `json:"email"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+// Username picks the most readable name the provider sent.
+func (claims Claims) Username() string {
+	switch {
+	case claims.PreferredUsername != "":
+		return claims.PreferredUsername
+	case claims.Email != "":
+		return claims.Email
+	default:
+		return claims.Subject
+	}
+}
+
+// Flow is the secret state of one pending sign-in, kept by the client
+// between the redirect to the provider and the callback.
+type Flow struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// ErrStateMismatch reports a callback that does not belong to the pending
+// sign-in, such as a forged or replayed redirect.
+var ErrStateMismatch = errors.New("callback state does not match the pending sign-in")
+
+// ProviderError is an error the provider redirected back with instead of
+// a code.
+type ProviderError struct {
+	Code string
+}
+
+func (e *ProviderError) Error() string {
+	return "identity provider refused sign-in: " + e.Code
+}
+
+// Client runs the authorization code flow with PKCE against an OpenID
+// Connect provider. Discovery happens on first use rather than at startup,
+// so the application can boot while the provider is unreachable.
+type Client struct {
+	config   Config
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+func NewClient(config Config) *Client {
+	return &Client{
+		config: config,
+	}
+}
+
+// AuthCodeURL returns the provider URL to send the browser to. The caller
+// keeps state, nonce and verifier until the callback.
+func (client *Client) AuthCodeURL(ctx context.Context, state string, nonce string, verifier string) (string, error) {
+	oauth, _, err := client.oauth(ctx)
+	if err != nil {
+		return "", err
+	}
+	return oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Callback completes flow with the query of the provider's redirect: the
+// state must be the one sent, an error from the provider is returned as a
+// *ProviderError, and the code is exchanged for verified claims.
+func (client *Client) Callback(ctx context.Context, flow Flow, query url.Values) (*Claims, error) {
+	state := query.Get("state")
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(flow.State)) != 1 {
+		return nil, ErrStateMismatch
+	}
+	if code := query.Get("error"); code != "" {
+		return nil, &ProviderError{Code: code}
+	}
+	return client.Exchange(ctx, query.Get("code"), flow.Nonce, flow.Verifier)
+}
+
+// Exchange redeems code for tokens and returns the claims of the verified
+// ID token.
+func (client *Client) Exchange(ctx context.Context, code string, nonce string, verifier string) (*Claims, error) {
+	oauth, provider, err := client.oauth(ctx)
+	if err != nil {
+		return nil, err
+	}
+	token, err := oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("exchange authorization code: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("token response has no id_token")
+	}
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: client.config.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("verify id token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("id token nonce does not match")
+	}
+
+	var claims Claims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("decode id token claims: %w", err)
+	}
+	return &claims, nil
+}
+
+func (client *Client) oauth(ctx context.Context) (*oauth2.Config, *oidc.Provider, error) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if client.provider == nil {
+		provider, err := oidc.NewProvider(ctx, client.config.Issuer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("discover %s: %w", client.config.Issuer, err)
+		}
+		client.provider = provider
+	}
+
+	return &oauth2.Config{
+		ClientID:     client.config.ClientID,
+		ClientSecret: client.config.ClientSecret,
+		RedirectURL:  client.config.RedirectURL,
+		Endpoint:     client.provider.Endpoint(),
+		Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
+	}, client.provider, nil
+}
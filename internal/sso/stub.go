@@ -0,0 +1,262 @@
+package sso
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const stubKeyID = "stub"
+const stubCodeTTL = time.Minute
+
+// stubLoginPage lets the tester pick who to sign in as; the stub has no
+// passwords.
+var stubLoginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html><head><title>Stub identity provider</title></head>
+<body>
+<h1>Stub identity provider</h1>
+<form method="post">
+{{range $name, $value := .}}<input type="hidden" name="{{$name}}" value="{{index $value 0}}">
+{{end}}<input name="login_hint" placeholder="Username" required autofocus>
+<button type="submit">Sign in</button>
+</form>
+</body></html>`))
+
+type stubGrant struct {
+	clientID      string
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+	username      string
+	expiresAt     time.Time
+}
+
+// Stub is a minimal in-process OpenID Connect provider for development and
+// integration tests. It supports discovery, the authorization code flow
+// with S256 PKCE and RS256 ID tokens, and signs in whoever is named in the
+// login_hint parameter or its login form. Never expose it in production.
+type Stub struct {
+	issuer   string
+	clientID string
+	key      *rsa.PrivateKey
+	mux      *http.ServeMux
+	mu       sync.Mutex
+	grants   map[string]stubGrant
+}
+
+// NewStub creates a provider that answers as issuer, which must be the URL
+// the stub is reachable at, to the single client clientID.
+func NewStub(issuer string, clientID string) (*Stub, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	stub := &Stub{
+		issuer:   strings.TrimSuffix(issuer, "/"),
+		clientID: clientID,
+		key:      key,
+		mux:      http.NewServeMux(),
+		grants:   map[string]stubGrant{},
+	}
+	stub.mux.HandleFunc("GET /.well-known/openid-configuration", stub.discovery)
+	stub.mux.HandleFunc("GET /authorize", stub.authorize)
+	stub.mux.HandleFunc("POST /authorize", stub.authorize)
+	stub.mux.HandleFunc("POST /token", stub.token)
+	stub.mux.HandleFunc("GET /jwks", stub.jwks)
+	return stub, nil
+}
+
+// ServeHTTP serves the provider endpoints relative to the issuer path, so
+// the stub is mounted with http.StripPrefix when it shares a server.
+func (stub *Stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	stub.mux.ServeHTTP(w, r)
+}
+
+func (stub *Stub) discovery(w http.ResponseWriter, r *http.Request) {
+	writeStubJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                stub.issuer,
+		"authorization_endpoint":                stub.issuer + "/authorize",
+		"token_endpoint":                        stub.issuer + "/token",
+		"jwks_uri":                              stub.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "profile", "email"},
+	})
+}
+
+func (stub *Stub) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	params := r.Form
+	if params.Get("client_id") != stub.clientID {
+		http.Error(w, "unknown client_id", http.StatusBadRequest)
+		return
+	}
+	redirectURI, err := url.Parse(params.Get("redirect_uri"))
+	if err != nil || !redirectURI.IsAbs() {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if params.Get("response_type") != "code" || params.Get("code_challenge_method") != "S256" || params.Get("code_challenge") == "" {
+		redirectError(w, r, redirectURI, params.Get("state"), "invalid_request")
+		return
+	}
+
+	username := strings.TrimSpace(params.Get("login_hint"))
+	if username == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := stubLoginPage.Execute(w, r.URL.Query()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	code := randomString()
+	stub.mu.Lock()
+	stub.grants[code] = stubGrant{
+		clientID:      stub.clientID,
+		redirectURI:   redirectURI.String(),
+		codeChallenge: params.Get("code_challenge"),
+		nonce:         params.Get("nonce"),
+		username:      username,
+		expiresAt:     time.Now().Add(stubCodeTTL),
+	}
+	stub.mu.Unlock()
+
+	query := redirectURI.Query()
+	query.Set("code", code)
+	query.Set("state", params.Get("state"))
+	redirectURI.RawQuery = query.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+// token redeems an authorization code once. The client authenticates with
+// the PKCE verifier alone, like a public client.
+func (stub *Stub) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+	clientID, _, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostForm.Get("client_id")
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	stub.mu.Lock()
+	grant, found := stub.grants[code]
+	delete(stub.grants, code)
+	stub.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	switch {
+	case !found || time.Now().After(grant.expiresAt):
+		tokenError(w, "invalid_grant")
+		return
+	case clientID != grant.clientID || r.PostForm.Get("redirect_uri") != grant.redirectURI:
+		tokenError(w, "invalid_grant")
+		return
+	case subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(challenge[:])), []byte(grant.codeChallenge)) != 1:
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	idToken, err := stub.sign(grant)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeStubJSON(w, http.StatusOK, map[string]any{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (stub *Stub) jwks(w http.ResponseWriter, r *http.Request) {
+	public := stub.key.PublicKey
+	writeStubJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": stubKeyID,
+			"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}},
+	})
+}
+
+func (stub *Stub) sign(grant stubGrant) (string, error) {
+	now := time.Now()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": stubKeyID, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iss":                stub.issuer,
+		"sub":                "stub|" + grant.username,
+		"aud":                grant.clientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"nonce":              grant.nonce,
+		"preferred_username": grant.username,
+		"email":              grant.username + "@example.com",
+	})
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(payload))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, stub.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return payload + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func redirectError(w http.ResponseWriter, r *http.Request, redirectURI *url.URL, state string, code string) {
+	query := redirectURI.Query()
+	query.Set("error", code)
+	query.Set("state", state)
+	redirectURI.RawQuery = query.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeStubJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeStubJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
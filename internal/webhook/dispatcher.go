@@ -0,0 +1,369 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-task/internal/model"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	// attemptsPerRound is how often a delivery is tried before it goes to
+	// the dead letters, which are replayed every replayInterval until
+	// maxAttempts is reached.
+	attemptsPerRound = 4
+	maxAttempts      = 4 * attemptsPerRound
+	replayInterval   = time.Minute
+	replayBatch      = 100
+	requestTimeout   = 10 * time.Second
+	// deliveryWorkers is how many deliveries are sent at the same time;
+	// the others wait in a queue of deliveryQueue. Deliveries that do not
+	// fit in the queue are parked as dead letters for the replay.
+	deliveryWorkers = 8
+	deliveryQueue   = 200
+)
+
+// ErrNonPublicAddress is the error of a delivery to an address on the
+// server itself or its internal network.
+var ErrNonPublicAddress = errors.New("webhook address is not public")
+
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+type Store interface {
+	FindAll(workspaceID int64) ([]*model.Webhook, error)
+	FindWebhook(id int64) (*model.Webhook, error)
+	SaveDelivery(delivery *model.WebhookDelivery) (*model.WebhookDelivery, error)
+	FindDeadDeliveries(limit int) ([]*model.WebhookDelivery, error)
+	ParkPendingDeliveries() (int64, error)
+}
+
+// Payload is the JSON body of a delivery.
+type Payload struct {
+	Event      model.WebhookEvent           `json:"event"`
+	OccurredAt time.Time                    `json:"occurredAt"`
+	Actor      string                       `json:"actor"`
+	Task       TaskPayload                  `json:"task"`
+	Changes    map[string]model.FieldChange `json:"changes"`
+}
+
+type TaskPayload struct {
+	ID          int64      `json:"id"`
+	WorkspaceID int64      `json:"workspaceId"`
+	ProjectID   *int64     `json:"projectId,omitempty"`
+	Title       string     `json:"title"`
+	Content     string     `json:"content"`
+	Status      string     `json:"status"`
+	Labels      []string   `json:"labels,omitempty"`
+	DueAt       *time.Time `json:"dueAt,omitempty"`
+	Recurrence  string     `json:"recurrence,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	DeletedAt   *time.Time `json:"deletedAt,omitempty"`
+}
+
+type notification struct {
+	workspaceID int64
+	event       model.WebhookEvent
+	payload     []byte
+}
+
+type job struct {
+	webhook  *model.Webhook
+	delivery *model.WebhookDelivery
+}
+
+// Dispatcher delivers task events to the webhooks subscribed to them.
+// Like the search sync it retries a failing delivery a few times in a row
+// and then parks it as a dead letter, which a ticker replays later.
+// Deliveries are sent by a fixed pool of workers, so a burst of events or
+// a slow receiver queues deliveries instead of piling up connections, and
+// once the queues are full deliveries go to the dead letters rather than
+// hold up the requests that changed the tasks.
+type Dispatcher struct {
+	store         Store
+	client        *http.Client
+	notifications chan notification
+	deliveries    chan job
+	backoff       time.Duration
+	clock         func() time.Time
+}
+
+func NewDispatcher(store Store) *Dispatcher {
+	// Deliveries left pending by the previous run were lost with it. They
+	// are parked for the replay before this run logs any of its own.
+	if parked, err := store.ParkPendingDeliveries(); err != nil {
+		log.Println("Failed to park pending webhook deliveries:", err)
+	} else if parked > 0 {
+		log.Printf("parked %d webhook deliveries left pending", parked)
+	}
+	dispatcher := newDispatcher(store, &http.Client{Timeout: requestTimeout, Transport: publicTransport()}, deliveryWorkers, deliveryQueue)
+	go dispatcher.runReplayDeadLetter()
+	return dispatcher
+}
+
+func newDispatcher(store Store, client *http.Client, workers int, queue int) *Dispatcher {
+	dispatcher := &Dispatcher{
+		store:         store,
+		client:        client,
+		notifications: make(chan notification, queue),
+		deliveries:    make(chan job, queue),
+		backoff:       time.Second,
+		clock:         time.Now,
+	}
+	go dispatcher.startWorker()
+	for i := 0; i < workers; i++ {
+		go dispatcher.startDeliveryWorker()
+	}
+	return dispatcher
+}
+
+// publicTransport only connects to public addresses, so a webhook cannot
+// make the server call itself or its internal network. The check runs on
+// the address being dialled, after name resolution, which also covers a
+// name that resolves to a public address when the webhook is created and
+// to a private one when it is delivered. Proxies are not used as they
+// would be dialled instead of the receiver.
+func publicTransport() *http.Transport {
+	dialer := &net.Dialer{Timeout: requestTimeout, Control: dialPublic}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+func dialPublic(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !model.PublicAddr(addr) {
+		return fmt.Errorf("%s: %w", host, ErrNonPublicAddress)
+	}
+	return nil
+}
+
+// TaskChanged queues event for the webhooks of the task's workspace. It
+// runs in the request that changed the task, so it never waits for the
+// queue: when that is full the deliveries are logged as dead letters.
+func (dispatcher *Dispatcher) TaskChanged(event *model.TaskEvent, task *model.Task) {
+	webhookEvent := model.WebhookEventOf(event.Operation)
+	payload, err := json.Marshal(Payload{
+		Event:      webhookEvent,
+		OccurredAt: event.CreatedAt,
+		Actor:      event.Actor,
+		Task:       taskPayload(task),
+		Changes:    event.Changes,
+	})
+	if err != nil {
+		log.Println("Failed to marshal webhook payload:", err)
+		return
+	}
+	n := notification{workspaceID: task.WorkspaceID, event: webhookEvent, payload: payload}
+	select {
+	case dispatcher.notifications <- n:
+	default:
+		log.Printf("webhook queue full, parking %s of task %d", webhookEvent, task.ID)
+		dispatcher.record(n, model.DeliveryDead)
+	}
+}
+
+func (dispatcher *Dispatcher) startWorker() {
+	log.Println("starting webhook dispatcher")
+	for n := range dispatcher.notifications {
+		for _, job := range dispatcher.record(n, model.DeliveryPending) {
+			dispatcher.enqueue(job)
+		}
+	}
+}
+
+// record logs a delivery of n with status for every webhook subscribed
+// to it.
+func (dispatcher *Dispatcher) record(n notification, status model.DeliveryStatus) []job {
+	webhooks, err := dispatcher.store.FindAll(n.workspaceID)
+	if err != nil {
+		log.Println("Failed to load webhooks:", err)
+		return nil
+	}
+	var jobs []job
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(n.event) {
+			continue
+		}
+		delivery := model.NewWebhookDelivery(webhook.ID, n.event, n.payload)
+		delivery.Status = status
+		if _, err := dispatcher.store.SaveDelivery(delivery); err != nil {
+			log.Println("Failed to log webhook delivery:", err)
+			continue
+		}
+		jobs = append(jobs, job{webhook: webhook, delivery: delivery})
+	}
+	return jobs
+}
+
+// enqueue hands job to the delivery workers. When the queue is full the
+// delivery is parked as a dead letter instead, for the replay to send, and
+// enqueue reports false.
+func (dispatcher *Dispatcher) enqueue(job job) bool {
+	select {
+	case dispatcher.deliveries <- job:
+		return true
+	default:
+		job.delivery.Status = model.DeliveryDead
+		if _, err := dispatcher.store.SaveDelivery(job.delivery); err != nil {
+			log.Println("Failed to park webhook delivery:", err)
+		}
+		return false
+	}
+}
+
+func (dispatcher *Dispatcher) startDeliveryWorker() {
+	for job := range dispatcher.deliveries {
+		dispatcher.deliver(job.webhook, job.delivery)
+	}
+}
+
+func (dispatcher *Dispatcher) runReplayDeadLetter() {
+	dispatcher.replayDeadLetter()
+	ticker := time.NewTicker(replayInterval)
+	for range ticker.C {
+		dispatcher.replayDeadLetter()
+	}
+}
+
+func (dispatcher *Dispatcher) replayDeadLetter() {
+	deliveries, err := dispatcher.store.FindDeadDeliveries(replayBatch)
+	if err != nil {
+		log.Println("Failed to query dead webhook deliveries:", err)
+		return
+	}
+	for _, delivery := range deliveries {
+		webhook, err := dispatcher.store.FindWebhook(delivery.WebhookID)
+		if err != nil {
+			log.Println("Failed to load webhook of dead delivery:", err)
+			continue
+		}
+		// Marked pending first so the next replay does not pick the
+		// delivery up again while it is still being retried.
+		delivery.Status = model.DeliveryPending
+		if _, err := dispatcher.store.SaveDelivery(delivery); err != nil {
+			log.Println("Failed to update webhook delivery:", err)
+			continue
+		}
+		if !dispatcher.enqueue(job{webhook: webhook, delivery: delivery}) {
+			return
+		}
+	}
+}
+
+// deliver runs one round of attempts with exponential backoff and records
+// the outcome in the delivery log.
+func (dispatcher *Dispatcher) deliver(webhook *model.Webhook, delivery *model.WebhookDelivery) {
+	wait := dispatcher.backoff
+	for i := 0; i < attemptsPerRound; i++ {
+		if i > 0 {
+			time.Sleep(wait)
+			wait *= 2
+		}
+		delivery.Attempts++
+		code, err := dispatcher.send(webhook, delivery)
+		delivery.ResponseCode = code
+		if err == nil {
+			delivery.Status = model.DeliveryDelivered
+			delivery.Error = ""
+			break
+		}
+		delivery.Error = err.Error()
+		log.Printf("webhook delivery %d attempt %d: %s", delivery.ID, delivery.Attempts, err)
+		if delivery.Attempts >= maxAttempts {
+			break
+		}
+	}
+	if delivery.Status != model.DeliveryDelivered {
+		delivery.Status = model.DeliveryDead
+		if delivery.Attempts >= maxAttempts {
+			delivery.Status = model.DeliveryFailed
+		}
+	}
+	if _, err := dispatcher.store.SaveDelivery(delivery); err != nil {
+		log.Println("Failed to update webhook delivery:", err)
+	}
+}
+
+func (dispatcher *Dispatcher) send(webhook *model.Webhook, delivery *model.WebhookDelivery) (*int, error) {
+	request, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return nil, err
+	}
+	timestamp := dispatcher.clock().Unix()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "go-task-webhooks")
+	request.Header.Set(HeaderEvent, string(delivery.Event))
+	request.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	request.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, delivery.Payload))
+
+	response, err := dispatcher.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	code := response.StatusCode
+	if code < 200 || code > 299 {
+		return &code, fmt.Errorf("receiver answered %s", response.Status)
+	}
+	return &code, nil
+}
+
+// Sign computes the X-Webhook-Signature of a delivery: the hex HMAC-SHA256,
+// keyed by the webhook secret, of the timestamp, a dot and the body.
+// Receivers recompute it and should reject stale timestamps.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func taskPayload(task *model.Task) TaskPayload {
+	payload := TaskPayload{
+		ID:          task.ID,
+		WorkspaceID: task.WorkspaceID,
+		ProjectID:   task.ProjectID,
+		Title:       task.Title,
+		Content:     task.Content,
+		Status:      string(task.Status),
+		Labels:      task.Labels,
+		DueAt:       task.DueAt,
+		CreatedAt:   task.CreatedAt,
+		UpdatedAt:   task.UpdatedAt,
+		DeletedAt:   task.DeletedAt,
+	}
+	if task.Recurrence != nil {
+		payload.Recurrence = task.Recurrence.String()
+	}
+	return payload
+}
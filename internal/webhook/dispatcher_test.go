@@ -0,0 +1,452 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/pkg"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testSecret = "s3cret"
+
+// fakeStore keeps webhooks and the delivery log in memory. Deliveries are
+// stored as copies, as a database would, and every save is kept so tests
+// can follow a delivery through the log.
+type fakeStore struct {
+	mu       sync.Mutex
+	webhooks []*model.Webhook
+	log      map[int64]model.WebhookDelivery
+	saved    chan model.WebhookDelivery
+}
+
+func newFakeStore(webhooks ...*model.Webhook) *fakeStore {
+	for i, webhook := range webhooks {
+		webhook.ID = int64(i + 1)
+	}
+	return &fakeStore{webhooks: webhooks, log: map[int64]model.WebhookDelivery{}, saved: make(chan model.WebhookDelivery, 100)}
+}
+
+func (store *fakeStore) FindAll(workspaceID int64) ([]*model.Webhook, error) {
+	var found []*model.Webhook
+	for _, webhook := range store.webhooks {
+		if webhook.WorkspaceID == workspaceID {
+			found = append(found, webhook)
+		}
+	}
+	return found, nil
+}
+
+func (store *fakeStore) FindWebhook(id int64) (*model.Webhook, error) {
+	for _, webhook := range store.webhooks {
+		if webhook.ID == id {
+			return webhook, nil
+		}
+	}
+	return nil, fmt.Errorf("webhook %d: %w", id, pkg.ErrNotFound)
+}
+
+func (store *fakeStore) SaveDelivery(delivery *model.WebhookDelivery) (*model.WebhookDelivery, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if delivery.ID == 0 {
+		delivery.ID = int64(len(store.log) + 1)
+	}
+	store.log[delivery.ID] = *delivery
+	store.saved <- *delivery
+	return delivery, nil
+}
+
+func (store *fakeStore) FindDeadDeliveries(limit int) ([]*model.WebhookDelivery, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	var dead []*model.WebhookDelivery
+	for _, delivery := range store.log {
+		if delivery.Status == model.DeliveryDead && len(dead) < limit {
+			dead = append(dead, &delivery)
+		}
+	}
+	return dead, nil
+}
+
+func (store *fakeStore) ParkPendingDeliveries() (int64, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	var parked int64
+	for id, delivery := range store.log {
+		if delivery.Status == model.DeliveryPending {
+			delivery.Status = model.DeliveryDead
+			store.log[id] = delivery
+			parked++
+		}
+	}
+	return parked, nil
+}
+
+// statuses counts the deliveries in the log by status.
+func (store *fakeStore) statuses() map[model.DeliveryStatus]int {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	counts := map[model.DeliveryStatus]int{}
+	for _, delivery := range store.log {
+		counts[delivery.Status]++
+	}
+	return counts
+}
+
+// finished waits for the delivery log to record the outcome of a delivery.
+func (store *fakeStore) finished(t *testing.T) model.WebhookDelivery {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case delivery := <-store.saved:
+			if delivery.Status != model.DeliveryPending {
+				return delivery
+			}
+		case <-timeout:
+			t.Fatal("no delivery finished")
+		}
+	}
+}
+
+// receiver is a webhook endpoint that answers with the given status codes
+// in turn, repeating the last one, and keeps the requests it got.
+type receiver struct {
+	mu       sync.Mutex
+	codes    []int
+	requests []receivedRequest
+}
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+	at     time.Time
+}
+
+func (receiver *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	receiver.requests = append(receiver.requests, receivedRequest{header: r.Header.Clone(), body: body, at: time.Now()})
+	w.WriteHeader(receiver.codes[min(len(receiver.requests), len(receiver.codes))-1])
+}
+
+func (receiver *receiver) received() []receivedRequest {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	return slices.Clone(receiver.requests)
+}
+
+// newTestDispatcher delivers to a receiver on the loopback interface, which
+// the dispatcher of NewDispatcher refuses, for a webhook subscribed to
+// events or, without any, to all of them.
+func newTestDispatcher(t *testing.T, codes []int, events ...model.WebhookEvent) (*Dispatcher, *fakeStore, *receiver) {
+	t.Helper()
+	if len(events) == 0 {
+		events = []model.WebhookEvent{model.WebhookTaskCreated, model.WebhookTaskUpdated, model.WebhookTaskDeleted, model.WebhookTaskStatusChanged}
+	}
+	receiver := &receiver{codes: codes}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+	store := newFakeStore(&model.Webhook{WorkspaceID: 1, URL: server.URL + "/hook", Secret: testSecret, Events: events})
+	dispatcher := newDispatcher(store, server.Client(), 2, deliveryQueue)
+	dispatcher.backoff = 20 * time.Millisecond
+	return dispatcher, store, receiver
+}
+
+func taskChanged(dispatcher *Dispatcher, operation model.EventOperation) {
+	dispatcher.TaskChanged(
+		&model.TaskEvent{Operation: operation, Actor: "ada", CreatedAt: time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC)},
+		&model.Task{ID: 42, WorkspaceID: 1, Title: "write report", Status: pkg.TODO},
+	)
+}
+
+func TestDeliveryIsSigned(t *testing.T) {
+	dispatcher, store, receiver := newTestDispatcher(t, []int{http.StatusNoContent})
+	now := time.Unix(1700000000, 0)
+	dispatcher.clock = func() time.Time { return now }
+
+	taskChanged(dispatcher, model.EventCreate)
+	delivery := store.finished(t)
+
+	requests := receiver.received()
+	if len(requests) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(requests))
+	}
+	request := requests[0]
+	timestamp, err := strconv.ParseInt(request.header.Get(HeaderTimestamp), 10, 64)
+	if err != nil || timestamp != now.Unix() {
+		t.Fatalf("timestamp header %q, want %d", request.header.Get(HeaderTimestamp), now.Unix())
+	}
+	signature := request.header.Get(HeaderSignature)
+	if !hmac.Equal([]byte(signature), []byte(Sign(testSecret, timestamp, request.body))) {
+		t.Fatalf("signature %q does not verify", signature)
+	}
+	if hmac.Equal([]byte(signature), []byte(Sign("other secret", timestamp, request.body))) {
+		t.Fatal("signature verifies with the wrong secret")
+	}
+	if hmac.Equal([]byte(signature), []byte(Sign(testSecret, timestamp+1, request.body))) {
+		t.Fatal("signature verifies with another timestamp")
+	}
+	if got := request.header.Get(HeaderEvent); got != string(model.WebhookTaskCreated) {
+		t.Errorf("event header %q, want %q", got, model.WebhookTaskCreated)
+	}
+	if got := request.header.Get(HeaderDelivery); got != strconv.FormatInt(delivery.ID, 10) {
+		t.Errorf("delivery header %q, want %d", got, delivery.ID)
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(request.body, &payload); err != nil {
+		t.Fatalf("payload: %v", err)
+	}
+	if payload.Event != model.WebhookTaskCreated || payload.Actor != "ada" || payload.Task.ID != 42 {
+		t.Errorf("payload %+v does not describe the change", payload)
+	}
+}
+
+func TestDeliveryLog(t *testing.T) {
+	code := func(code int) *int { return &code }
+	tests := []struct {
+		name         string
+		codes        []int
+		wantStatus   model.DeliveryStatus
+		wantAttempts int
+		wantCode     *int
+		wantError    bool
+	}{
+		{"delivered", []int{http.StatusOK}, model.DeliveryDelivered, 1, code(http.StatusOK), false},
+		{"delivered on retry", []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusAccepted}, model.DeliveryDelivered, 3, code(http.StatusAccepted), false},
+		{"retries used up", []int{http.StatusServiceUnavailable}, model.DeliveryDead, attemptsPerRound, code(http.StatusServiceUnavailable), true},
+		{"answer outside 2xx", []int{http.StatusNotModified}, model.DeliveryDead, attemptsPerRound, code(http.StatusNotModified), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dispatcher, store, receiver := newTestDispatcher(t, tt.codes)
+
+			taskChanged(dispatcher, model.EventUpdate)
+			delivery := store.finished(t)
+
+			if delivery.Status != tt.wantStatus || delivery.Attempts != tt.wantAttempts {
+				t.Errorf("delivery %s after %d attempts, want %s after %d", delivery.Status, delivery.Attempts, tt.wantStatus, tt.wantAttempts)
+			}
+			if delivery.ResponseCode == nil || *delivery.ResponseCode != *tt.wantCode {
+				t.Errorf("response code %v, want %d", delivery.ResponseCode, *tt.wantCode)
+			}
+			if (delivery.Error != "") != tt.wantError {
+				t.Errorf("error %q, want one: %v", delivery.Error, tt.wantError)
+			}
+			if got := len(receiver.received()); got != tt.wantAttempts {
+				t.Errorf("receiver got %d requests, want %d", got, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+// Each retry waits twice as long as the one before.
+func TestRetryBacksOff(t *testing.T) {
+	dispatcher, store, receiver := newTestDispatcher(t, []int{http.StatusInternalServerError})
+
+	taskChanged(dispatcher, model.EventUpdate)
+	store.finished(t)
+
+	requests := receiver.received()
+	if len(requests) != attemptsPerRound {
+		t.Fatalf("receiver got %d requests, want %d", len(requests), attemptsPerRound)
+	}
+	wait := dispatcher.backoff
+	for i := 1; i < len(requests); i++ {
+		if gap := requests[i].at.Sub(requests[i-1].at); gap < wait {
+			t.Errorf("attempt %d came %s after the one before, want at least %s", i+1, gap, wait)
+		}
+		wait *= 2
+	}
+}
+
+func TestOnlySubscribedEventsAreDelivered(t *testing.T) {
+	dispatcher, store, receiver := newTestDispatcher(t, []int{http.StatusOK}, model.WebhookTaskDeleted)
+
+	taskChanged(dispatcher, model.EventUpdate)
+	taskChanged(dispatcher, model.EventDelete)
+	delivery := store.finished(t)
+
+	if delivery.Event != model.WebhookTaskDeleted {
+		t.Fatalf("delivered %s, want only %s", delivery.Event, model.WebhookTaskDeleted)
+	}
+	if got := len(receiver.received()); got != 1 {
+		t.Fatalf("receiver got %d requests, want 1", got)
+	}
+}
+
+// No more deliveries are in flight at once than there are workers.
+func TestDeliveriesAreBounded(t *testing.T) {
+	const workers, deliveries = 2, 6
+	var (
+		mu       sync.Mutex
+		inFlight int
+		peak     int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+	store := newFakeStore(&model.Webhook{WorkspaceID: 1, URL: server.URL, Secret: testSecret, Events: []model.WebhookEvent{model.WebhookTaskUpdated}})
+	dispatcher := newDispatcher(store, server.Client(), workers, deliveryQueue)
+
+	for i := 0; i < deliveries; i++ {
+		taskChanged(dispatcher, model.EventUpdate)
+	}
+	for i := 0; i < deliveries; i++ {
+		if delivery := store.finished(t); delivery.Status != model.DeliveryDelivered {
+			t.Fatalf("delivery %d %s: %s", delivery.ID, delivery.Status, delivery.Error)
+		}
+	}
+	if peak > workers {
+		t.Fatalf("%d deliveries in flight at once, want at most %d", peak, workers)
+	}
+}
+
+// A receiver that holds up every worker must not hold up task changes:
+// once the queues are full the deliveries are parked for the replay.
+func TestFullQueueParksDeliveries(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+	store := newFakeStore(&model.Webhook{WorkspaceID: 1, URL: server.URL, Secret: testSecret, Events: []model.WebhookEvent{model.WebhookTaskUpdated}})
+	dispatcher := newDispatcher(store, server.Client(), 1, 1)
+
+	const changes = 20
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < changes; i++ {
+			taskChanged(dispatcher, model.EventUpdate)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("TaskChanged waited for the stalled receiver")
+	}
+
+	// At most one delivery is in flight, one queued and one waiting to
+	// be queued; the others are all parked.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		statuses := store.statuses()
+		if statuses[model.DeliveryPending] <= 3 && statuses[model.DeliveryPending]+statuses[model.DeliveryDead] == changes {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("delivery log %v, want %d deliveries with all but the queued ones dead", statuses, changes)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// The replay picks up the deliveries that were parked, and on start those
+// left pending by the previous run.
+func TestReplaySendsParkedDeliveries(t *testing.T) {
+	dispatcher, store, receiver := newTestDispatcher(t, []int{http.StatusOK})
+	webhook := store.webhooks[0]
+	for _, status := range []model.DeliveryStatus{model.DeliveryDead, model.DeliveryPending, model.DeliveryDelivered} {
+		delivery := model.NewWebhookDelivery(webhook.ID, model.WebhookTaskUpdated, []byte(`{}`))
+		delivery.Status = status
+		if _, err := store.SaveDelivery(delivery); err != nil {
+			t.Fatalf("SaveDelivery: %v", err)
+		}
+		<-store.saved
+	}
+	if parked, _ := store.ParkPendingDeliveries(); parked != 1 {
+		t.Fatalf("parked %d deliveries, want the pending one", parked)
+	}
+
+	dispatcher.replayDeadLetter()
+	delivered := map[int64]bool{}
+	for len(delivered) < 2 {
+		delivery := store.finished(t)
+		if delivery.Status != model.DeliveryDelivered {
+			t.Fatalf("delivery %d %s: %s", delivery.ID, delivery.Status, delivery.Error)
+		}
+		delivered[delivery.ID] = true
+	}
+	if !delivered[1] || !delivered[2] || len(receiver.received()) != 2 {
+		t.Fatalf("replayed %v with %d requests, want deliveries 1 and 2", delivered, len(receiver.received()))
+	}
+}
+
+// The production transport refuses to connect to the receiver, which
+// listens on loopback, whatever its URL says.
+func TestNonPublicReceiverIsRefused(t *testing.T) {
+	receiver := &receiver{codes: []int{http.StatusOK}}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+	store := newFakeStore(&model.Webhook{WorkspaceID: 1, URL: server.URL, Secret: testSecret, Events: []model.WebhookEvent{model.WebhookTaskUpdated}})
+	dispatcher := newDispatcher(store, &http.Client{Timeout: requestTimeout, Transport: publicTransport()}, 1, deliveryQueue)
+	dispatcher.backoff = time.Millisecond
+
+	taskChanged(dispatcher, model.EventUpdate)
+	delivery := store.finished(t)
+
+	if delivery.Status != model.DeliveryDead || delivery.ResponseCode != nil {
+		t.Fatalf("delivery %s with code %v, want dead without a response", delivery.Status, delivery.ResponseCode)
+	}
+	if len(receiver.received()) != 0 {
+		t.Fatal("receiver on loopback was reached")
+	}
+}
+
+func TestDialPublic(t *testing.T) {
+	tests := []struct {
+		address string
+		public  bool
+	}{
+		{"93.184.215.14:443", true},
+		{"[2606:2800:21f:cb07:6820:80da:af6b:8b2c]:443", true},
+		{"127.0.0.1:80", false},
+		{"127.8.9.10:80", false},
+		{"[::1]:80", false},
+		{"10.1.2.3:80", false},
+		{"172.16.0.1:80", false},
+		{"192.168.1.1:80", false},
+		{"169.254.169.254:80", false},
+		{"[fe80::1]:80", false},
+		{"[fd00::1]:80", false},
+		{"0.0.0.0:80", false},
+		{"[::]:80", false},
+		{"100.64.0.1:80", false},
+		{"[::ffff:127.0.0.1]:80", false},
+		{"[::ffff:169.254.169.254]:80", false},
+		{"224.0.0.1:80", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := dialPublic("tcp", tt.address, nil)
+			if tt.public && err != nil {
+				t.Fatalf("got %v, want the address allowed", err)
+			}
+			if !tt.public && !errors.Is(err, ErrNonPublicAddress) {
+				t.Fatalf("got %v, want %v", err, ErrNonPublicAddress)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/internal/pubsub"
+	"go-task/internal/service"
+	"go-task/internal/template"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// liveKeepAlive is how often an idle event stream sends a comment so
+// proxies do not time the connection out.
+const liveKeepAlive = 30 * time.Second
+
+// LiveController streams task changes to the pages as Server-Sent Events,
+// already rendered as task table rows for the htmx SSE extension.
+type LiveController struct {
+	broker *pubsub.Broker
+}
+
+func NewLiveController(broker *pubsub.Broker) *LiveController {
+	return &LiveController{
+		broker: broker,
+	}
+}
+
+func (controller *LiveController) register(router *http.ServeMux) {
+	router.Handle("GET /events", taskHandler(controller.stream))
+}
+
+// stream sends every change of the workspace, or of one project when
+// called with ?project=ID, until the client goes away. Each change is sent
+// twice: as "task-ID", which the row of the task swaps itself with, and as
+// "task", which the table appends when it has no row for the task yet.
+// A project's stream follows the whole workspace so that a task moved to
+// another project is removed from the list it left; tasks outside the
+// project are only sent as empty "task-ID" rows, which pages without such
+// a row ignore.
+func (controller *LiveController) stream(w http.ResponseWriter, r *http.Request) error {
+	user, ok := service.UserFrom(r.Context())
+	if !ok {
+		unauthorized(w, r)
+		return nil
+	}
+	projectID, ok := optionalID(w, r.FormValue("project"))
+	if !ok {
+		return nil
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return fmt.Errorf("response writer does not support streaming")
+	}
+
+	sub := controller.broker.Subscribe(user.WorkspaceID, nil)
+	defer controller.broker.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(liveKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+		case task, ok := <-sub.C:
+			if !ok {
+				return nil
+			}
+			if err := writeTaskEvents(w, r, task, inProject(task, projectID)); err != nil {
+				return nil
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// writeTaskEvents sends the row of task; a task that is deleted, or no
+// longer shown by the stream, is sent as an empty row so the page drops it.
+func writeTaskEvents(w io.Writer, r *http.Request, task model.Task, shown bool) error {
+	shown = shown && task.DeletedAt == nil
+	var row bytes.Buffer
+	if shown {
+		if err := template.TaskRow(task).Render(r.Context(), &row); err != nil {
+			return err
+		}
+	}
+	if err := writeEvent(w, fmt.Sprintf("task-%d", task.ID), task.ID, row.String()); err != nil {
+		return err
+	}
+	if !shown {
+		return nil
+	}
+	return writeEvent(w, "task", task.ID, row.String())
+}
+
+// inProject reports whether task belongs in the list of projectID, which
+// is every task of the workspace when projectID is nil.
+func inProject(task model.Task, projectID *int64) bool {
+	if projectID == nil {
+		return true
+	}
+	return task.ProjectID != nil && *task.ProjectID == *projectID
+}
+
+func writeEvent(w io.Writer, name string, id int64, data string) error {
+	var event strings.Builder
+	fmt.Fprintf(&event, "event: %s\nid: %d\n", name, id)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&event, "data: %s\n", line)
+	}
+	event.WriteString("\n")
+	_, err := io.WriteString(w, event.String())
+	return err
+}
@@ -4,172 +4,308 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"github.com/elastic/go-elasticsearch/v8"
 	"go-task/internal/dao"
 	"go-task/internal/db"
 	"go-task/internal/elastic"
+	"go-task/internal/graph"
 	"go-task/internal/model"
+	"go-task/internal/openapi"
+	"go-task/internal/pubsub"
+	"go-task/internal/rpc"
 	"go-task/internal/service"
-	"go-task/internal/template"
+	"go-task/internal/sso"
+	"go-task/internal/webhook"
 	"go-task/pkg"
 	"go-task/pkg/request"
 	"go-task/pkg/response"
 	"log"
-	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
 )
 
-var (
-	mysqlDb     *db.MysqlDB
-	dbInst      *sql.DB
-	taskChannel chan *model.Task
-	storage     *dao.MysqlStore
-	serviceInst *service.Service
-	controller  *Controller
-	esClient    *elasticsearch.Client
-	_           *elastic.ElasticsearchSync
-	router      *http.ServeMux
+// services are what the HTTP API runs on. main backs them with MySQL and
+// Elasticsearch; tests back them with in-memory stores.
+type services struct {
+	tasks       *service.Service
+	projects    *service.ProjectService
+	comments    *service.CommentService
+	auth        *service.AuthService
+	tokens      *service.TokenService
+	webhooks    *service.WebhookService
+	idempotency *service.IdempotencyService
+	broker      *pubsub.Broker
+	// sso and ssoClient are set when single sign-on is enabled, ssoStub
+	// when the built-in stub provider is served as well.
+	sso       *service.SSOService
+	ssoClient *sso.Client
+	ssoStub   *sso.Stub
+}
+
+const defaultRetentionPeriod = 30 * 24 * time.Hour
+
+// defaultGRPCAddr is where the gRPC API listens unless GRPC_ADDR says
+// otherwise.
+const defaultGRPCAddr = ":7001"
+
+// defaultPageSize and maxPageSize bound the pages of the task list.
+const (
+	defaultPageSize = 100
+	maxPageSize     = 500
 )
 
-func init() {
-	taskChannel = make(chan *model.Task, 200)
-	log.Printf("initializing database")
-	mysqlDb = &db.MysqlDB{}
-	dbInst = mysqlDb.Init()
-	storage = dao.NewMysqlStore(dbInst, taskChannel)
+// mergePatchContentType is the media type of an RFC 7396 JSON merge patch.
+const mergePatchContentType = "application/merge-patch+json"
 
-	log.Printf("initializing task service")
-	serviceInst = service.NewService(storage)
-	log.Printf("initializing task controller")
-	controller = NewController(serviceInst)
+// ssoStubPath is where the stub identity provider is mounted when enabled.
+const ssoStubPath = "/oidc-stub"
+
+// newServices connects the services to MySQL and Elasticsearch and
+// starts their background work: the search sync, webhook deliveries and
+// trash retention. Task changes are published on taskChannel.
+func newServices(dbInst *sql.DB, taskChannel chan []*model.Task) services {
+	searchChannel := make(chan []*model.Task, 200)
+	storage := dao.NewMysqlStore(dbInst, taskChannel)
+	projectStorage := dao.NewMysqlProjectStore(dbInst)
+	userStorage := dao.NewMysqlUserStore(dbInst)
+	webhookStorage := dao.NewMysqlWebhookStore(dbInst)
 	log.Printf("initializing elasticsearch")
-	esClient = elastic.NewElasticsearch()
-	_ = elastic.NewElasticsearchSync(esClient, taskChannel, dbInst)
-	router = initRouter()
+	esClient := elastic.NewElasticsearch()
+	log.Printf("initializing elasticsearch sync")
+	searchSync := elastic.NewElasticsearchSync(esClient, searchChannel, dbInst)
+	broker := pubsub.NewBroker(taskChannel, searchChannel, searchSync.DeadLetter)
+
+	log.Printf("initializing webhook dispatcher")
+	dispatcher := webhook.NewDispatcher(webhookStorage)
+
+	log.Printf("initializing task service")
+	authService := service.NewAuthService(userStorage, dao.NewMysqlSessionStore(dbInst), dao.NewMysqlWorkspaceStore(dbInst))
+	deps := services{
+		tasks: service.NewService(storage, dao.NewMysqlEventStore(dbInst), projectStorage, elastic.NewElasticsearchSearch(esClient), dispatcher,
+			transactor{dao.NewMysqlTransactor(dbInst, taskChannel)}),
+		projects:    service.NewProjectService(projectStorage, storage),
+		comments:    service.NewCommentService(dao.NewMysqlCommentStore(dbInst), storage),
+		auth:        authService,
+		tokens:      service.NewTokenService(dao.NewMysqlAPITokenStore(dbInst), userStorage),
+		webhooks:    service.NewWebhookService(webhookStorage),
+		idempotency: service.NewIdempotencyService(dao.NewMysqlIdempotencyStore(dbInst), time.Hour),
+		broker:      broker,
+	}
+	ssoConfig, ssoStub, ssoEnabled := ssoSetup()
+	if ssoEnabled {
+		log.Printf("initializing single sign-on with %s", ssoConfig.Issuer)
+		deps.sso = service.NewSSOService(authService, dao.NewMysqlIdentityStore(dbInst), ssoConfig.WorkspaceID)
+		deps.ssoClient = sso.NewClient(ssoConfig)
+		deps.ssoStub = ssoStub
+	}
+	log.Printf("initializing trash retention")
+	_ = service.NewRetention(storage, retentionPeriod(), time.Hour)
+	return deps
 }
 
-func renderIndex(service Service) []*model.Task {
-	tasks, _ := serviceInst.FindAll()
-	return tasks
+// transactor hands the task service the stores of a MySQL transaction.
+type transactor struct {
+	mysql *dao.MysqlTransactor
 }
 
-func initRouter() *http.ServeMux {
-	log.Println("init router")
-	router := http.NewServeMux()
-	//router.Handle("/", templ.Handler(template.Index(renderIndex(serviceInst))))
-	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		tasks, _ := serviceInst.FindAll()
-		w.Header().Set("Cache-Control", "no-cache")
-		err := template.Index(tasks).Render(context.Background(), w)
+func (t transactor) Transaction(fn func(datastore service.DataStore, events service.EventStore, savepoints service.Savepoints) error) error {
+	return t.mysql.Transaction(func(tasks *dao.MysqlStore, events *dao.MysqlEventStore, savepoints *dao.MysqlSavepoints) error {
+		return fn(tasks, events, savepoints)
+	})
+}
+
+// retentionPeriod reads how long deleted tasks stay in the trash from
+// TASK_RETENTION_PERIOD, a Go duration such as "720h".
+func retentionPeriod() time.Duration {
+	value := os.Getenv("TASK_RETENTION_PERIOD")
+	if value == "" {
+		return defaultRetentionPeriod
+	}
+	period, err := time.ParseDuration(value)
+	if err != nil || period <= 0 {
+		log.Printf("invalid TASK_RETENTION_PERIOD %q, using %s", value, defaultRetentionPeriod)
+		return defaultRetentionPeriod
+	}
+	return period
+}
+
+// ssoSetup reads the OIDC_* single sign-on settings. With OIDC_STUB=true
+// the built-in stub provider is served under /oidc-stub and used as the
+// issuer unless OIDC_ISSUER points elsewhere.
+func ssoSetup() (sso.Config, *sso.Stub, bool) {
+	config, enabled := sso.ConfigFromEnv()
+	var stub *sso.Stub
+	if os.Getenv("OIDC_STUB") == "true" {
+		if config.Issuer == "" {
+			config.Issuer = "http://localhost:7000" + ssoStubPath
+		}
+		if config.ClientID == "" {
+			config.ClientID = "go-task"
+		}
+		var err error
+		stub, err = sso.NewStub(config.Issuer, config.ClientID)
 		if err != nil {
-			return
+			log.Fatal(err)
 		}
-	})
-	router.Handle("GET /{id}", taskHandler(taskByIDHandler))
-	router.HandleFunc("DELETE /{id}", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Cache-Control", "no-cache")
-		slog.Info("deleting task", "id", r.PathValue("id"))
-		dbInst.Exec("DELETE FROM tasks WHERE id = ?", r.PathValue("id"))
-		return
-	})
-	router.HandleFunc("PUT /{id}/{status}", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Cache-Control", "no-cache")
-		slog.Info("updating task", "id", r.PathValue("id"), "status", r.PathValue("status"))
-		dbInst.Exec("UPDATE tasks SET status = ? WHERE id = ?", r.PathValue("status"), r.PathValue("id"))
-		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
-		task, _ := serviceInst.FindById(id)
-		template.UpdateTask(*task).Render(context.Background(), w)
-	})
-	router.HandleFunc("PUT /{id}", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Cache-Control", "no-cache")
-		slog.Info("updating task", "id", r.PathValue("id"), "title", r.FormValue("title"))
-		dbInst.Exec("UPDATE tasks SET title = ? WHERE id = ?", r.FormValue("title"), r.PathValue("id"))
-		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
-		task, _ := serviceInst.FindById(id)
-		template.UpdateTask(*task).Render(context.Background(), w)
-	})
-	return router
+		enabled = true
+	}
+	if config.RedirectURL == "" {
+		config.RedirectURL = "http://localhost:7000" + ssoCookiePath + "/callback"
+	}
+	return config, stub, enabled
 }
 
-type HttpErr struct {
-	Err  error  `json:"error"`
-	Code int    `json:"code"`
-	Msg  string `json:"message"`
+// newRouter serves the HTTP API and the UI on deps, behind authentication,
+// Idempotency-Key handling and, when enabled, request validation.
+func newRouter(deps services) http.Handler {
+	apiDoc := apiDescription()
+	authController := NewAuthController(deps.auth, deps.tokens, deps.sso != nil)
+	return pkg.HandleError(authController.authenticate(idempotent(deps.idempotency, validateRequests(apiDoc, newMux(deps, apiDoc)))))
 }
 
-func (e HttpErr) Error() string { return e.Msg }
+func newMux(deps services, apiDoc *openapi.Document) *http.ServeMux {
+	log.Println("init router")
+	router := http.NewServeMux()
+	NewAuthController(deps.auth, deps.tokens, deps.sso != nil).register(router)
+	NewTokenController(deps.tokens).register(router)
+	NewUserController(deps.auth).register(router)
+	NewProjectController(deps.projects, deps.tasks).register(router)
+	NewBoardController(deps.tasks, deps.projects).register(router)
+	NewTaskFormController(deps.tasks, deps.projects).register(router)
+	NewLiveController(deps.broker).register(router)
+	NewWebhookController(deps.webhooks).register(router)
+	NewBatchController(deps.tasks).register(router)
+	NewOpenAPIController(apiDoc).register(router)
+	NewGraphQLController(graph.New(deps.tasks, deps.projects, deps.comments, deps.broker)).register(router)
+	if deps.sso != nil {
+		NewSSOController(deps.ssoClient, deps.sso).register(router)
+	}
+	if deps.ssoStub != nil {
+		router.Handle(ssoStubPath+"/", http.StripPrefix(ssoStubPath, deps.ssoStub))
+	}
+	router.Handle("/api/v1/tasks", NewController(deps.tasks))
+	NewCommentController(deps.comments).register(router)
+	NewTaskController(deps.tasks, deps.projects, deps.comments).register(router)
+	return router
+}
 
 type taskHandler func(w http.ResponseWriter, r *http.Request) error
 
 func (th taskHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	err := th(w, r)
+	if err := th(w, r); err != nil {
+		pkg.WriteProblem(w, pkg.ProblemFor(err))
+	}
+}
+
+// writeServiceError answers with the problem matching a service error.
+// It returns nil so handlers can return its result once the response is
+// written.
+func writeServiceError(w http.ResponseWriter, err error) error {
+	pkg.WriteProblem(w, pkg.ProblemFor(err))
+	return nil
+}
+
+// badRequest answers with a 400 problem for input that is malformed
+// before any field can be validated, such as unparsable JSON.
+func badRequest(w http.ResponseWriter, detail string) {
+	pkg.WriteProblem(w, pkg.NewProblem(http.StatusBadRequest, detail))
+}
+
+// prefersHTML reports whether the Accept header asks for text/html with a
+// higher quality than JSON. Wildcards only count towards JSON, so API
+// clients sending */* or no Accept header at all keep getting JSON.
+func prefersHTML(r *http.Request) bool {
+	var htmlQ, jsonQ float64
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			mediaType, params, _ := strings.Cut(strings.TrimSpace(mediaRange), ";")
+			q := 1.0
+			for _, param := range strings.Split(params, ";") {
+				if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+					if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+						q = parsed
+					}
+				}
+			}
+			switch strings.ToLower(strings.TrimSpace(mediaType)) {
+			case "text/html":
+				htmlQ = max(htmlQ, q)
+			case "application/json", "application/*", "*/*":
+				jsonQ = max(jsonQ, q)
+			}
+		}
+	}
+	return htmlQ > jsonQ
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) error {
+	jsonData, err := json.Marshal(v)
 	if err != nil {
-		httpErr := HttpErr{
-			Err:  err,
-			Code: http.StatusInternalServerError,
-			Msg:  "Internal Server Error",
+		return fmt.Errorf("error serialize response: %w", err)
+	}
+	w.Header().Set("Content-Type", "Application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(jsonData)
+	return err
+}
+
+func writeTasks(w http.ResponseWriter, tasks []*model.Task) error {
+	tasksRS := make([]*response.TaskResponse, 0, len(tasks))
+	for _, t := range tasks {
+		task, err := mapToTaskRes(*t)
+		if err != nil {
+			return err
 		}
-		httpErrJson, _ := json.Marshal(httpErr)
-		w.Header().Set("Content-Type", "Application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		_, _ = w.Write(httpErrJson)
+		tasksRS = append(tasksRS, task)
 	}
+	return writeJSON(w, http.StatusOK, tasksRS)
 }
+
 func main() {
+	taskChannel := make(chan []*model.Task, 200)
 	defer close(taskChannel)
+	log.Printf("initializing database")
+	dbInst := (&db.MysqlDB{}).Init()
 	defer func(dbInst *sql.DB) {
 		err := dbInst.Close()
 		if err != nil {
 			log.Printf("db conn closed")
 		}
 	}(dbInst)
-	log.Fatal(http.ListenAndServe(":7000", router))
+	deps := newServices(dbInst, taskChannel)
+	go serveGRPC(rpc.NewServer(rpc.NewTaskServer(deps.tasks, deps.projects, deps.broker), deps.tokens))
+	log.Fatal(http.ListenAndServe(":7000", newRouter(deps)))
 }
 
-func taskByIDHandler(w http.ResponseWriter, r *http.Request) error {
-	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		log.Printf(err.Error())
-		return err
-	}
-	task, err := serviceInst.FindById(id)
-	if err != nil {
-		if errors.Is(err, pkg.ErrNotFound) {
-			w.WriteHeader(http.StatusNotFound)
-			log.Printf(err.Error())
-			return err
-		}
-		log.Printf(err.Error())
-		return err
-	}
-	taskRs, err := mapToTaskRes(*task)
-	if err != nil {
-		log.Printf(err.Error())
-		return err
+// serveGRPC serves the gRPC API next to the HTTP one, on its own port so
+// neither needs to multiplex HTTP/2 by content type.
+func serveGRPC(grpcServer *grpc.Server) {
+	addr := os.Getenv("GRPC_ADDR")
+	if addr == "" {
+		addr = defaultGRPCAddr
 	}
-	jsonData, err := json.Marshal(taskRs)
-	if err != nil {
-		return fmt.Errorf("error serialize task: %w", err)
-	}
-
-	w.Header().Set("Content-Type", "Application/jsonData")
-	_, err = w.Write([]byte(jsonData))
+	listener, err := net.Listen("tcp", addr)
 	if err != nil {
-		return err
+		log.Fatal(err)
 	}
-	return nil
+	log.Printf("serving grpc on %s", addr)
+	log.Fatal(grpcServer.Serve(listener))
 }
 
 type Service interface {
-	Create(task *model.Task) (*model.Task, error)
-	Update(task model.Task, id int64) (*model.Task, error)
-	Delete(int64) error
-	FindAll() ([]*model.Task, error)
-	FindById(int64) (*model.Task, error)
+	Create(ctx context.Context, task *model.Task) (*model.Task, error)
+	Update(ctx context.Context, task model.Task, id int64) (*model.Task, error)
+	Delete(ctx context.Context, id int64) error
+	FindAll(ctx context.Context) ([]*model.Task, error)
+	FindPage(ctx context.Context, after *model.TaskCursor, limit int) ([]*model.Task, *model.TaskCursor, error)
+	FindById(ctx context.Context, id int64) (*model.Task, error)
 }
 
 type Controller struct {
@@ -185,54 +321,73 @@ func NewController(service Service) *Controller {
 func (controller *Controller) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		var tasksRS []*response.TaskResponse
-		tasks, _ := controller.service.FindAll()
-		for _, t := range tasks {
-			task, err := mapToTaskRes(*t)
-			if err != nil {
-				return
-			}
-			tasksRS = append(tasksRS, task)
-		}
-		json, _ := json.Marshal(tasksRS)
-		w.Header().Set("Content-Type", "Application/json")
-		w.Write(json)
-		return
+		taskHandler(controller.list).ServeHTTP(w, r)
 	case http.MethodPost:
-		var taskReq request.TaskRequest
-		err := json.NewDecoder(r.Body).Decode(&taskReq)
+		taskHandler(controller.create).ServeHTTP(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		pkg.WriteProblem(w, pkg.NewProblem(http.StatusMethodNotAllowed, ""))
+	}
+}
 
+// list answers with every task, or with one page of tasks when limit or
+// after is given. A Link header then points at the next page, if any.
+func (controller *Controller) list(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+	if !query.Has("limit") && !query.Has("after") {
+		tasks, err := controller.service.FindAll(r.Context())
 		if err != nil {
-			w.Write([]byte(err.Error()))
-			fmt.Errorf(err.Error())
-			return
+			return writeServiceError(w, err)
 		}
+		return writeTasks(w, tasks)
+	}
 
-		task, err := mapToTask(taskReq)
-		if err != nil {
-			http.Error(w, err.Error(), 400)
-			fmt.Errorf(err.Error())
-			return
+	limit := defaultPageSize
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxPageSize {
+			return writeServiceError(w, &pkg.ValidationError{Field: "limit", Message: fmt.Sprintf("limit must be between 1 and %d", maxPageSize)})
 		}
-		createdTask, err := controller.service.Create(task)
-		if err != nil {
-			w.Write([]byte(err.Error()))
-			log.Printf(err.Error())
-			return
-		}
-		res, _ := mapToTaskRes(*createdTask)
-		jsonData, err := json.Marshal(res)
+		limit = n
+	}
+	var after *model.TaskCursor
+	if token := query.Get("after"); token != "" {
+		var err error
+		after, err = model.ParseTaskCursor(token)
 		if err != nil {
-			w.Write([]byte(err.Error()))
-			fmt.Errorf(err.Error())
-			return
+			return writeServiceError(w, err)
 		}
-		w.Header().Set("Content-Type", "Application/jsonData")
-		w.Write([]byte(jsonData))
-		return
-	default:
-		w.Write([]byte("method not allowed"))
 	}
+	tasks, next, err := controller.service.FindPage(r.Context(), after, limit)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	if next != nil {
+		nextPage := url.URL{Path: r.URL.Path, RawQuery: url.Values{"limit": {strconv.Itoa(limit)}, "after": {next.String()}}.Encode()}
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, nextPage.String()))
+	}
+	return writeTasks(w, tasks)
+}
+
+func (controller *Controller) create(w http.ResponseWriter, r *http.Request) error {
+	var taskReq request.TaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&taskReq); err != nil {
+		badRequest(w, err.Error())
+		return nil
+	}
+	task, err := mapToTask(taskReq)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	createdTask, err := controller.service.Create(r.Context(), task)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	res, err := mapToTaskRes(*createdTask)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusCreated, res)
 }
 
 func mapToTask(req request.TaskRequest) (*model.Task, error) {
@@ -240,19 +395,74 @@ func mapToTask(req request.TaskRequest) (*model.Task, error) {
 	if err != nil {
 		return nil, err
 	}
+	task.Labels = req.Labels
+	task.DueAt = req.DueAt
+	task.ProjectID = req.ProjectID
+	if req.Recurrence != "" {
+		task.Recurrence, err = model.ParseRecurrence(req.Recurrence)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	return task, nil
 }
 
+func mapToTaskPatch(req request.TaskPatchRequest) (model.TaskPatch, error) {
+	patch := model.TaskPatch{
+		Title:     req.Title,
+		Content:   req.Content,
+		Status:    req.Status,
+		Labels:    req.Labels,
+		DueAt:     req.DueAt,
+		ProjectID: req.ProjectID,
+	}
+	if req.Recurrence.Set {
+		patch.Recurrence.Set = true
+		if req.Recurrence.Value != "" {
+			recurrence, err := model.ParseRecurrence(req.Recurrence.Value)
+			if err != nil {
+				return model.TaskPatch{}, err
+			}
+			patch.Recurrence.Value = recurrence
+		}
+	}
+	return patch, nil
+}
+
 func mapToTaskRes(task model.Task) (*response.TaskResponse, error) {
 	res := &response.TaskResponse{
 		ID:        task.ID,
 		Title:     task.Title,
 		Content:   task.Content,
 		Status:    string(task.Status),
+		Labels:    task.Labels,
+		DueAt:     task.DueAt,
+		ProjectID: task.ProjectID,
+		Rank:      task.Rank,
+		CreatedBy: task.CreatedBy,
+		UpdatedBy: task.UpdatedBy,
 		CreatedAt: task.CreatedAt,
 		UpdatedAt: task.UpdatedAt,
 	}
+	if task.Recurrence != nil {
+		res.Recurrence = task.Recurrence.String()
+	}
 
 	return res, nil
 }
+
+func mapToTaskEventRes(event model.TaskEvent) *response.TaskEventResponse {
+	changes := make(map[string]response.FieldChangeResponse, len(event.Changes))
+	for field, change := range event.Changes {
+		changes[field] = response.FieldChangeResponse{Before: change.Before, After: change.After}
+	}
+	return &response.TaskEventResponse{
+		ID:        event.ID,
+		TaskID:    event.TaskID,
+		Actor:     event.Actor,
+		Operation: string(event.Operation),
+		Changes:   changes,
+		CreatedAt: event.CreatedAt,
+	}
+}
@@ -0,0 +1,673 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/internal/pubsub"
+	"go-task/internal/service"
+	"go-task/pkg"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memDB keeps every table of the app in memory, so the router can be
+// served on the real services without MySQL. Lookups are scoped by
+// workspace as the MySQL stores do. Each store is a view of it.
+type memDB struct {
+	mu          sync.Mutex
+	nextID      int64
+	tasks       map[int64]*model.Task
+	events      []*model.TaskEvent
+	projects    map[int64]*model.Project
+	comments    map[int64]*model.Comment
+	users       map[int64]*model.User
+	workspaces  map[int64]*model.Workspace
+	sessions    map[string]*model.Session
+	tokens      map[int64]*model.APIToken
+	webhooks    map[int64]*model.Webhook
+	idempotency map[string]*model.IdempotencyRecord
+	savepoints  map[string]memSnapshot
+}
+
+func newMemDB() *memDB {
+	return &memDB{
+		tasks:       map[int64]*model.Task{},
+		projects:    map[int64]*model.Project{},
+		comments:    map[int64]*model.Comment{},
+		users:       map[int64]*model.User{},
+		workspaces:  map[int64]*model.Workspace{},
+		sessions:    map[string]*model.Session{},
+		tokens:      map[int64]*model.APIToken{},
+		webhooks:    map[int64]*model.Webhook{},
+		idempotency: map[string]*model.IdempotencyRecord{},
+	}
+}
+
+// newMemServices returns the services of the app over db. Task changes
+// are not published since nothing in memory feeds the broker.
+func newMemServices(db *memDB) services {
+	tasks := memTasks{db}
+	users := memUsers{db}
+	auth := service.NewAuthService(users, memSessions{db}, memWorkspaces{db})
+	return services{
+		tasks:       service.NewService(tasks, memEvents{db}, memProjects{db}, memIndex{}, memNotifier{}, tasks),
+		projects:    service.NewProjectService(memProjects{db}, tasks),
+		comments:    service.NewCommentService(memComments{db}, tasks),
+		auth:        auth,
+		tokens:      service.NewTokenService(memTokens{db}, users),
+		webhooks:    service.NewWebhookService(memWebhooks{db}),
+		idempotency: service.NewIdempotencyService(memIdempotency{db}, time.Hour),
+		broker:      pubsub.NewBroker(make(chan []*model.Task), make(chan []*model.Task, 1), func([]*model.Task) {}),
+	}
+}
+
+// signUp adds a user with a workspace of their own to db and issues them
+// an API token with scope.
+func signUp(t *testing.T, db *memDB, deps services, username string, scope model.TokenScope) (*model.User, string) {
+	t.Helper()
+	workspace, _ := memWorkspaces{db}.Save(&model.Workspace{Name: username})
+	user, _ := memUsers{db}.Save(&model.User{WorkspaceID: workspace.ID, Username: username, Role: model.RoleAdmin})
+	_, token, err := deps.tokens.Issue(user, "test", scope, nil)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	return user, token
+}
+
+func (db *memDB) id() int64 {
+	db.nextID++
+	return db.nextID
+}
+
+// memSnapshot is the task data of a memDB at one point in time.
+type memSnapshot struct {
+	tasks  map[int64]*model.Task
+	events []*model.TaskEvent
+}
+
+func (db *memDB) snapshot() memSnapshot {
+	tasks := make(map[int64]*model.Task, len(db.tasks))
+	for id, task := range db.tasks {
+		copied := *task
+		tasks[id] = &copied
+	}
+	return memSnapshot{tasks: tasks, events: slices.Clone(db.events)}
+}
+
+func (db *memDB) restore(snapshot memSnapshot) {
+	db.tasks, db.events = snapshot.tasks, snapshot.events
+}
+
+type memTasks struct{ db *memDB }
+
+func (store memTasks) Save(task *model.Task) (*model.Task, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	if task.ID == 0 {
+		task.ID = store.db.id()
+	}
+	stored := *task
+	store.db.tasks[task.ID] = &stored
+	return task, nil
+}
+
+func (store memTasks) FindById(workspaceID int64, id int64) (*model.Task, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	task, ok := store.db.tasks[id]
+	if !ok || task.WorkspaceID != workspaceID {
+		return nil, fmt.Errorf("task %d: %w", id, pkg.ErrNotFound)
+	}
+	found := *task
+	return &found, nil
+}
+
+func (store memTasks) FindAll(workspaceID int64) ([]*model.Task, error) {
+	return store.find(func(task *model.Task) bool {
+		return task.WorkspaceID == workspaceID && task.DeletedAt == nil
+	}), nil
+}
+
+func (store memTasks) FindPage(workspaceID int64, after *model.TaskCursor, limit int) ([]*model.Task, error) {
+	tasks := store.find(func(task *model.Task) bool {
+		return task.WorkspaceID == workspaceID && task.DeletedAt == nil &&
+			(after == nil || task.Rank > after.Rank || (task.Rank == after.Rank && task.ID > after.ID))
+	})
+	return tasks[:min(limit, len(tasks))], nil
+}
+
+func (store memTasks) FindByProject(workspaceID int64, projectID int64) ([]*model.Task, error) {
+	return store.FindByProjects(workspaceID, []int64{projectID})
+}
+
+func (store memTasks) FindByIds(workspaceID int64, ids []int64) ([]*model.Task, error) {
+	return store.find(func(task *model.Task) bool {
+		return task.WorkspaceID == workspaceID && slices.Contains(ids, task.ID)
+	}), nil
+}
+
+func (store memTasks) FindByProjects(workspaceID int64, projectIDs []int64) ([]*model.Task, error) {
+	return store.find(func(task *model.Task) bool {
+		return task.WorkspaceID == workspaceID && task.DeletedAt == nil &&
+			task.ProjectID != nil && slices.Contains(projectIDs, *task.ProjectID)
+	}), nil
+}
+
+func (store memTasks) LastRank(workspaceID int64) (float64, error) {
+	var rank float64
+	for _, task := range store.find(func(task *model.Task) bool { return task.WorkspaceID == workspaceID }) {
+		rank = max(rank, task.Rank)
+	}
+	return rank, nil
+}
+
+func (store memTasks) Rebalance(workspaceID int64) error {
+	tasks := store.find(func(task *model.Task) bool { return task.WorkspaceID == workspaceID })
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	for i, task := range tasks {
+		store.db.tasks[task.ID].Rank = float64(i + 1)
+	}
+	return nil
+}
+
+func (store memTasks) FindDeleted(workspaceID int64) ([]*model.Task, error) {
+	return store.find(func(task *model.Task) bool {
+		return task.WorkspaceID == workspaceID && task.DeletedAt != nil
+	}), nil
+}
+
+func (store memTasks) Purge(cutoff time.Time) (int64, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	var purged int64
+	for id, task := range store.db.tasks {
+		if task.DeletedAt != nil && task.DeletedAt.Before(cutoff) {
+			delete(store.db.tasks, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (store memTasks) Reindex(*model.Task) {}
+
+// find returns copies of the matching tasks in rank order.
+func (store memTasks) find(match func(task *model.Task) bool) []*model.Task {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	var tasks []*model.Task
+	for _, task := range store.db.tasks {
+		if match(task) {
+			found := *task
+			tasks = append(tasks, &found)
+		}
+	}
+	slices.SortFunc(tasks, func(a, b *model.Task) int {
+		if a.Rank != b.Rank {
+			if a.Rank < b.Rank {
+				return -1
+			}
+			return 1
+		}
+		return int(a.ID - b.ID)
+	})
+	return tasks
+}
+
+// Transaction runs fn against the stores themselves and undoes its task
+// writes when it fails.
+func (store memTasks) Transaction(fn func(datastore service.DataStore, events service.EventStore, savepoints service.Savepoints) error) error {
+	store.db.mu.Lock()
+	snapshot := store.db.snapshot()
+	store.db.savepoints = map[string]memSnapshot{}
+	store.db.mu.Unlock()
+	if err := fn(store, memEvents(store), store); err != nil {
+		store.db.mu.Lock()
+		store.db.restore(snapshot)
+		store.db.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (store memTasks) Savepoint(name string) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	store.db.savepoints[name] = store.db.snapshot()
+	return nil
+}
+
+func (store memTasks) RollbackTo(name string) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	snapshot, ok := store.db.savepoints[name]
+	if !ok {
+		return &pkg.TaskError{Message: "Failed to roll back to savepoint", Err: fmt.Errorf("savepoint %q is not set", name)}
+	}
+	store.db.restore(snapshot)
+	store.db.savepoints[name] = store.db.snapshot()
+	return nil
+}
+
+type memEvents struct{ db *memDB }
+
+func (store memEvents) Append(event *model.TaskEvent) (*model.TaskEvent, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	event.ID = store.db.id()
+	store.db.events = append(store.db.events, event)
+	return event, nil
+}
+
+func (store memEvents) FindByTaskId(workspaceID int64, taskID int64) ([]*model.TaskEvent, error) {
+	return store.FindByTaskIds(workspaceID, []int64{taskID})
+}
+
+func (store memEvents) FindByTaskIds(workspaceID int64, taskIDs []int64) ([]*model.TaskEvent, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	var events []*model.TaskEvent
+	for _, event := range store.db.events {
+		if event.WorkspaceID == workspaceID && slices.Contains(taskIDs, event.TaskID) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+type memProjects struct{ db *memDB }
+
+func (store memProjects) Save(project *model.Project) (*model.Project, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	if project.ID == 0 {
+		project.ID = store.db.id()
+	}
+	stored := *project
+	store.db.projects[project.ID] = &stored
+	return project, nil
+}
+
+func (store memProjects) FindById(workspaceID int64, id int64) (*model.Project, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	project, ok := store.db.projects[id]
+	if !ok || project.WorkspaceID != workspaceID {
+		return nil, fmt.Errorf("project %d: %w", id, pkg.ErrNotFound)
+	}
+	found := *project
+	return &found, nil
+}
+
+func (store memProjects) FindByIds(workspaceID int64, ids []int64) ([]*model.Project, error) {
+	var found []*model.Project
+	for _, id := range ids {
+		if project, err := store.FindById(workspaceID, id); err == nil {
+			found = append(found, project)
+		}
+	}
+	return found, nil
+}
+
+func (store memProjects) FindAll(workspaceID int64, includeArchived bool) ([]*model.Project, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	var found []*model.Project
+	for _, id := range slices.Sorted(maps.Keys(store.db.projects)) {
+		if project := store.db.projects[id]; project.WorkspaceID == workspaceID && (includeArchived || !project.Archived) {
+			copied := *project
+			found = append(found, &copied)
+		}
+	}
+	return found, nil
+}
+
+func (store memProjects) CountByStatus(workspaceID int64) (map[int64]model.StatusCounts, error) {
+	tasks, _ := memTasks(store).FindAll(workspaceID)
+	counts := map[int64]model.StatusCounts{}
+	for _, task := range tasks {
+		if task.ProjectID == nil {
+			continue
+		}
+		if counts[*task.ProjectID] == nil {
+			counts[*task.ProjectID] = model.StatusCounts{}
+		}
+		counts[*task.ProjectID][task.Status]++
+	}
+	return counts, nil
+}
+
+type memComments struct{ db *memDB }
+
+func (store memComments) Save(comment *model.Comment) (*model.Comment, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	if comment.ID == 0 {
+		comment.ID = store.db.id()
+	}
+	stored := *comment
+	store.db.comments[comment.ID] = &stored
+	return comment, nil
+}
+
+func (store memComments) FindById(workspaceID int64, id int64) (*model.Comment, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	comment, ok := store.db.comments[id]
+	if !ok || comment.WorkspaceID != workspaceID {
+		return nil, fmt.Errorf("comment %d: %w", id, pkg.ErrNotFound)
+	}
+	found := *comment
+	return &found, nil
+}
+
+func (store memComments) FindByTaskId(workspaceID int64, taskID int64) ([]*model.Comment, error) {
+	return store.FindByTaskIds(workspaceID, []int64{taskID})
+}
+
+func (store memComments) FindByTaskIds(workspaceID int64, taskIDs []int64) ([]*model.Comment, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	var found []*model.Comment
+	for _, id := range slices.Sorted(maps.Keys(store.db.comments)) {
+		if comment := store.db.comments[id]; comment.WorkspaceID == workspaceID && slices.Contains(taskIDs, comment.TaskID) {
+			copied := *comment
+			found = append(found, &copied)
+		}
+	}
+	return found, nil
+}
+
+func (store memComments) Delete(workspaceID int64, id int64) error {
+	if _, err := store.FindById(workspaceID, id); err != nil {
+		return err
+	}
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	delete(store.db.comments, id)
+	return nil
+}
+
+type memUsers struct{ db *memDB }
+
+func (store memUsers) Save(user *model.User) (*model.User, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	if user.ID == 0 {
+		user.ID = store.db.id()
+	}
+	stored := *user
+	store.db.users[user.ID] = &stored
+	return user, nil
+}
+
+func (store memUsers) FindById(id int64) (*model.User, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	user, ok := store.db.users[id]
+	if !ok {
+		return nil, fmt.Errorf("user %d: %w", id, pkg.ErrNotFound)
+	}
+	found := *user
+	return &found, nil
+}
+
+func (store memUsers) FindByUsername(username string) (*model.User, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	for _, user := range store.db.users {
+		if strings.EqualFold(user.Username, username) {
+			found := *user
+			return &found, nil
+		}
+	}
+	return nil, fmt.Errorf("user %q: %w", username, pkg.ErrNotFound)
+}
+
+func (store memUsers) FindAll(workspaceID int64) ([]*model.User, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	var found []*model.User
+	for _, id := range slices.Sorted(maps.Keys(store.db.users)) {
+		if user := store.db.users[id]; user.WorkspaceID == workspaceID {
+			copied := *user
+			found = append(found, &copied)
+		}
+	}
+	return found, nil
+}
+
+type memWorkspaces struct{ db *memDB }
+
+func (store memWorkspaces) Save(workspace *model.Workspace) (*model.Workspace, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	if workspace.ID == 0 {
+		workspace.ID = store.db.id()
+	}
+	stored := *workspace
+	store.db.workspaces[workspace.ID] = &stored
+	return workspace, nil
+}
+
+func (store memWorkspaces) FindById(id int64) (*model.Workspace, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	workspace, ok := store.db.workspaces[id]
+	if !ok {
+		return nil, fmt.Errorf("workspace %d: %w", id, pkg.ErrNotFound)
+	}
+	found := *workspace
+	return &found, nil
+}
+
+type memSessions struct{ db *memDB }
+
+func (store memSessions) Save(session *model.Session) (*model.Session, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	stored := *session
+	store.db.sessions[session.ID] = &stored
+	return session, nil
+}
+
+func (store memSessions) FindById(id string) (*model.Session, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	session, ok := store.db.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("session: %w", pkg.ErrNotFound)
+	}
+	found := *session
+	return &found, nil
+}
+
+func (store memSessions) Delete(id string) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	delete(store.db.sessions, id)
+	return nil
+}
+
+type memTokens struct{ db *memDB }
+
+func (store memTokens) Save(token *model.APIToken) (*model.APIToken, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	if token.ID == 0 {
+		token.ID = store.db.id()
+	}
+	stored := *token
+	store.db.tokens[token.ID] = &stored
+	return token, nil
+}
+
+func (store memTokens) FindByHash(hash string) (*model.APIToken, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	for _, token := range store.db.tokens {
+		if token.TokenHash == hash {
+			found := *token
+			return &found, nil
+		}
+	}
+	return nil, fmt.Errorf("api token: %w", pkg.ErrNotFound)
+}
+
+func (store memTokens) FindByUserId(userID int64) ([]*model.APIToken, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	var found []*model.APIToken
+	for _, id := range slices.Sorted(maps.Keys(store.db.tokens)) {
+		if token := store.db.tokens[id]; token.UserID == userID {
+			copied := *token
+			found = append(found, &copied)
+		}
+	}
+	return found, nil
+}
+
+func (store memTokens) Revoke(userID int64, id int64, at time.Time) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	token, ok := store.db.tokens[id]
+	if !ok || token.UserID != userID {
+		return fmt.Errorf("api token %d: %w", id, pkg.ErrNotFound)
+	}
+	token.RevokedAt = &at
+	return nil
+}
+
+func (store memTokens) Touch(id int64, at time.Time) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	if token, ok := store.db.tokens[id]; ok {
+		token.LastUsedAt = &at
+	}
+	return nil
+}
+
+type memWebhooks struct{ db *memDB }
+
+func (store memWebhooks) Save(webhook *model.Webhook) (*model.Webhook, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	if webhook.ID == 0 {
+		webhook.ID = store.db.id()
+	}
+	stored := *webhook
+	store.db.webhooks[webhook.ID] = &stored
+	return webhook, nil
+}
+
+func (store memWebhooks) FindById(workspaceID int64, id int64) (*model.Webhook, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	webhook, ok := store.db.webhooks[id]
+	if !ok || webhook.WorkspaceID != workspaceID {
+		return nil, fmt.Errorf("webhook %d: %w", id, pkg.ErrNotFound)
+	}
+	found := *webhook
+	return &found, nil
+}
+
+func (store memWebhooks) FindAll(workspaceID int64) ([]*model.Webhook, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	var found []*model.Webhook
+	for _, id := range slices.Sorted(maps.Keys(store.db.webhooks)) {
+		if webhook := store.db.webhooks[id]; webhook.WorkspaceID == workspaceID {
+			copied := *webhook
+			found = append(found, &copied)
+		}
+	}
+	return found, nil
+}
+
+func (store memWebhooks) Delete(workspaceID int64, id int64) error {
+	if _, err := store.FindById(workspaceID, id); err != nil {
+		return err
+	}
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	delete(store.db.webhooks, id)
+	return nil
+}
+
+func (store memWebhooks) FindDeliveries(int64, int) ([]*model.WebhookDelivery, error) {
+	return nil, nil
+}
+
+type memIdempotency struct{ db *memDB }
+
+func idempotencyKey(userID int64, key string) string {
+	return fmt.Sprintf("%d/%s", userID, key)
+}
+
+func (store memIdempotency) Reserve(record *model.IdempotencyRecord) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	if _, ok := store.db.idempotency[idempotencyKey(record.UserID, record.Key)]; ok {
+		return fmt.Errorf("idempotency key %q: %w", record.Key, pkg.ErrConflict)
+	}
+	stored := *record
+	store.db.idempotency[idempotencyKey(record.UserID, record.Key)] = &stored
+	return nil
+}
+
+func (store memIdempotency) Find(userID int64, key string) (*model.IdempotencyRecord, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	record, ok := store.db.idempotency[idempotencyKey(userID, key)]
+	if !ok {
+		return nil, fmt.Errorf("idempotency key %q: %w", key, pkg.ErrNotFound)
+	}
+	found := *record
+	return &found, nil
+}
+
+// Complete stores the response the request was answered with.
+func (store memIdempotency) Complete(record *model.IdempotencyRecord) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	if stored, ok := store.db.idempotency[idempotencyKey(record.UserID, record.Key)]; ok {
+		stored.StatusCode, stored.Header, stored.Body = record.StatusCode, record.Header, record.Body
+	}
+	return nil
+}
+
+func (store memIdempotency) Delete(userID int64, key string) error {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	delete(store.db.idempotency, idempotencyKey(userID, key))
+	return nil
+}
+
+func (store memIdempotency) Purge(cutoff time.Time) (int64, error) {
+	store.db.mu.Lock()
+	defer store.db.mu.Unlock()
+	var purged int64
+	for key, record := range store.db.idempotency {
+		if record.ExpiresAt.Before(cutoff) {
+			delete(store.db.idempotency, key)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+type memIndex struct{}
+
+func (memIndex) Search(context.Context, int64, *int64, string) ([]int64, error) {
+	return nil, nil
+}
+
+type memNotifier struct{}
+
+func (memNotifier) TaskChanged(*model.TaskEvent, *model.Task) {}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-task/internal/openapi"
+	"go-task/internal/template"
+	"go-task/pkg"
+	"go-task/pkg/request"
+	"go-task/pkg/response"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+)
+
+const (
+	openAPIPath = "/api/openapi.json"
+	apiDocsPath = "/api/docs"
+)
+
+type OpenAPIController struct {
+	spec   []byte
+	assets fs.FS
+}
+
+func NewOpenAPIController(doc *openapi.Document) *OpenAPIController {
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		log.Fatal(err)
+	}
+	assets, err := fs.Sub(template.SwaggerUI, "swagger-ui")
+	if err != nil {
+		log.Fatal(err)
+	}
+	return &OpenAPIController{
+		spec:   spec,
+		assets: assets,
+	}
+}
+
+func (controller *OpenAPIController) register(router *http.ServeMux) {
+	router.Handle("GET /api/openapi.json", taskHandler(controller.document))
+	router.Handle("GET /api/docs", taskHandler(controller.viewer))
+	router.Handle("GET /api/docs/", http.StripPrefix(apiDocsPath, http.FileServerFS(controller.assets)))
+}
+
+func (controller *OpenAPIController) document(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	_, err := w.Write(controller.spec)
+	return err
+}
+
+func (controller *OpenAPIController) viewer(w http.ResponseWriter, r *http.Request) error {
+	return template.APIDocs(openAPIPath, apiDocsPath).Render(r.Context(), w)
+}
+
+// validateRequests wraps next with request validation against the API
+// description when OPENAPI_VALIDATE is true.
+func validateRequests(doc *openapi.Document, next http.Handler) http.Handler {
+	if os.Getenv("OPENAPI_VALIDATE") != "true" {
+		return next
+	}
+	log.Printf("validating api requests against %s", openAPIPath)
+	return openapi.Validate(doc, next)
+}
+
+// apiDescription describes the JSON API. The routes are listed by hand;
+// request and response schemas are generated from the types in
+// pkg/request and pkg/response.
+func apiDescription() *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:       "Tasks API",
+		Version:     "1.0.0",
+		Description: "Errors are RFC 9457 problem details.",
+	})
+	doc.Enum(pkg.TaskStatus(""), string(pkg.TODO), string(pkg.PENDING), string(pkg.COMPLETED))
+	doc.Secure("bearerAuth", &openapi.SecurityScheme{Type: "http", Scheme: "bearer"})
+	doc.Secure("sessionCookie", &openapi.SecurityScheme{Type: "apiKey", In: "cookie", Name: sessionCookieName})
+
+	query := func() []*openapi.Parameter {
+		return []*openapi.Parameter{{Name: "q", Description: "Full-text query", Required: true}}
+	}
+	routes := []openapi.Route{
+		{Method: http.MethodGet, Path: "/api/v1/tasks", ID: "listTasks", Tag: "tasks", Summary: "List the tasks of the workspace in rank order",
+			Query: []*openapi.Parameter{
+				{Name: "limit", Description: fmt.Sprintf("Page size, at most %d; the Link header points at the next page", maxPageSize)},
+				{Name: "after", Description: "Cursor of the page, taken from the Link header"},
+			},
+			Response: []response.TaskResponse{}, Status: http.StatusOK},
+		{Method: http.MethodPost, Path: "/api/v1/tasks", ID: "createTask", Tag: "tasks", Summary: "Create a task",
+			Request: request.TaskRequest{}, Response: response.TaskResponse{}, Status: http.StatusCreated},
+		{Method: http.MethodGet, Path: "/api/v1/tasks/{id}", ID: "getTask", Tag: "tasks", Summary: "Get a task",
+			Response: response.TaskResponse{}, Status: http.StatusOK},
+		{Method: http.MethodDelete, Path: "/api/v1/tasks/{id}", ID: "deleteTask", Tag: "tasks", Summary: "Move a task to the trash",
+			Status: http.StatusOK},
+		{Method: http.MethodPatch, Path: "/api/v1/tasks/{id}", ID: "patchTask", Tag: "tasks", Summary: "Change some fields of a task",
+			Request: request.TaskPatchRequest{}, RequestType: mergePatchContentType, Response: response.TaskResponse{}, Status: http.StatusOK},
+		{Method: http.MethodPost, Path: "/api/v1/tasks:batch", ID: "batchTasks", Tag: "tasks", Summary: "Run several task operations in one transaction",
+			Request: request.BatchRequest{}, Response: response.BatchResponse{}, Status: http.StatusOK},
+		{Method: http.MethodGet, Path: "/api/v1/tasks/{id}/history", ID: "taskHistory", Tag: "tasks", Summary: "List the changes of a task",
+			Response: []response.TaskEventResponse{}, Status: http.StatusOK},
+		{Method: http.MethodGet, Path: "/api/v1/tasks/trash", ID: "listTrash", Tag: "tasks", Summary: "List the deleted tasks",
+			Response: []response.TaskResponse{}, Status: http.StatusOK},
+		{Method: http.MethodGet, Path: "/api/v1/tasks/search", ID: "searchTasks", Tag: "tasks", Summary: "Search the tasks of the workspace",
+			Query: query(), Response: []response.TaskResponse{}, Status: http.StatusOK},
+		{Method: http.MethodPost, Path: "/api/v1/tasks/{id}/restore", ID: "restoreTask", Tag: "tasks", Summary: "Take a task out of the trash",
+			Response: response.TaskResponse{}, Status: http.StatusOK},
+		{Method: http.MethodPost, Path: "/api/v1/tasks/{id}/move", ID: "moveTask", Tag: "tasks", Summary: "Place a task between two others",
+			Request: request.MoveRequest{}, Response: response.TaskResponse{}, Status: http.StatusOK},
+
+		{Method: http.MethodGet, Path: "/api/v1/tasks/{id}/comments", ID: "listComments", Tag: "comments", Summary: "List the comments of a task",
+			Response: []response.CommentResponse{}, Status: http.StatusOK},
+		{Method: http.MethodPost, Path: "/api/v1/tasks/{id}/comments", ID: "createComment", Tag: "comments", Summary: "Comment on a task",
+			Request: request.CommentRequest{}, Response: response.CommentResponse{}, Status: http.StatusCreated},
+		{Method: http.MethodGet, Path: "/api/v1/tasks/{id}/comments/{commentId}", ID: "getComment", Tag: "comments", Summary: "Get a comment",
+			Response: response.CommentResponse{}, Status: http.StatusOK},
+		{Method: http.MethodPut, Path: "/api/v1/tasks/{id}/comments/{commentId}", ID: "updateComment", Tag: "comments", Summary: "Edit a comment",
+			Request: request.CommentRequest{}, Response: response.CommentResponse{}, Status: http.StatusOK},
+		{Method: http.MethodDelete, Path: "/api/v1/tasks/{id}/comments/{commentId}", ID: "deleteComment", Tag: "comments", Summary: "Delete a comment",
+			Status: http.StatusNoContent},
+
+		{Method: http.MethodGet, Path: "/api/v1/projects", ID: "listProjects", Tag: "projects", Summary: "List the projects of the workspace",
+			Response: []response.ProjectResponse{}, Status: http.StatusOK},
+		{Method: http.MethodPost, Path: "/api/v1/projects", ID: "createProject", Tag: "projects", Summary: "Create a project",
+			Request: request.ProjectRequest{}, Response: response.ProjectResponse{}, Status: http.StatusCreated},
+		{Method: http.MethodGet, Path: "/api/v1/projects/{id}", ID: "getProject", Tag: "projects", Summary: "Get a project",
+			Response: response.ProjectResponse{}, Status: http.StatusOK},
+		{Method: http.MethodPut, Path: "/api/v1/projects/{id}", ID: "updateProject", Tag: "projects", Summary: "Rename or archive a project",
+			Request: request.ProjectRequest{}, Response: response.ProjectResponse{}, Status: http.StatusOK},
+		{Method: http.MethodGet, Path: "/api/v1/projects/{id}/tasks", ID: "listProjectTasks", Tag: "projects", Summary: "List the tasks of a project",
+			Response: []response.TaskResponse{}, Status: http.StatusOK},
+		{Method: http.MethodGet, Path: "/api/v1/projects/{id}/search", ID: "searchProjectTasks", Tag: "projects", Summary: "Search the tasks of a project",
+			Query: query(), Response: []response.TaskResponse{}, Status: http.StatusOK},
+
+		{Method: http.MethodGet, Path: "/api/v1/webhooks", ID: "listWebhooks", Tag: "webhooks", Summary: "List the webhooks of the workspace",
+			Response: []response.WebhookResponse{}, Status: http.StatusOK},
+		{Method: http.MethodPost, Path: "/api/v1/webhooks", ID: "createWebhook", Tag: "webhooks", Summary: "Subscribe a URL to task events",
+			Request: request.WebhookRequest{}, Response: response.WebhookResponse{}, Status: http.StatusCreated},
+		{Method: http.MethodDelete, Path: "/api/v1/webhooks/{id}", ID: "deleteWebhook", Tag: "webhooks", Summary: "Delete a webhook",
+			Status: http.StatusNoContent},
+		{Method: http.MethodGet, Path: "/api/v1/webhooks/{id}/deliveries", ID: "listWebhookDeliveries", Tag: "webhooks", Summary: "List the latest deliveries of a webhook",
+			Response: []response.WebhookDeliveryResponse{}, Status: http.StatusOK},
+	}
+	for _, route := range routes {
+		doc.Add(route)
+	}
+	return doc
+}
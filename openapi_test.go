@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Every operation of the API description is served by the handler
+// registered for its path, rather than by the page handler at "/" or a
+// route that only happens to match.
+func TestDescribedRoutesResolve(t *testing.T) {
+	doc := apiDescription()
+	mux := newMux(newMemServices(newMemDB()), doc)
+	params := regexp.MustCompile(`\{[^}]+\}`)
+	for path, item := range doc.Paths {
+		for method := range item {
+			method = strings.ToUpper(method)
+			req := httptest.NewRequest(method, params.ReplaceAllString(path, "1"), nil)
+			_, pattern := mux.Handler(req)
+			if i := strings.Index(pattern, "/"); i >= 0 {
+				pattern = pattern[i:]
+			}
+			if params.ReplaceAllString(pattern, "1") != req.URL.Path {
+				t.Errorf("%s %s resolves to %q", method, path, pattern)
+			}
+		}
+	}
+}
+
+// The docs page loads Swagger UI from this server, without a token.
+func TestAPIDocsServeLocalAssets(t *testing.T) {
+	api := newTestAPI(t)
+	res, err := http.Get(api.server.URL + apiDocsPath)
+	if err != nil {
+		t.Fatalf("GET %s: %v", apiDocsPath, err)
+	}
+	page, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: status %d", apiDocsPath, res.StatusCode)
+	}
+
+	assets := regexp.MustCompile(`(?:href|src)="([^"]+)"`).FindAllStringSubmatch(string(page), -1)
+	if len(assets) != 2 {
+		t.Fatalf("page links %v, want the stylesheet and the bundle", assets)
+	}
+	for _, asset := range assets {
+		path := asset[1]
+		if !strings.HasPrefix(path, apiDocsPath+"/") {
+			t.Errorf("page loads %s, want a path under %s/", path, apiDocsPath)
+			continue
+		}
+		res, err := http.Get(api.server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK || res.ContentLength < 100_000 {
+			t.Errorf("GET %s: status %d, %d bytes", path, res.StatusCode, res.ContentLength)
+		}
+	}
+}
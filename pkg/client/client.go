@@ -0,0 +1,293 @@
+// Package client is a Go client of the task API. It authenticates with an
+// API token, reports failures as *Error values that follow the problem
+// details the server answers with, and retries calls that are safe to
+// repeat.
+package client
+
+import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-task/pkg"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRetries = 3
+	defaultBackoff = 200 * time.Millisecond
+	maxBackoff     = 5 * time.Second
+
+	userAgent            = "go-task-client"
+	idempotencyKeyHeader = "Idempotency-Key"
+	mergePatchType       = "application/merge-patch+json"
+)
+
+// Client calls the API of one server. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	token      string
+	httpClient *http.Client
+	retries    int
+	backoff    time.Duration
+}
+
+type Option func(*Client)
+
+// WithToken authenticates every call with an API token.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithHTTPClient sends the calls with httpClient instead of
+// http.DefaultClient, for example to set a timeout.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries sets how often a failed call is repeated and how long to
+// wait before the first repetition; the wait doubles with every further
+// one. Zero retries turns retrying off.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// New returns a client of the server at baseURL, such as
+// "http://localhost:7000".
+func New(baseURL string, options ...Option) (*Client, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base url: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base url %q: scheme must be http or https", baseURL)
+	}
+	c := &Client{
+		baseURL:    parsed,
+		httpClient: http.DefaultClient,
+		retries:    defaultRetries,
+		backoff:    defaultBackoff,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c, nil
+}
+
+// call is one request to the API. Calls that change state carry an
+// idempotency key, which the server uses to answer a repeated call with
+// the stored response, so every call can be retried.
+type call struct {
+	method      string
+	path        string
+	query       url.Values
+	body        any
+	contentType string
+}
+
+// do sends req and decodes a successful response into out, unless out is
+// nil. Failed calls are retried when the failure is likely temporary:
+// network errors and 429, 502, 503 and 504 responses.
+func (c *Client) do(ctx context.Context, req call, out any) (http.Header, error) {
+	var body []byte
+	if req.body != nil {
+		var err error
+		body, err = json.Marshal(req.body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var key string
+	if req.method != http.MethodGet {
+		key = newIdempotencyKey()
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, req, body, key)
+		var retryAfter time.Duration
+		if err == nil {
+			if !retryable(resp.StatusCode) || attempt >= c.retries {
+				return resp.Header, decode(resp, out)
+			}
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+			drain(resp)
+		} else if ctx.Err() != nil || attempt >= c.retries {
+			return nil, err
+		}
+
+		wait := max(c.wait(attempt), retryAfter)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, req call, body []byte, key string) (*http.Response, error) {
+	target := c.baseURL.JoinPath(req.path)
+	target.RawQuery = req.query.Encode()
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, target.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("User-Agent", userAgent)
+	if body != nil {
+		contentType := req.contentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	if key != "" {
+		httpReq.Header.Set(idempotencyKeyHeader, key)
+	}
+	if c.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return c.httpClient.Do(httpReq)
+}
+
+// wait is the backoff before retry attempt+1, with jitter so clients that
+// failed together do not retry together.
+func (c *Client) wait(attempt int) time.Duration {
+	backoff := min(c.backoff<<attempt, maxBackoff)
+	if backoff <= 0 {
+		return 0
+	}
+	return backoff/2 + rand.N(backoff/2+1)
+}
+
+func decode(resp *http.Response, out any) error {
+	defer drain(resp)
+	if resp.StatusCode >= http.StatusBadRequest {
+		return errorFrom(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+// drain reads what is left of the body so the connection can be reused.
+func drain(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+}
+
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds; dates are
+// not used by the server and are ignored.
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return min(time.Duration(seconds)*time.Second, maxBackoff)
+}
+
+func newIdempotencyKey() string {
+	key := make([]byte, 16)
+	_, _ = crand.Read(key)
+	return hex.EncodeToString(key)
+}
+
+// nextCursor returns the cursor of the next page from a Link header, or
+// false on the last page.
+func nextCursor(header http.Header) (string, bool) {
+	for _, link := range header.Values("Link") {
+		for _, value := range strings.Split(link, ",") {
+			target, params, ok := strings.Cut(strings.TrimSpace(value), ";")
+			if !ok || !strings.Contains(params, `rel="next"`) {
+				continue
+			}
+			next, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+			if err != nil {
+				continue
+			}
+			if cursor := next.Query().Get("after"); cursor != "" {
+				return cursor, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Error is a problem the server answered a call with. It matches the error
+// classes of pkg by status, so a caller checks errors.Is(err,
+// pkg.ErrNotFound) as the server does. Rejected fields are listed in
+// Errors.
+type Error struct {
+	pkg.Problem
+}
+
+func (e *Error) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("%d %s: %s", e.Status, e.Title, e.Detail)
+	}
+	return fmt.Sprintf("%d %s", e.Status, e.Title)
+}
+
+func (e *Error) Is(target error) bool {
+	switch target {
+	case pkg.ErrNotFound:
+		return e.Status == http.StatusNotFound
+	case pkg.ErrConflict:
+		return e.Status == http.StatusConflict
+	case pkg.ErrForbidden:
+		return e.Status == http.StatusForbidden
+	case pkg.ErrUnauthorized:
+		return e.Status == http.StatusUnauthorized
+	case pkg.ErrInternal:
+		return e.Status >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// IsValidation reports whether the server rejected some input fields.
+func (e *Error) IsValidation() bool {
+	return e.Type == pkg.ValidationProblemType
+}
+
+// errorFrom reads the problem of a failed response. A response that is not
+// a problem, such as one from a proxy, becomes a problem with its status.
+func errorFrom(resp *http.Response) error {
+	problem := pkg.NewProblem(resp.StatusCode, "")
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), pkg.ProblemContentType) {
+		if err := json.NewDecoder(resp.Body).Decode(problem); err != nil {
+			return errors.Join(&Error{Problem: *pkg.NewProblem(resp.StatusCode, "")}, fmt.Errorf("decoding problem: %w", err))
+		}
+	}
+	return &Error{Problem: *problem}
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go-task/pkg"
+	"go-task/pkg/request"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// The client is tested against the server's router in package main; the
+// tests here cover what that router does not answer with.
+
+// unavailable serves a 503 to every request, as an overloaded proxy would,
+// and counts the requests.
+func unavailable(t *testing.T) (*Client, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		pkg.WriteProblem(w, pkg.NewProblem(http.StatusServiceUnavailable, ""))
+	}))
+	t.Cleanup(server.Close)
+	client, err := New(server.URL, WithRetries(3, time.Millisecond))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return client, &requests
+}
+
+// Once the retries are used up the last failure is returned.
+func TestRetriesRunOut(t *testing.T) {
+	client, requests := unavailable(t)
+
+	_, err := client.Create(context.Background(), request.TaskRequest{Title: "write report", Status: pkg.TODO})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusServiceUnavailable {
+		t.Fatalf("got %v, want the 503", err)
+	}
+	if got := requests.Load(); got != 4 {
+		t.Fatalf("sent %d requests, want the call and 3 retries", got)
+	}
+}
+
+// Every attempt of a call that changes state carries the same key.
+func TestRetriesKeepIdempotencyKey(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(idempotencyKeyHeader))
+		pkg.WriteProblem(w, pkg.NewProblem(http.StatusServiceUnavailable, ""))
+	}))
+	t.Cleanup(server.Close)
+	client, _ := New(server.URL, WithRetries(2, time.Millisecond))
+
+	_, _ = client.Create(context.Background(), request.TaskRequest{Title: "write report", Status: pkg.TODO})
+	_, _ = client.Create(context.Background(), request.TaskRequest{Title: "write report", Status: pkg.TODO})
+	if len(keys) != 6 || keys[0] == "" || keys[1] != keys[0] || keys[2] != keys[0] || keys[3] == keys[0] || keys[5] != keys[3] {
+		t.Fatalf("sent keys %q, want one per call", keys)
+	}
+}
+
+// Answers that will not change on a retry are returned at once.
+func TestClientErrorsAreNotRetried(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		pkg.WriteProblem(w, pkg.ProblemFor(fmt.Errorf("task 42: %w", pkg.ErrNotFound)))
+	}))
+	t.Cleanup(server.Close)
+	client, _ := New(server.URL, WithRetries(3, time.Millisecond))
+
+	if _, err := client.Get(context.Background(), 42); !errors.Is(err, pkg.ErrNotFound) {
+		t.Fatalf("got %v, want %v", err, pkg.ErrNotFound)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Fatalf("sent %d requests, want 1", got)
+	}
+}
+
+func TestNextCursor(t *testing.T) {
+	tests := []struct {
+		name string
+		link []string
+		want string
+	}{
+		{"none", nil, ""},
+		{"next", []string{`</api/v1/tasks?after=abc&limit=2>; rel="next"`}, "abc"},
+		{"among others", []string{`</api/v1/tasks?limit=2>; rel="first", </api/v1/tasks?after=xyz&limit=2>; rel="next"`}, "xyz"},
+		{"only prev", []string{`</api/v1/tasks?after=abc&limit=2>; rel="prev"`}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{"Link": tt.link}
+			got, ok := nextCursor(header)
+			if got != tt.want || ok != (tt.want != "") {
+				t.Fatalf("got %q, %v; want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestNonProblemErrorKeepsStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write(bytes.Repeat([]byte("<p>denied</p>"), 3))
+	}))
+	t.Cleanup(server.Close)
+	client, _ := New(server.URL)
+	if _, err := client.Get(context.Background(), 1); !errors.Is(err, pkg.ErrForbidden) {
+		t.Fatalf("got %v, want %v", err, pkg.ErrForbidden)
+	}
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"context"
+	"go-task/pkg/request"
+	"go-task/pkg/response"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const defaultPageSize = 100
+
+// ListOptions tune how List fetches tasks.
+type ListOptions struct {
+	// PageSize is how many tasks one call fetches, 100 when zero.
+	PageSize int
+}
+
+// Create adds a task and returns it as stored.
+func (c *Client) Create(ctx context.Context, task request.TaskRequest) (*response.TaskResponse, error) {
+	var created response.TaskResponse
+	if _, err := c.do(ctx, call{method: http.MethodPost, path: "/api/v1/tasks", body: task}, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// Get returns the task with the given id.
+func (c *Client) Get(ctx context.Context, id int64) (*response.TaskResponse, error) {
+	var task response.TaskResponse
+	if _, err := c.do(ctx, call{method: http.MethodGet, path: taskPath(id)}, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// List iterates over the tasks of the workspace in rank order, fetching
+// them a page at a time as the loop advances. Iteration ends at the first
+// error, which is yielded with a nil task.
+func (c *Client) List(ctx context.Context, options ListOptions) iter.Seq2[*response.TaskResponse, error] {
+	return func(yield func(*response.TaskResponse, error) bool) {
+		pageSize := options.PageSize
+		if pageSize <= 0 {
+			pageSize = defaultPageSize
+		}
+		query := url.Values{"limit": {strconv.Itoa(pageSize)}}
+		for {
+			var page []*response.TaskResponse
+			header, err := c.do(ctx, call{method: http.MethodGet, path: "/api/v1/tasks", query: query}, &page)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, task := range page {
+				if !yield(task, nil) {
+					return
+				}
+			}
+			cursor, ok := nextCursor(header)
+			if !ok {
+				return
+			}
+			query.Set("after", cursor)
+		}
+	}
+}
+
+// Update applies a merge patch to the task with the given id and returns
+// the task as updated. Fields left unset in patch keep their value.
+func (c *Client) Update(ctx context.Context, id int64, patch request.TaskPatchRequest) (*response.TaskResponse, error) {
+	var task response.TaskResponse
+	req := call{method: http.MethodPatch, path: taskPath(id), body: patch, contentType: mergePatchType}
+	if _, err := c.do(ctx, req, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// Delete moves the task with the given id to the trash.
+func (c *Client) Delete(ctx context.Context, id int64) error {
+	_, err := c.do(ctx, call{method: http.MethodDelete, path: taskPath(id)}, nil)
+	return err
+}
+
+// Search returns the tasks matching a full-text query, best match first.
+func (c *Client) Search(ctx context.Context, query string) ([]*response.TaskResponse, error) {
+	var tasks []*response.TaskResponse
+	req := call{method: http.MethodGet, path: "/api/v1/tasks/search", query: url.Values{"q": {query}}}
+	if _, err := c.do(ctx, req, &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// ProjectTasks returns the live tasks of a project in rank order.
+func (c *Client) ProjectTasks(ctx context.Context, projectID int64) ([]*response.TaskResponse, error) {
+	var tasks []*response.TaskResponse
+	req := call{method: http.MethodGet, path: "/api/v1/projects/" + strconv.FormatInt(projectID, 10) + "/tasks"}
+	if _, err := c.do(ctx, req, &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func taskPath(id int64) string {
+	return "/api/v1/tasks/" + strconv.FormatInt(id, 10)
+}
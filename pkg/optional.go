@@ -0,0 +1,31 @@
+package pkg
+
+import "encoding/json"
+
+// Optional is a field of a partial update. Set tells a field that was left
+// out from one given its zero value; in JSON, null sets the zero value, so
+// a merge patch clears a field with null.
+type Optional[T any] struct {
+	Value T
+	Set   bool
+}
+
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		var zero T
+		o.Value = zero
+		return nil
+	}
+	return json.Unmarshal(data, &o.Value)
+}
+
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(o.Value)
+}
+
+// IsZero reports whether the field was left out, so that a field tagged
+// omitzero is only encoded when it is set.
+func (o Optional[T]) IsZero() bool {
+	return !o.Set
+}
@@ -0,0 +1,71 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// ValidationProblemType identifies problems that list invalid fields in
+// Errors; all other problems are plain HTTP statuses ("about:blank").
+const ValidationProblemType = "urn:go-task:problem:validation"
+
+// Problem is an RFC 9457 problem details object.
+type Problem struct {
+	Type   string         `json:"type"`
+	Title  string         `json:"title"`
+	Status int            `json:"status"`
+	Detail string         `json:"detail,omitempty"`
+	Errors []FieldProblem `json:"errors,omitempty"`
+}
+
+// FieldProblem is the reason one input field was rejected.
+type FieldProblem struct {
+	Field  string `json:"field,omitempty"`
+	Detail string `json:"detail"`
+}
+
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// ProblemFor maps an error to the problem it is reported as. Internal
+// errors are logged and their details withheld from the client.
+func ProblemFor(err error) *Problem {
+	var validationErr *ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		problem := NewProblem(http.StatusBadRequest, validationErr.Message)
+		problem.Type = ValidationProblemType
+		problem.Title = "Invalid request"
+		problem.Errors = []FieldProblem{{Field: validationErr.Field, Detail: validationErr.Message}}
+		return problem
+	case errors.Is(err, ErrNotFound):
+		return NewProblem(http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrConflict):
+		return NewProblem(http.StatusConflict, err.Error())
+	case errors.Is(err, ErrForbidden):
+		return NewProblem(http.StatusForbidden, err.Error())
+	case errors.Is(err, ErrUnauthorized):
+		return NewProblem(http.StatusUnauthorized, err.Error())
+	}
+	log.Println("internal error:", err)
+	return NewProblem(http.StatusInternalServerError, "")
+}
+
+func WriteProblem(w http.ResponseWriter, problem *Problem) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		log.Println("Failed to write problem:", err)
+	}
+}
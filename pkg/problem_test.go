@@ -0,0 +1,116 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProblemFor(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantType   string
+		wantDetail string
+	}{
+		{"validation", &ValidationError{Field: "title", Message: "title cannot be empty"}, http.StatusBadRequest, ValidationProblemType, "title cannot be empty"},
+		{"wrapped validation", fmt.Errorf("create: %w", &ValidationError{Field: "title", Message: "title cannot be empty"}), http.StatusBadRequest, ValidationProblemType, "title cannot be empty"},
+		{"not found", fmt.Errorf("task 7: %w", ErrNotFound), http.StatusNotFound, "about:blank", "task 7: not found"},
+		{"conflict", fmt.Errorf("project 3 is archived: %w", ErrConflict), http.StatusConflict, "about:blank", "project 3 is archived: conflict"},
+		{"forbidden", ErrForbidden, http.StatusForbidden, "about:blank", "forbidden"},
+		{"unauthorized", ErrUnauthorized, http.StatusUnauthorized, "about:blank", "unauthorized"},
+		{"internal", &TaskError{Message: "Data Access Error: connection refused", Err: errors.New("dial tcp: connection refused")}, http.StatusInternalServerError, "about:blank", ""},
+		{"unknown", errors.New("something broke"), http.StatusInternalServerError, "about:blank", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problem := ProblemFor(tt.err)
+			if problem.Status != tt.wantStatus || problem.Type != tt.wantType {
+				t.Fatalf("got %d %s, want %d %s", problem.Status, problem.Type, tt.wantStatus, tt.wantType)
+			}
+			// Internal details never reach the client.
+			if problem.Detail != tt.wantDetail {
+				t.Errorf("detail = %q, want %q", problem.Detail, tt.wantDetail)
+			}
+			if problem.Title == "" {
+				t.Error("problem has no title")
+			}
+		})
+	}
+
+	problem := ProblemFor(&ValidationError{Field: "dueAt", Message: "due date must be a date"})
+	if len(problem.Errors) != 1 || problem.Errors[0] != (FieldProblem{Field: "dueAt", Detail: "due date must be a date"}) {
+		t.Errorf("validation problem lists %+v, want the invalid field", problem.Errors)
+	}
+}
+
+func TestWriteProblem(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	WriteProblem(recorder, ProblemFor(fmt.Errorf("task 7: %w", ErrNotFound)))
+
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+	if got := recorder.Header().Get("Content-Type"); got != ProblemContentType {
+		t.Errorf("content type = %q, want %q", got, ProblemContentType)
+	}
+	var problem Problem
+	if err := json.Unmarshal(recorder.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("body: %v", err)
+	}
+	if problem.Status != http.StatusNotFound || problem.Detail != "task 7: not found" {
+		t.Errorf("body = %+v", problem)
+	}
+}
+
+func TestHandleErrorRecoversPanic(t *testing.T) {
+	handler := HandleError(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("nil map")
+	}))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/tasks", nil))
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusInternalServerError)
+	}
+	if got := recorder.Header().Get("Content-Type"); got != ProblemContentType {
+		t.Errorf("content type = %q, want %q", got, ProblemContentType)
+	}
+	var problem Problem
+	if err := json.Unmarshal(recorder.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("body: %v", err)
+	}
+	if problem.Status != http.StatusInternalServerError || problem.Detail != "" {
+		t.Errorf("body = %+v, want a 500 without details", problem)
+	}
+}
+
+func TestHandleErrorPassesThrough(t *testing.T) {
+	handler := HandleError(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/tasks", nil))
+	if recorder.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusCreated)
+	}
+}
+
+// http.ErrAbortHandler is how a handler aborts a response on purpose; the
+// server, not HandleError, deals with it.
+func TestHandleErrorRepanicsAbort(t *testing.T) {
+	handler := HandleError(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if err := recover(); err != http.ErrAbortHandler {
+			t.Fatalf("recovered %v, want %v", err, http.ErrAbortHandler)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	t.Fatal("abort was swallowed")
+}
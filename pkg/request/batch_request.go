@@ -0,0 +1,24 @@
+package request
+
+import (
+	"go-task/pkg"
+)
+
+// BatchRequest runs several task operations in one transaction. Mode is
+// "atomic", the default, to roll everything back when one operation fails,
+// or "per-item" to skip the failing operations and commit the others.
+type BatchRequest struct {
+	Mode       string                  `json:"mode,omitempty" enum:"atomic,per-item"`
+	Operations []BatchOperationRequest `json:"operations"`
+}
+
+// BatchOperationRequest is one operation of a batch. Op is create, update,
+// status or delete; create takes Task, update a merge patch in Patch and
+// status the new Status. All but create name the task by ID.
+type BatchOperationRequest struct {
+	Op     string            `json:"op" enum:"create,update,status,delete"`
+	ID     int64             `json:"id,omitempty"`
+	Task   *TaskRequest      `json:"task,omitempty"`
+	Patch  *TaskPatchRequest `json:"patch,omitempty"`
+	Status pkg.TaskStatus    `json:"status,omitempty"`
+}
@@ -0,0 +1,14 @@
+package request
+
+import (
+	"go-task/pkg"
+)
+
+// MoveRequest places a task between the tasks Before and After, by id.
+// Either may be omitted at the start or end of the list. Status optionally
+// moves the task to another status at the same time.
+type MoveRequest struct {
+	Before *int64         `json:"before,omitempty"`
+	After  *int64         `json:"after,omitempty"`
+	Status pkg.TaskStatus `json:"status,omitempty"`
+}
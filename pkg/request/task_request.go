@@ -1,9 +1,29 @@
 package request
 
-import "go-task/pkg"
+import (
+	"go-task/pkg"
+	"time"
+)
 
 type TaskRequest struct {
-	Title   string         `json:"title"`
-	Content string         `json:"content"`
-	Status  pkg.TaskStatus `json:"status"`
+	Title      string         `json:"title,omitzero"`
+	Content    string         `json:"content,omitempty"`
+	Status     pkg.TaskStatus `json:"status,omitzero"`
+	Labels     []string       `json:"labels,omitempty"`
+	DueAt      *time.Time     `json:"dueAt,omitempty"`
+	Recurrence string         `json:"recurrence,omitempty"`
+	ProjectID  *int64         `json:"projectId,omitempty"`
+}
+
+// TaskPatchRequest is a JSON merge patch (RFC 7396) of a task. Members
+// left out keep their value and null clears one; labels are replaced as a
+// whole.
+type TaskPatchRequest struct {
+	Title      pkg.Optional[string]         `json:"title,omitzero"`
+	Content    pkg.Optional[string]         `json:"content,omitzero"`
+	Status     pkg.Optional[pkg.TaskStatus] `json:"status,omitzero"`
+	Labels     pkg.Optional[[]string]       `json:"labels,omitzero"`
+	DueAt      pkg.Optional[*time.Time]     `json:"dueAt,omitzero"`
+	Recurrence pkg.Optional[string]         `json:"recurrence,omitzero"`
+	ProjectID  pkg.Optional[*int64]         `json:"projectId,omitzero"`
 }
@@ -0,0 +1,7 @@
+package request
+
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"`
+	Events []string `json:"events,omitempty" enum:"task.created,task.updated,task.deleted,task.status_changed"`
+}
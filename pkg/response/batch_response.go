@@ -0,0 +1,18 @@
+package response
+
+import (
+	"go-task/pkg"
+)
+
+type BatchResponse struct {
+	Committed bool                   `json:"committed"`
+	Results   []*BatchResultResponse `json:"results"`
+}
+
+// BatchResultResponse is the outcome of one operation: the HTTP status it
+// would have had on its own, with either the written task or the problem.
+type BatchResultResponse struct {
+	Status int           `json:"status"`
+	Task   *TaskResponse `json:"task,omitempty"`
+	Error  *pkg.Problem  `json:"error,omitempty"`
+}
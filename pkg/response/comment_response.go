@@ -0,0 +1,14 @@
+package response
+
+import (
+	"time"
+)
+
+type CommentResponse struct {
+	ID        int64     `json:"id"`
+	TaskID    int64     `json:"taskId"`
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
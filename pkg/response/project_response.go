@@ -0,0 +1,15 @@
+package response
+
+import (
+	"time"
+)
+
+type ProjectResponse struct {
+	ID          int64          `json:"id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Archived    bool           `json:"archived"`
+	TaskCounts  map[string]int `json:"taskCounts"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+}
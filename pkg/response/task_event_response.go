@@ -0,0 +1,19 @@
+package response
+
+import (
+	"time"
+)
+
+type FieldChangeResponse struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+type TaskEventResponse struct {
+	ID        int64                          `json:"id"`
+	TaskID    int64                          `json:"taskId"`
+	Actor     string                         `json:"actor"`
+	Operation string                         `json:"operation"`
+	Changes   map[string]FieldChangeResponse `json:"changes"`
+	CreatedAt time.Time                      `json:"createdAt"`
+}
@@ -5,10 +5,17 @@ import (
 )
 
 type TaskResponse struct {
-	ID        int64     `json:"id"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID         int64      `json:"id"`
+	Title      string     `json:"title"`
+	Content    string     `json:"content"`
+	Status     string     `json:"status"`
+	Labels     []string   `json:"labels,omitempty"`
+	DueAt      *time.Time `json:"dueAt,omitempty"`
+	Recurrence string     `json:"recurrence,omitempty"`
+	ProjectID  *int64     `json:"projectId,omitempty"`
+	Rank       float64    `json:"rank"`
+	CreatedBy  *int64     `json:"createdBy,omitempty"`
+	UpdatedBy  *int64     `json:"updatedBy,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
 }
@@ -0,0 +1,25 @@
+package response
+
+import (
+	"time"
+)
+
+// WebhookResponse carries the secret only when the webhook is created.
+type WebhookResponse struct {
+	ID        int64     `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID           int64     `json:"id"`
+	Event        string    `json:"event"`
+	Status       string    `json:"status"`
+	Attempts     int       `json:"attempts"`
+	ResponseCode *int      `json:"responseCode,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
@@ -1,12 +1,13 @@
 package pkg
 
 import (
-	"encoding/json"
 	"errors"
 	"log"
 	"net/http"
 )
 
+// TaskError is an internal failure, typically of the database. Its
+// message is logged but never shown to clients.
 type TaskError struct {
 	Message string
 	Err     error
@@ -17,28 +18,44 @@ func (e TaskError) Error() string {
 	return e.Message
 }
 
-type HttpError struct {
-	Message string `json:"message"`
-	Err     error  `json:"error"`
-	Code    int    `json:"code"`
+func (e TaskError) Is(target error) bool {
+	return target == ErrInternal
 }
 
+// HandleError recovers from a panic in next and answers with a 500
+// problem instead of dropping the connection.
 func HandleError(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				httpErr := HttpError{Message: "Internal Server Error", Err: nil, Code: http.StatusInternalServerError}
-				log.Printf("error: %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Header().Set("Content-Type", "Application/json")
-				jsonData, _ := json.Marshal(httpErr)
-				w.Write(jsonData)
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+				log.Printf("panic serving %s %s: %v", r.Method, r.URL.Path, err)
+				WriteProblem(w, NewProblem(http.StatusInternalServerError, ""))
 			}
 		}()
 		next.ServeHTTP(w, r)
 	})
 }
 
+// The error classes of the service layer. Every error that reaches the
+// HTTP layer is one of these, a *ValidationError, or internal.
 var (
-	ErrNotFound = errors.New("not found")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrForbidden    = errors.New("forbidden")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrInternal     = errors.New("internal error")
 )
+
+// ValidationError rejects a single input field. Field names the form field
+// so the UI can show Message next to it.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
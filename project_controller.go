@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/internal/service"
+	"go-task/internal/template"
+	"go-task/pkg"
+	"go-task/pkg/request"
+	"go-task/pkg/response"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type ProjectController struct {
+	service *service.ProjectService
+	tasks   *service.Service
+}
+
+func NewProjectController(service *service.ProjectService, tasks *service.Service) *ProjectController {
+	return &ProjectController{
+		service: service,
+		tasks:   tasks,
+	}
+}
+
+func (controller *ProjectController) register(router *http.ServeMux) {
+	router.Handle("GET /api/v1/projects", taskHandler(controller.list))
+	router.Handle("POST /api/v1/projects", taskHandler(controller.create))
+	router.Handle("GET /api/v1/projects/{id}", taskHandler(controller.get))
+	router.Handle("PUT /api/v1/projects/{id}", taskHandler(controller.update))
+	router.Handle("GET /api/v1/projects/{id}/tasks", taskHandler(controller.listTasks))
+	router.Handle("GET /api/v1/projects/{id}/search", taskHandler(controller.search))
+	router.Handle("GET /projects", taskHandler(controller.projectsPage))
+	router.Handle("POST /projects", taskHandler(controller.createForm))
+	router.Handle("GET /projects/{id}", taskHandler(controller.projectPage))
+	router.Handle("POST /projects/{id}/archive", taskHandler(controller.archiveForm))
+	router.Handle("GET /nav/projects", taskHandler(controller.switcher))
+}
+
+// list answers with the live projects, or with archived ones too when
+// called with ?archived=true.
+func (controller *ProjectController) list(w http.ResponseWriter, r *http.Request) error {
+	projects, err := controller.service.FindAll(r.Context(), r.URL.Query().Get("archived") == "true")
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	counts, err := controller.service.Counts(r.Context())
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	projectsRS := make([]*response.ProjectResponse, 0, len(projects))
+	for _, p := range projects {
+		projectsRS = append(projectsRS, mapToProjectRes(*p, counts[p.ID]))
+	}
+	return writeJSON(w, http.StatusOK, projectsRS)
+}
+
+func (controller *ProjectController) create(w http.ResponseWriter, r *http.Request) error {
+	var projectReq request.ProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&projectReq); err != nil {
+		badRequest(w, err.Error())
+		return nil
+	}
+	project, err := model.NewProject(projectReq.Name, projectReq.Description)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	project.Archived = projectReq.Archived
+	project, err = controller.service.Create(r.Context(), project)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	return writeJSON(w, http.StatusCreated, mapToProjectRes(*project, nil))
+}
+
+func (controller *ProjectController) get(w http.ResponseWriter, r *http.Request) error {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	project, err := controller.service.FindById(r.Context(), id)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	counts, err := controller.service.Counts(r.Context())
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	return writeJSON(w, http.StatusOK, mapToProjectRes(*project, counts[project.ID]))
+}
+
+func (controller *ProjectController) update(w http.ResponseWriter, r *http.Request) error {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	var projectReq request.ProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&projectReq); err != nil {
+		badRequest(w, err.Error())
+		return nil
+	}
+	project, err := controller.service.Update(r.Context(), id, projectReq.Name, projectReq.Description, projectReq.Archived)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	return writeJSON(w, http.StatusOK, mapToProjectRes(*project, nil))
+}
+
+func (controller *ProjectController) listTasks(w http.ResponseWriter, r *http.Request) error {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	tasks, err := controller.service.Tasks(r.Context(), id)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	return writeTasks(w, tasks)
+}
+
+func (controller *ProjectController) search(w http.ResponseWriter, r *http.Request) error {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		badRequest(w, "missing search query q")
+		return nil
+	}
+	project, err := controller.service.FindById(r.Context(), id)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	tasks, err := controller.tasks.Search(r.Context(), &project.ID, query)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	return writeTasks(w, tasks)
+}
+
+func (controller *ProjectController) projectsPage(w http.ResponseWriter, r *http.Request) error {
+	return controller.renderProjects(w, r, "")
+}
+
+func (controller *ProjectController) createForm(w http.ResponseWriter, r *http.Request) error {
+	project, err := model.NewProject(r.FormValue("name"), r.FormValue("description"))
+	if err == nil {
+		project, err = controller.service.Create(r.Context(), project)
+	}
+	if err != nil {
+		var taskErr *pkg.TaskError
+		if errors.Is(err, pkg.ErrForbidden) || errors.As(err, &taskErr) {
+			return writeServiceError(w, err)
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		return controller.renderProjects(w, r, err.Error())
+	}
+	http.Redirect(w, r, fmt.Sprintf("/projects/%d", project.ID), http.StatusSeeOther)
+	return nil
+}
+
+func (controller *ProjectController) archiveForm(w http.ResponseWriter, r *http.Request) error {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	project, err := controller.service.FindById(r.Context(), id)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	archived := r.FormValue("archived") == "true"
+	if _, err := controller.service.Update(r.Context(), id, project.Name, project.Description, archived); err != nil {
+		return writeServiceError(w, err)
+	}
+	http.Redirect(w, r, "/projects", http.StatusSeeOther)
+	return nil
+}
+
+func (controller *ProjectController) projectPage(w http.ResponseWriter, r *http.Request) error {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	project, err := controller.service.FindById(r.Context(), id)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	tasks, err := controller.service.Tasks(r.Context(), id)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	counts, err := controller.service.Counts(r.Context())
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	return template.ProjectPage(*project, tasks, counts[project.ID]).Render(r.Context(), w)
+}
+
+// switcher renders the project menu of the navigation bar, which htmx
+// loads after the page. The page the menu sits on comes from the
+// HX-Current-URL header so the current project can be preselected.
+func (controller *ProjectController) switcher(w http.ResponseWriter, r *http.Request) error {
+	projects, err := controller.service.FindAll(r.Context(), false)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	var current int64
+	if page, err := url.Parse(r.Header.Get("HX-Current-URL")); err == nil {
+		if id, ok := strings.CutPrefix(page.Path, "/projects/"); ok {
+			current, _ = strconv.ParseInt(id, 10, 64)
+		}
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	return template.ProjectSwitcher(projects, current).Render(r.Context(), w)
+}
+
+func (controller *ProjectController) renderProjects(w http.ResponseWriter, r *http.Request, errorMsg string) error {
+	projects, err := controller.service.FindAll(r.Context(), true)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	counts, err := controller.service.Counts(r.Context())
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	return template.Projects(projects, counts, errorMsg).Render(r.Context(), w)
+}
+
+func mapToProjectRes(project model.Project, counts model.StatusCounts) *response.ProjectResponse {
+	taskCounts := make(map[string]int, len(counts))
+	for status, count := range counts {
+		taskCounts[string(status)] = count
+	}
+	return &response.ProjectResponse{
+		ID:          project.ID,
+		Name:        project.Name,
+		Description: project.Description,
+		Archived:    project.Archived,
+		TaskCounts:  taskCounts,
+		CreatedAt:   project.CreatedAt,
+		UpdatedAt:   project.UpdatedAt,
+	}
+}
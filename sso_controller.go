@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"go-task/internal/model"
+	"go-task/internal/service"
+	"go-task/internal/sso"
+	"go-task/internal/template"
+	"go-task/pkg"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const ssoCookieName = "go_task_oidc"
+const ssoCookiePath = "/auth/oidc"
+const ssoFlowTTL = 10 * time.Minute
+
+// SSOController signs users in through an OpenID Connect provider with
+// the authorization code flow and PKCE. The state, nonce and code verifier
+// of a pending login travel in a short-lived cookie scoped to the callback.
+type SSOController struct {
+	client  *sso.Client
+	service *service.SSOService
+}
+
+func NewSSOController(client *sso.Client, service *service.SSOService) *SSOController {
+	return &SSOController{
+		client:  client,
+		service: service,
+	}
+}
+
+func (controller *SSOController) register(router *http.ServeMux) {
+	router.Handle("GET /auth/oidc/login", taskHandler(controller.login))
+	router.Handle("GET /auth/oidc/callback", taskHandler(controller.callback))
+}
+
+func (controller *SSOController) login(w http.ResponseWriter, r *http.Request) error {
+	flow := make([]string, 3)
+	for i := range flow {
+		value, err := model.NewToken()
+		if err != nil {
+			return err
+		}
+		flow[i] = value
+	}
+	state, nonce, verifier := flow[0], flow[1], flow[2]
+
+	target, err := controller.client.AuthCodeURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		log.Println("Failed to start single sign-on:", err)
+		w.WriteHeader(http.StatusBadGateway)
+		return template.Login("identity provider unavailable", true).Render(r.Context(), w)
+	}
+	setSSOCookie(w, strings.Join(flow, "."), time.Now().Add(ssoFlowTTL))
+	http.Redirect(w, r, target, http.StatusFound)
+	return nil
+}
+
+func (controller *SSOController) callback(w http.ResponseWriter, r *http.Request) error {
+	cookie, err := r.Cookie(ssoCookieName)
+	setSSOCookie(w, "", time.Unix(0, 0))
+	if err != nil {
+		return controller.fail(w, r, "sign-in expired, please try again")
+	}
+	flow := strings.Split(cookie.Value, ".")
+	if len(flow) != 3 {
+		return controller.fail(w, r, "sign-in expired, please try again")
+	}
+
+	claims, err := controller.client.Callback(r.Context(), sso.Flow{State: flow[0], Nonce: flow[1], Verifier: flow[2]}, r.URL.Query())
+	var providerErr *sso.ProviderError
+	switch {
+	case errors.Is(err, sso.ErrStateMismatch):
+		return controller.fail(w, r, "sign-in expired, please try again")
+	case errors.As(err, &providerErr):
+		return controller.fail(w, r, providerErr.Error())
+	case err != nil:
+		log.Println("Failed to complete single sign-on:", err)
+		return controller.fail(w, r, "could not verify the identity provider response")
+	}
+	_, token, err := controller.service.Login(service.ExternalIdentity{
+		Issuer:   claims.Issuer,
+		Subject:  claims.Subject,
+		Username: claims.Username(),
+	})
+	if err != nil {
+		var taskErr *pkg.TaskError
+		if errors.As(err, &taskErr) {
+			return err
+		}
+		return controller.fail(w, r, err.Error())
+	}
+	setSessionCookie(w, token, time.Now().Add(service.SessionTTL))
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+	return nil
+}
+
+func (controller *SSOController) fail(w http.ResponseWriter, r *http.Request, errorMsg string) error {
+	w.WriteHeader(http.StatusUnauthorized)
+	return template.Login(errorMsg, true).Render(r.Context(), w)
+}
+
+func setSSOCookie(w http.ResponseWriter, value string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     ssoCookieName,
+		Value:    value,
+		Path:     ssoCookiePath,
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
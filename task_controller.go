@@ -0,0 +1,305 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-task/internal/model"
+	"go-task/internal/service"
+	"go-task/internal/template"
+	"go-task/pkg"
+	"go-task/pkg/request"
+	"go-task/pkg/response"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// TaskController serves single tasks, the trash and search, both as JSON
+// under /api/v1/tasks and as the pages and fragments of the UI.
+type TaskController struct {
+	service  *service.Service
+	projects *service.ProjectService
+	comments *service.CommentService
+}
+
+func NewTaskController(service *service.Service, projects *service.ProjectService, comments *service.CommentService) *TaskController {
+	return &TaskController{
+		service:  service,
+		projects: projects,
+		comments: comments,
+	}
+}
+
+func (controller *TaskController) register(router *http.ServeMux) {
+	router.Handle("/", taskHandler(controller.index))
+	router.Handle("GET /api/v1/tasks/{id}/history", taskHandler(controller.history))
+	router.Handle("GET /api/v1/tasks/trash", taskHandler(controller.trash))
+	router.Handle("GET /api/v1/tasks/search", taskHandler(controller.search))
+	router.Handle("POST /api/v1/tasks/{id}/restore", taskHandler(controller.restore))
+	router.Handle("POST /api/v1/tasks/{id}/move", taskHandler(controller.move))
+	router.Handle("GET /api/v1/tasks/{id}", taskHandler(controller.get))
+	router.Handle("PATCH /api/v1/tasks/{id}", taskHandler(controller.patch))
+	router.Handle("DELETE /api/v1/tasks/{id}", taskHandler(controller.delete))
+	router.Handle("GET /trash", taskHandler(controller.trashPage))
+	router.Handle("POST /tasks/{id}/restore", taskHandler(controller.restoreForm))
+	router.Handle("GET /tasks/{id}", taskHandler(controller.detail))
+	router.Handle("POST /tasks/{id}/comments", taskHandler(controller.commentForm))
+	router.Handle("GET /{id}", taskHandler(controller.byID))
+	router.Handle("DELETE /{id}", taskHandler(controller.delete))
+	router.Handle("PUT /{id}/{status}", taskHandler(controller.updateStatus))
+}
+
+// index is the task table of the workspace. The navigation it renders
+// reads the signed-in user from the request context.
+//
+// "/" also catches the paths and methods no other route serves; under
+// /api/ those are answered with a 404 problem rather than the page.
+func (controller *TaskController) index(w http.ResponseWriter, r *http.Request) error {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		return fmt.Errorf("no route for %s %s: %w", r.Method, r.URL.Path, pkg.ErrNotFound)
+	}
+	if _, ok := service.UserFrom(r.Context()); !ok {
+		unauthorized(w, r)
+		return nil
+	}
+	tasks, err := controller.service.FindAll(r.Context())
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	return template.Index(tasks).Render(r.Context(), w)
+}
+
+// byID serves a task as JSON, or as its detail page to clients that
+// prefer HTML, such as a browser following a link.
+func (controller *TaskController) byID(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Add("Vary", "Accept")
+	if prefersHTML(r) {
+		return controller.detail(w, r)
+	}
+	return controller.get(w, r)
+}
+
+func (controller *TaskController) get(w http.ResponseWriter, r *http.Request) error {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	task, err := controller.service.FindById(r.Context(), id)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	taskRs, err := mapToTaskRes(*task)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, taskRs)
+}
+
+func (controller *TaskController) delete(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Cache-Control", "no-cache")
+	slog.Info("deleting task", "id", r.PathValue("id"))
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	if err := controller.service.Delete(r.Context(), id); err != nil {
+		return writeServiceError(w, err)
+	}
+	return nil
+}
+
+func (controller *TaskController) detail(w http.ResponseWriter, r *http.Request) error {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	task, err := controller.service.FindById(r.Context(), id)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	var project *model.Project
+	if task.ProjectID != nil {
+		project, err = controller.projects.FindById(r.Context(), *task.ProjectID)
+		if err != nil {
+			return writeServiceError(w, err)
+		}
+	}
+	comments, err := controller.comments.FindByTaskId(r.Context(), id)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	events, err := controller.service.History(r.Context(), id)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	return template.TaskDetail(*task, project, comments, events).Render(r.Context(), w)
+}
+
+func (controller *TaskController) history(w http.ResponseWriter, r *http.Request) error {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	events, err := controller.service.History(r.Context(), id)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	eventsRS := make([]*response.TaskEventResponse, 0, len(events))
+	for _, e := range events {
+		eventsRS = append(eventsRS, mapToTaskEventRes(*e))
+	}
+	return writeJSON(w, http.StatusOK, eventsRS)
+}
+
+func (controller *TaskController) commentForm(w http.ResponseWriter, r *http.Request) error {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	if _, err := controller.comments.Create(r.Context(), id, r.FormValue("body")); err != nil {
+		return writeServiceError(w, err)
+	}
+	comments, err := controller.comments.FindByTaskId(r.Context(), id)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	return template.CommentList(comments).Render(r.Context(), w)
+}
+
+func (controller *TaskController) trash(w http.ResponseWriter, r *http.Request) error {
+	tasks, err := controller.service.Trash(r.Context())
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	return writeTasks(w, tasks)
+}
+
+// search runs a full-text search over the whole workspace.
+func (controller *TaskController) search(w http.ResponseWriter, r *http.Request) error {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		badRequest(w, "missing search query q")
+		return nil
+	}
+	tasks, err := controller.service.Search(r.Context(), nil, query)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	return writeTasks(w, tasks)
+}
+
+func (controller *TaskController) restore(w http.ResponseWriter, r *http.Request) error {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	task, err := controller.service.Restore(r.Context(), id)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	taskRs, err := mapToTaskRes(*task)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, taskRs)
+}
+
+// move reorders a task by hand; list endpoints return tasks in the
+// resulting rank order.
+func (controller *TaskController) move(w http.ResponseWriter, r *http.Request) error {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	var moveReq request.MoveRequest
+	if err := json.NewDecoder(r.Body).Decode(&moveReq); err != nil {
+		badRequest(w, err.Error())
+		return nil
+	}
+	task, err := controller.service.Move(r.Context(), id, moveReq.Status, moveReq.Before, moveReq.After)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	taskRs, err := mapToTaskRes(*task)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, taskRs)
+}
+
+// patch applies a JSON merge patch to a task. Plain JSON is taken
+// as a merge patch too, since that is what most clients send.
+func (controller *TaskController) patch(w http.ResponseWriter, r *http.Request) error {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	mediaType, _, _ := strings.Cut(r.Header.Get("Content-Type"), ";")
+	switch strings.ToLower(strings.TrimSpace(mediaType)) {
+	case mergePatchContentType, "application/json":
+	default:
+		w.Header().Set("Accept-Patch", mergePatchContentType)
+		pkg.WriteProblem(w, pkg.NewProblem(http.StatusUnsupportedMediaType, "patch must be "+mergePatchContentType))
+		return nil
+	}
+	var patchReq request.TaskPatchRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patchReq); err != nil {
+		badRequest(w, err.Error())
+		return nil
+	}
+	patch, err := mapToTaskPatch(patchReq)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	task, err := controller.service.Patch(r.Context(), id, patch)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	taskRs, err := mapToTaskRes(*task)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, taskRs)
+}
+
+func (controller *TaskController) trashPage(w http.ResponseWriter, r *http.Request) error {
+	tasks, err := controller.service.Trash(r.Context())
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	return template.Trash(tasks).Render(r.Context(), w)
+}
+
+// restoreForm answers the trash page with an empty body so htmx
+// drops the restored row from the table.
+func (controller *TaskController) restoreForm(w http.ResponseWriter, r *http.Request) error {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	if _, err := controller.service.Restore(r.Context(), id); err != nil {
+		return writeServiceError(w, err)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	return nil
+}
+
+func (controller *TaskController) updateStatus(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Cache-Control", "no-cache")
+	slog.Info("updating task", "id", r.PathValue("id"), "status", r.PathValue("status"))
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	task, err := controller.service.UpdateStatus(r.Context(), id, pkg.TaskStatus(r.PathValue("status")))
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	return template.UpdateTask(*task).Render(r.Context(), w)
+}
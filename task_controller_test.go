@@ -0,0 +1,74 @@
+package main
+
+import (
+	"go-task/pkg"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// get requests path from the test API as its user, with the browser's
+// Accept header when html is set.
+func (api *testAPI) get(t *testing.T, path string, html bool) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, api.server.URL+path, nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+api.token)
+	if html {
+		req.Header.Set("Accept", "text/html")
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	return res, string(body)
+}
+
+// The task table renders with the signed-in user, so the navigation shows
+// the account menu.
+func TestIndexRendersForUser(t *testing.T) {
+	api := newTestAPI(t)
+	api.seed(t, 1)
+
+	res, body := api.get(t, "/", true)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want %d", res.StatusCode, http.StatusOK)
+	}
+	for _, want := range []string{"task 1", "Sign out", api.user.Username} {
+		if !strings.Contains(body, want) {
+			t.Errorf("index does not show %q", want)
+		}
+	}
+}
+
+// API requests no route serves are answered with a problem, not the page
+// rendered at "/".
+func TestUnknownAPIRoutesAreProblems(t *testing.T) {
+	api := newTestAPI(t)
+	tests := []struct{ method, path string }{
+		{http.MethodGet, "/api/v1/nothing"},
+		{http.MethodGet, "/api/v1/tasks/1/nothing"},
+		{http.MethodPut, "/api/v1/tasks/1"},
+		{http.MethodDelete, "/api/v1/projects/1"},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, api.server.URL+tt.path, nil)
+		if err != nil {
+			t.Fatalf("NewRequest: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+api.token)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", tt.method, tt.path, err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusNotFound || res.Header.Get("Content-Type") != pkg.ProblemContentType {
+			t.Errorf("%s %s: status %d, %s; want a 404 problem", tt.method, tt.path, res.StatusCode, res.Header.Get("Content-Type"))
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"go-task/internal/model"
+	"go-task/internal/service"
+	"go-task/internal/template"
+	"go-task/pkg"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TaskFormController serves the create and edit forms of the task table.
+// htmx swaps the forms in and out; a submission that fails validation is
+// answered with the form and its errors with a 200, as the board does for
+// a refused move, so htmx swaps it in.
+type TaskFormController struct {
+	service  *service.Service
+	projects *service.ProjectService
+}
+
+func NewTaskFormController(service *service.Service, projects *service.ProjectService) *TaskFormController {
+	return &TaskFormController{
+		service:  service,
+		projects: projects,
+	}
+}
+
+func (controller *TaskFormController) register(router *http.ServeMux) {
+	router.Handle("GET /tasks/new", taskHandler(controller.newForm))
+	router.Handle("POST /tasks", taskHandler(controller.create))
+	router.Handle("GET /tasks/{id}/edit", taskHandler(controller.editForm))
+	router.Handle("GET /tasks/{id}/row", taskHandler(controller.row))
+	router.Handle("PUT /{id}", taskHandler(controller.update))
+}
+
+// newForm renders an empty create form; ?project=ID preselects a project
+// and marks the form as the one of that project's list.
+func (controller *TaskFormController) newForm(w http.ResponseWriter, r *http.Request) error {
+	project := r.FormValue("project")
+	return controller.renderCreateForm(w, r, template.TaskForm{Status: string(pkg.TODO), Project: project, View: project}, nil)
+}
+
+// create answers with an empty form again and, when the new task belongs
+// in the list below the form, its row.
+func (controller *TaskFormController) create(w http.ResponseWriter, r *http.Request) error {
+	form, task := readTaskForm(r)
+	if task == nil {
+		return controller.renderCreateForm(w, r, form, nil)
+	}
+	created, err := controller.service.Create(r.Context(), task)
+	if err != nil {
+		if err := addTaskFormError(&form, err); err != nil {
+			return writeServiceError(w, err)
+		}
+		return controller.renderCreateForm(w, r, form, nil)
+	}
+	if form.View != "" && form.View != form.Project {
+		created = nil
+	}
+	return controller.renderCreateForm(w, r, template.TaskForm{Status: string(pkg.TODO), Project: form.Project, View: form.View}, created)
+}
+
+func (controller *TaskFormController) editForm(w http.ResponseWriter, r *http.Request) error {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	task, err := controller.service.FindById(r.Context(), id)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	return controller.renderEditRow(w, r, template.TaskFormOf(*task))
+}
+
+// row renders a task row again when its edit is cancelled.
+func (controller *TaskFormController) row(w http.ResponseWriter, r *http.Request) error {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	task, err := controller.service.FindById(r.Context(), id)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	return template.TaskRow(*task).Render(r.Context(), w)
+}
+
+// update saves the edit form as one patch, so the edit is a single change
+// in the history and completing a recurring task in it still spawns the
+// next occurrence.
+func (controller *TaskFormController) update(w http.ResponseWriter, r *http.Request) error {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	form, update := readTaskForm(r)
+	form.ID = id
+	if update == nil {
+		return controller.renderEditRow(w, r, form)
+	}
+	task, err := controller.service.Patch(r.Context(), id, formPatch(update))
+	if err != nil {
+		if err := addTaskFormError(&form, err); err != nil {
+			return writeServiceError(w, err)
+		}
+		return controller.renderEditRow(w, r, form)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	return template.TaskRow(*task).Render(r.Context(), w)
+}
+
+func (controller *TaskFormController) renderCreateForm(w http.ResponseWriter, r *http.Request, form template.TaskForm, created *model.Task) error {
+	projects, err := controller.projects.FindAll(r.Context(), true)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	return template.TaskCreateForm(form, projects, created).Render(r.Context(), w)
+}
+
+func (controller *TaskFormController) renderEditRow(w http.ResponseWriter, r *http.Request, form template.TaskForm) error {
+	projects, err := controller.projects.FindAll(r.Context(), true)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	return template.TaskEditRow(form, projects).Render(r.Context(), w)
+}
+
+// readTaskForm reads the submitted task form. The task is nil when a field
+// is invalid; the form then carries the error messages by field.
+func readTaskForm(r *http.Request) (template.TaskForm, *model.Task) {
+	form := template.TaskForm{
+		Title:      r.FormValue("title"),
+		Content:    r.FormValue("content"),
+		Status:     r.FormValue("status"),
+		Labels:     r.FormValue("labels"),
+		DueAt:      r.FormValue("dueAt"),
+		Recurrence: r.FormValue("recurrence"),
+		Project:    r.FormValue("project"),
+		View:       r.FormValue("view"),
+		Errors:     map[string]string{},
+	}
+	task, err := model.NewTask(form.Title, form.Content, pkg.TaskStatus(form.Status))
+	if err != nil {
+		addTaskFormError(&form, err)
+		task = &model.Task{}
+	}
+	for _, label := range strings.Split(form.Labels, ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			task.Labels = append(task.Labels, label)
+		}
+	}
+	if form.DueAt != "" {
+		dueAt, err := time.Parse(time.DateOnly, form.DueAt)
+		if err != nil {
+			form.Errors["dueAt"] = "due date must be a date like 2025-01-31"
+		} else {
+			task.DueAt = &dueAt
+		}
+	}
+	if strings.TrimSpace(form.Recurrence) != "" {
+		if task.Recurrence, err = model.ParseRecurrence(form.Recurrence); err != nil {
+			form.Errors["recurrence"] = err.Error()
+		}
+	}
+	if form.Project != "" && form.Project != "0" {
+		projectID, err := strconv.ParseInt(form.Project, 10, 64)
+		if err != nil {
+			form.Errors["project"] = "invalid project"
+		} else {
+			task.ProjectID = &projectID
+		}
+	}
+	if len(form.Errors) > 0 {
+		return form, nil
+	}
+	return form, task
+}
+
+// formPatch sets every field of the edit form, which always submits them
+// all.
+func formPatch(task *model.Task) model.TaskPatch {
+	return model.TaskPatch{
+		Title:      pkg.Optional[string]{Value: task.Title, Set: true},
+		Content:    pkg.Optional[string]{Value: task.Content, Set: true},
+		Status:     pkg.Optional[pkg.TaskStatus]{Value: task.Status, Set: true},
+		Labels:     pkg.Optional[[]string]{Value: task.Labels, Set: true},
+		DueAt:      pkg.Optional[*time.Time]{Value: task.DueAt, Set: true},
+		Recurrence: pkg.Optional[*model.Recurrence]{Value: task.Recurrence, Set: true},
+		ProjectID:  pkg.Optional[*int64]{Value: task.ProjectID, Set: true},
+	}
+}
+
+// addTaskFormError shows err on the form. Errors the form cannot show,
+// such as a missing permission or a failing database, are returned for
+// writeServiceError instead.
+func addTaskFormError(form *template.TaskForm, err error) error {
+	var validationErr *pkg.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		form.Errors[validationErr.Field] = validationErr.Message
+	case errors.Is(err, pkg.ErrConflict):
+		// The only conflict a task save runs into is an archived project.
+		form.Errors["project"] = err.Error()
+	case errors.Is(err, pkg.ErrForbidden), errors.Is(err, pkg.ErrNotFound), errors.Is(err, pkg.ErrInternal):
+		return err
+	default:
+		form.Errors[""] = err.Error()
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"go-task/internal/model"
+	"go-task/internal/service"
+	"go-task/pkg"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// putForm submits the edit form of task id as the test API's user.
+func (api *testAPI) putForm(t *testing.T, id int64, form url.Values) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPut, api.server.URL+"/"+strconv.FormatInt(id, 10), strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+api.token)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT: %v", err)
+	}
+	res.Body.Close()
+	return res.StatusCode
+}
+
+// An edit that changes fields and the status is saved as one change.
+func TestEditFormSavesOneChange(t *testing.T) {
+	api := newTestAPI(t)
+	ctx := service.WithUser(context.Background(), api.user)
+	due := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	rule, _ := model.ParseRecurrence("FREQ=WEEKLY")
+	task, err := api.deps.tasks.Create(ctx, &model.Task{Title: "water plants", Status: pkg.TODO, DueAt: &due, Recurrence: rule})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	code := api.putForm(t, task.ID, url.Values{
+		"title":      {"water the plants"},
+		"status":     {string(pkg.COMPLETED)},
+		"labels":     {"home"},
+		"dueAt":      {"2024-03-01"},
+		"recurrence": {"FREQ=WEEKLY"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("status %d, want %d", code, http.StatusOK)
+	}
+
+	saved, _ := api.deps.tasks.FindById(ctx, task.ID)
+	if saved.Title != "water the plants" || saved.Status != pkg.COMPLETED || len(saved.Labels) != 1 {
+		t.Fatalf("saved %+v, want the edit applied", saved)
+	}
+	events, _ := api.deps.tasks.History(ctx, task.ID)
+	if len(events) != 2 || events[1].Operation != model.EventStatusChange || len(events[1].Changes) < 3 {
+		t.Fatalf("history %+v, want the create and one change with every edited field", events)
+	}
+	if tasks, _ := api.deps.tasks.FindAll(ctx); len(tasks) != 2 {
+		t.Fatalf("got %d tasks, want the next occurrence spawned", len(tasks))
+	}
+}
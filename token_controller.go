@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"go-task/internal/model"
+	"go-task/internal/service"
+	"go-task/internal/template"
+	"go-task/pkg"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type TokenController struct {
+	service *service.TokenService
+}
+
+func NewTokenController(service *service.TokenService) *TokenController {
+	return &TokenController{
+		service: service,
+	}
+}
+
+func (controller *TokenController) register(router *http.ServeMux) {
+	router.Handle("GET /settings/tokens", taskHandler(controller.settingsPage))
+	router.Handle("POST /settings/tokens", taskHandler(controller.issue))
+	router.Handle("POST /settings/tokens/{tokenId}/revoke", taskHandler(controller.revoke))
+}
+
+func (controller *TokenController) settingsPage(w http.ResponseWriter, r *http.Request) error {
+	return controller.render(w, r, "", "")
+}
+
+// issue creates a token from the settings form. The plaintext token is
+// rendered once in the response and cannot be shown again.
+func (controller *TokenController) issue(w http.ResponseWriter, r *http.Request) error {
+	user, ok := service.UserFrom(r.Context())
+	if !ok {
+		unauthorized(w, r)
+		return nil
+	}
+	var expiresAt *time.Time
+	if days := r.FormValue("expiresInDays"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return controller.render(w, r, "", "expiry must be a positive number of days")
+		}
+		expiry := time.Now().AddDate(0, 0, n)
+		expiresAt = &expiry
+	}
+	_, plaintext, err := controller.service.Issue(user, r.FormValue("name"), model.TokenScope(r.FormValue("scope")), expiresAt)
+	if err != nil {
+		var taskErr *pkg.TaskError
+		if errors.As(err, &taskErr) {
+			return err
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		return controller.render(w, r, "", err.Error())
+	}
+	return controller.render(w, r, plaintext, "")
+}
+
+func (controller *TokenController) revoke(w http.ResponseWriter, r *http.Request) error {
+	user, ok := service.UserFrom(r.Context())
+	if !ok {
+		unauthorized(w, r)
+		return nil
+	}
+	id, ok := pathID(w, r, "tokenId")
+	if !ok {
+		return nil
+	}
+	if err := controller.service.Revoke(user, id); err != nil {
+		return writeServiceError(w, err)
+	}
+	http.Redirect(w, r, "/settings/tokens", http.StatusSeeOther)
+	return nil
+}
+
+func (controller *TokenController) render(w http.ResponseWriter, r *http.Request, issued string, errorMsg string) error {
+	user, ok := service.UserFrom(r.Context())
+	if !ok {
+		unauthorized(w, r)
+		return nil
+	}
+	tokens, err := controller.service.List(user)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	return template.Tokens(tokens, issued, errorMsg).Render(r.Context(), w)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"go-task/internal/model"
+	"go-task/internal/service"
+	"go-task/internal/template"
+	"go-task/pkg"
+	"net/http"
+)
+
+type UserController struct {
+	service *service.AuthService
+}
+
+func NewUserController(service *service.AuthService) *UserController {
+	return &UserController{
+		service: service,
+	}
+}
+
+func (controller *UserController) register(router *http.ServeMux) {
+	router.Handle("GET /settings/users", taskHandler(controller.usersPage))
+	router.Handle("POST /settings/users", taskHandler(controller.createUser))
+	router.Handle("POST /settings/users/{userId}/role", taskHandler(controller.setRole))
+}
+
+func (controller *UserController) usersPage(w http.ResponseWriter, r *http.Request) error {
+	return controller.render(w, r, "")
+}
+
+// createUser adds an account to the admin's workspace, re-rendering the
+// page with the validation message when the input is rejected.
+func (controller *UserController) createUser(w http.ResponseWriter, r *http.Request) error {
+	role := model.Role(r.FormValue("role"))
+	_, err := controller.service.CreateUser(r.Context(), r.FormValue("username"), r.FormValue("password"), role)
+	if err != nil {
+		var taskErr *pkg.TaskError
+		if errors.Is(err, pkg.ErrForbidden) || errors.As(err, &taskErr) {
+			return writeServiceError(w, err)
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		return controller.render(w, r, err.Error())
+	}
+	http.Redirect(w, r, "/settings/users", http.StatusSeeOther)
+	return nil
+}
+
+func (controller *UserController) setRole(w http.ResponseWriter, r *http.Request) error {
+	id, ok := pathID(w, r, "userId")
+	if !ok {
+		return nil
+	}
+	if _, err := controller.service.SetRole(r.Context(), id, model.Role(r.FormValue("role"))); err != nil {
+		return writeServiceError(w, err)
+	}
+	http.Redirect(w, r, "/settings/users", http.StatusSeeOther)
+	return nil
+}
+
+func (controller *UserController) render(w http.ResponseWriter, r *http.Request, errorMsg string) error {
+	users, err := controller.service.Users(r.Context())
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	workspace, err := controller.service.Workspace(r.Context())
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	return template.Users(*workspace, users, errorMsg).Render(r.Context(), w)
+}
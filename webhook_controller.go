@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"go-task/internal/model"
+	"go-task/internal/service"
+	"go-task/pkg/request"
+	"go-task/pkg/response"
+	"net/http"
+)
+
+type WebhookController struct {
+	service *service.WebhookService
+}
+
+func NewWebhookController(service *service.WebhookService) *WebhookController {
+	return &WebhookController{
+		service: service,
+	}
+}
+
+func (controller *WebhookController) register(router *http.ServeMux) {
+	router.Handle("GET /api/v1/webhooks", taskHandler(controller.list))
+	router.Handle("POST /api/v1/webhooks", taskHandler(controller.create))
+	router.Handle("DELETE /api/v1/webhooks/{id}", taskHandler(controller.delete))
+	router.Handle("GET /api/v1/webhooks/{id}/deliveries", taskHandler(controller.deliveries))
+}
+
+func (controller *WebhookController) list(w http.ResponseWriter, r *http.Request) error {
+	webhooks, err := controller.service.FindAll(r.Context())
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	webhooksRS := make([]*response.WebhookResponse, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		webhooksRS = append(webhooksRS, mapToWebhookRes(*webhook))
+	}
+	return writeJSON(w, http.StatusOK, webhooksRS)
+}
+
+// create answers with the secret deliveries are signed with; it is not
+// shown again afterwards.
+func (controller *WebhookController) create(w http.ResponseWriter, r *http.Request) error {
+	var webhookReq request.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&webhookReq); err != nil {
+		badRequest(w, err.Error())
+		return nil
+	}
+	events := make([]model.WebhookEvent, 0, len(webhookReq.Events))
+	for _, event := range webhookReq.Events {
+		events = append(events, model.WebhookEvent(event))
+	}
+	webhook, err := model.NewWebhook(webhookReq.URL, webhookReq.Secret, events)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	webhook, err = controller.service.Create(r.Context(), webhook)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	webhookRs := mapToWebhookRes(*webhook)
+	webhookRs.Secret = webhook.Secret
+	return writeJSON(w, http.StatusCreated, webhookRs)
+}
+
+func (controller *WebhookController) delete(w http.ResponseWriter, r *http.Request) error {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	if err := controller.service.Delete(r.Context(), id); err != nil {
+		return writeServiceError(w, err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (controller *WebhookController) deliveries(w http.ResponseWriter, r *http.Request) error {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return nil
+	}
+	deliveries, err := controller.service.Deliveries(r.Context(), id)
+	if err != nil {
+		return writeServiceError(w, err)
+	}
+	deliveriesRS := make([]*response.WebhookDeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		deliveriesRS = append(deliveriesRS, &response.WebhookDeliveryResponse{
+			ID:           delivery.ID,
+			Event:        string(delivery.Event),
+			Status:       string(delivery.Status),
+			Attempts:     delivery.Attempts,
+			ResponseCode: delivery.ResponseCode,
+			Error:        delivery.Error,
+			CreatedAt:    delivery.CreatedAt,
+			UpdatedAt:    delivery.UpdatedAt,
+		})
+	}
+	return writeJSON(w, http.StatusOK, deliveriesRS)
+}
+
+func mapToWebhookRes(webhook model.Webhook) *response.WebhookResponse {
+	events := make([]string, 0, len(webhook.Events))
+	for _, event := range webhook.Events {
+		events = append(events, string(event))
+	}
+	return &response.WebhookResponse{
+		ID:        webhook.ID,
+		URL:       webhook.URL,
+		Events:    events,
+		CreatedAt: webhook.CreatedAt,
+	}
+}